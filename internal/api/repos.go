@@ -0,0 +1,15 @@
+package api
+
+// RepoApproversResponseBody lists the approvers currently available for a repository.
+type RepoApproversResponseBody struct {
+	Repo string `json:"repo"`
+	// Approvers are the online approvers allowed to approve the PRs of the repository.
+	Approvers []string `json:"approvers"`
+	// RequiredApprovals is the minimum number of approvals configured for the repository.
+	RequiredApprovals int `json:"required_approvals"`
+}
+
+// RefreshRegistrationsResponseBody tells how many clients were asked to refresh their registration.
+type RefreshRegistrationsResponseBody struct {
+	Refreshed int `json:"refreshed"`
+}
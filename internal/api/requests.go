@@ -0,0 +1,102 @@
+// Package api holds the request and response bodies of the HTTP API of the lgtm server, shared by the server
+// and the clients calling it.
+package api
+
+import (
+	"time"
+
+	"github.com/clems4ever/lgtm/internal/forge"
+	"github.com/clems4ever/lgtm/internal/protocol"
+)
+
+// SubmitBodyRequest represents the expected JSON body for a PR submission.
+type SubmitBodyRequest struct {
+	PRLink string `json:"pr_link"`
+	// RequiredApprovals is the number of distinct approvals to collect. It cannot be lower than
+	// the number configured for the repository.
+	RequiredApprovals int `json:"required_approvals,omitempty"`
+	// Justification is a free-text reason for the approval, forwarded to the approvers.
+	Justification string `json:"justification,omitempty"`
+}
+
+// ApprovalRequest is a request to get a pull request approved.
+type ApprovalRequest struct {
+	// Link is the pull request, or merge request, to approve.
+	Link forge.ChangeLink `json:"link"`
+	// Requester is the GitHub user who asked for the approval.
+	Requester string `json:"requester"`
+	// RequiredApprovals is the number of distinct approvals to collect. A value lower than one is treated as one.
+	RequiredApprovals int `json:"required_approvals"`
+	// HeadSHA is the head commit of the PR when the approval was requested. If set, approvers only approve this commit.
+	HeadSHA string `json:"head_sha,omitempty"`
+	// Justification is the reason given by the requester for the approval.
+	Justification string `json:"justification,omitempty"`
+	// RequestedAt is the time the approval was submitted.
+	RequestedAt time.Time `json:"requested_at"`
+	// Source tells how the request was submitted, empty for the web UI and the API.
+	Source string `json:"source,omitempty"`
+}
+
+const (
+	// RequestSourceWebhook is the source of the requests triggered by a webhook. Their outcome is commented on the PR,
+	// including when they are delivered from the queue.
+	RequestSourceWebhook = "webhook"
+)
+
+// RoutingAttempt records the outcome of forwarding an approval request to one approver.
+type RoutingAttempt struct {
+	// Approver is the GitHub user the request was forwarded to.
+	Approver string `json:"approver"`
+	// Response is the response sent back by the approver, empty if none was received.
+	Response protocol.ApproveResponseType `json:"response,omitempty"`
+	// Error describes why the attempt failed, empty on success.
+	Error string `json:"error,omitempty"`
+	// Rejection is the structured reason sent by an approver declining the PR.
+	Rejection *protocol.Rejection `json:"rejection,omitempty"`
+	// StartedAt is the time the request was forwarded to the approver.
+	StartedAt time.Time `json:"started_at"`
+	// Duration is the time it took to get the outcome of the attempt.
+	Duration time.Duration `json:"duration"`
+}
+
+// RequestState is the state of a submitted approval request.
+type RequestState string
+
+const (
+	// RequestStateQueued means no approver was online, the request waits for one to connect.
+	RequestStateQueued RequestState = "queued"
+	// RequestStateRouting means the request is being forwarded to approvers.
+	RequestStateRouting RequestState = "routing"
+	// RequestStateApproved means the required number of approvals has been collected.
+	RequestStateApproved RequestState = "approved"
+	// RequestStateFailed means the request could not be approved.
+	RequestStateFailed RequestState = "failed"
+)
+
+// RequestStatus is the progress of a submitted approval request.
+type RequestStatus struct {
+	ApprovalRequest
+	ID string `json:"id"`
+	// URL is the web URL of the pull request, or merge request.
+	URL   string       `json:"url"`
+	State RequestState `json:"state"`
+	// CurrentApprover is the approver the request is being forwarded to, empty when no attempt is in progress.
+	CurrentApprover string `json:"current_approver,omitempty"`
+	// Approvers lists the GitHub users who approved the PR, in order.
+	Approvers []string `json:"approvers"`
+	// Attempts lists every approver the request was forwarded to, in order.
+	Attempts []RoutingAttempt `json:"attempts"`
+	// Error describes why the request failed.
+	Error string `json:"error,omitempty"`
+	// ExpiresAt is the time a queued request is dropped if no approver connects.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	// CompletedAt is the time the request got approved or failed.
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// Done tells whether the request reached a final state.
+func (rs RequestStatus) Done() bool {
+	return rs.State == RequestStateApproved || rs.State == RequestStateFailed
+}
@@ -9,10 +9,13 @@ import (
 	"sync"
 	"time"
 
+	"github.com/clems4ever/lgtm/internal/forge"
 	"github.com/clems4ever/lgtm/internal/github"
 	"github.com/gorilla/websocket"
 )
 
+const defaultChecksPollInterval = 15 * time.Second
+
 // Client represents a lgtm client instance, including its configuration, GitHub authentication,
 // local server, and WebSocket connection to the relay server.
 type Client struct {
@@ -24,14 +27,35 @@ type Client struct {
 	// The interval between two pings to the server to keep the connection open.
 	pingInterval time.Duration
 
-	// GitHub client for interacting with the GitHub API.
+	// The forge instance the client approves change requests on, e.g. github.com or a GitLab instance.
+	forge forge.Provider
+	// GitHub client for the features only GitHub provides, such as changed files and checks. Nil on other forges.
 	githubClient *github.Client
-	// The handle of the github user this client is served with.
-	githubUsername    string
+	// The handle of the forge user this client is served with.
+	username          string
 	reconnectInterval time.Duration
-	// Mutex for synchronizing WebSocket access.
+	// Mutex for synchronizing WebSocket access, including writes.
 	wsMu sync.Mutex
 
+	// policy is the local approval policy, nil to approve every PR.
+	policy *Policy
+	// repoFilter selects the repositories the client registers for, nil to register for all of them.
+	repoFilter *RepoFilter
+	// rediscoverInterval is the interval between two discoveries of the repositories of the approver, 0 to only
+	// discover them when connecting and when the server asks for it.
+	rediscoverInterval time.Duration
+	// registrationMu serializes the updates of the registration.
+	registrationMu sync.Mutex
+	// registeredRepos are the repositories the client is currently registered for.
+	registeredRepos []string
+	// checksPollInterval is the interval between two checks polls while waiting for checks to complete.
+	checksPollInterval time.Duration
+
+	// confirmer asks the user to confirm each approval, nil to approve without asking.
+	confirmer Confirmer
+	// confirmTimeout is the time given to the user to confirm an approval.
+	confirmTimeout time.Duration
+
 	// WebSocket connection to the relay server.
 	ws *websocket.Conn
 
@@ -57,23 +81,53 @@ func NewClient(
 
 	// Create a GitHub client using the provided token and HTTP client.
 	ghClient := github.NewClient(githubToken, githubAPIBaseURL, httpClient)
+	return NewClientWithGithub(serverURL, authToken, reconnectInterval, pingInterval, ghClient)
+}
 
-	// Retrieve the authenticated GitHub username.
-	ghUsername, err := ghClient.GetAuthenticatedUserLogin()
+// NewClientWithGithub creates a new instance of the lgtm client approving the PRs of github.com with the given
+// GitHub client, for instance one authenticated with the user-to-server tokens of a GitHub App.
+func NewClientWithGithub(
+	serverURL string,
+	authToken string,
+	reconnectInterval time.Duration,
+	pingInterval time.Duration,
+	ghClient *github.Client,
+) (*Client, error) {
+	return NewClientWithForge(serverURL, authToken, reconnectInterval, pingInterval, github.NewProvider(ghClient, ""))
+}
+
+// NewClientWithForge creates a new instance of the lgtm client approving the change requests of the given forge,
+// such as a GitHub Enterprise Server or a GitLab instance.
+func NewClientWithForge(
+	serverURL string,
+	authToken string,
+	reconnectInterval time.Duration,
+	pingInterval time.Duration,
+	provider forge.Provider,
+) (*Client, error) {
+	// Retrieve the authenticated username.
+	username, err := provider.CurrentUser()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get username: %w", err)
 	}
 
+	var ghClient *github.Client
+	if p, ok := provider.(*github.Provider); ok {
+		ghClient = p.Client()
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Client{
-		serverURL:         serverURL,
-		authToken:         authToken,
-		pingInterval:      pingInterval,
-		reconnectInterval: reconnectInterval,
-		ctx:               ctx,
-		done:              cancel,
-		githubClient:      ghClient,
-		githubUsername:    ghUsername,
+		serverURL:          serverURL,
+		authToken:          authToken,
+		pingInterval:       pingInterval,
+		reconnectInterval:  reconnectInterval,
+		ctx:                ctx,
+		done:               cancel,
+		forge:              provider,
+		githubClient:       ghClient,
+		username:           username,
+		checksPollInterval: defaultChecksPollInterval,
 	}, nil
 }
 
@@ -1,24 +1,47 @@
 package client
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"time"
 
+	"github.com/clems4ever/lgtm/internal/forge"
+	"github.com/clems4ever/lgtm/internal/gitea"
+	"github.com/clems4ever/lgtm/internal/github"
+	"github.com/clems4ever/lgtm/internal/gitlab"
 	"github.com/spf13/cobra"
+	"golang.org/x/oauth2"
 )
 
 var (
-	serverURLFlag         string
-	reconnectIntervalFlag time.Duration
-	pingIntervalFlag      time.Duration
+	serverURLFlag          string
+	reconnectIntervalFlag  time.Duration
+	pingIntervalFlag       time.Duration
+	confirmFlag            bool
+	confirmTimeoutFlag     time.Duration
+	policyFileFlag         string
+	githubAppClientIDFlag  string
+	githubHostFlag         string
+	githubAPIURLFlag       string
+	githubAffiliationFlag  []string
+	githubOrgsFlag         []string
+	forgeFlag              string
+	gitlabHostFlag         string
+	gitlabAPIURLFlag       string
+	giteaHostFlag          string
+	giteaAPIURLFlag        string
+	reposFlag              []string
+	rediscoverIntervalFlag time.Duration
 )
 
 const (
-	defaultServerURL         = "https://lgtm.clems4ever.com"
-	defaultReconnectInterval = 15 * time.Second
-	defaultPingInterval      = 10 * time.Second
+	defaultServerURL          = "https://lgtm.clems4ever.com"
+	defaultReconnectInterval  = 15 * time.Second
+	defaultPingInterval       = 10 * time.Second
+	defaultConfirmTimeout     = 30 * time.Second
+	defaultRediscoverInterval = time.Hour
 )
 
 // BuildCommand creates the root Cobra command for the lgtm client.
@@ -30,27 +53,76 @@ func BuildCommand() *cobra.Command {
 		Use:   "client",
 		Short: "Client commands for lgtm",
 		Run: func(cmd *cobra.Command, args []string) {
-			githubToken := os.Getenv("LGTM_GITHUB_TOKEN")
-			if githubToken == "" {
-				fmt.Println("LGTM_GITHUB_TOKEN env var must be provided. " +
-					"Make sure it has the 'repo' and 'read:user' permissions and that the token is authorized " +
-					"on all orgs you want to be an approver for.")
-				os.Exit(1)
-			}
-
 			authToken := os.Getenv("LGTM_API_AUTH_TOKEN")
 
+			var provider forge.Provider
+			switch forgeFlag {
+			case forge.ProviderGitHub:
+				provider = newGithubProvider()
+			case forge.ProviderGitLab:
+				gitlabToken := os.Getenv("LGTM_GITLAB_TOKEN")
+				if gitlabToken == "" {
+					fmt.Println("LGTM_GITLAB_TOKEN env var must be provided with --forge gitlab. " +
+						"Make sure the token has the 'api' scope.")
+					os.Exit(1)
+				}
+				provider = gitlab.NewClient(gitlabToken, gitlabHostFlag, gitlabAPIURLFlag, nil)
+			case forge.ProviderGitea:
+				giteaToken := os.Getenv("LGTM_GITEA_TOKEN")
+				if giteaToken == "" || giteaHostFlag == "" {
+					fmt.Println("LGTM_GITEA_TOKEN env var and --gitea-host must be provided with --forge gitea. " +
+						"Make sure the token has the 'read:user', 'read:repository' and 'write:repository' scopes.")
+					os.Exit(1)
+				}
+				provider = gitea.NewClient(giteaToken, giteaHostFlag, giteaAPIURLFlag, nil)
+			default:
+				log.Fatalf("unknown forge %q, expected github, gitlab or gitea", forgeFlag)
+			}
+
 			// Start the client with the provided configuration
-			c, err := NewClient(
+			c, err := NewClientWithForge(
 				serverURLFlag,
 				authToken,
 				reconnectIntervalFlag,
 				pingIntervalFlag,
-				githubToken, "", nil)
+				provider)
 			if err != nil {
 				log.Fatal(err)
 			}
 
+			// Load the local approval policy
+			if policyFileFlag != "" {
+				c.policy, err = LoadPolicy(policyFileFlag)
+				if err != nil {
+					log.Fatal(err)
+				}
+				if c.policy.NeedsGitHub() && c.githubClient == nil {
+					log.Fatal("max_changed_lines, forbidden_paths and require_green_checks are only supported on GitHub")
+				}
+			}
+
+			// Only register for the repositories selected by the patterns of the policy file and the flags
+			var repoPatterns []string
+			if c.policy != nil {
+				repoPatterns = append(repoPatterns, c.policy.Repos...)
+			}
+			repoPatterns = append(repoPatterns, reposFlag...)
+			if len(repoPatterns) > 0 {
+				c.repoFilter, err = ParseRepoFilter(repoPatterns)
+				if err != nil {
+					log.Fatal(err)
+				}
+			}
+
+			// Pick up the repositories the approver gained or lost access to without reconnecting
+			c.rediscoverInterval = rediscoverIntervalFlag
+
+			// In confirm mode, ask the user before approving each PR
+			if confirmFlag {
+				c.confirmer = NewTerminalConfirmer(os.Stdin, os.Stdout)
+				c.confirmTimeout = confirmTimeoutFlag
+			}
+
 			err = c.Start()
 			if err != nil {
 				log.Fatal(err)
@@ -62,6 +134,76 @@ func BuildCommand() *cobra.Command {
 	cmd.Flags().StringVar(&serverURLFlag, "server-url", defaultServerURL, "url to the lgtm relay server")
 	cmd.Flags().DurationVar(&reconnectIntervalFlag, "reconnect-interval", defaultReconnectInterval, "time between two reconnection attempts")
 	cmd.Flags().DurationVar(&pingIntervalFlag, "ping-interval", defaultPingInterval, "interval for websocket ping messages")
+	cmd.Flags().StringVar(&policyFileFlag, "policy-file", "", "path to a YAML or JSON file with the local approval policy")
+	cmd.Flags().StringSliceVar(&reposFlag, "repos", nil,
+		"glob patterns of the repositories to register for, prefixed with ! to exclude repositories (e.g. 'acme/*,!acme/legacy-*')")
+	cmd.Flags().DurationVar(&rediscoverIntervalFlag, "rediscover-interval", defaultRediscoverInterval,
+		"interval between two discoveries of the repositories to update the registration with (0 to only discover them when connecting)")
+	cmd.Flags().BoolVar(&confirmFlag, "confirm", false, "ask for confirmation in the terminal before approving each PR")
+	cmd.Flags().DurationVar(&confirmTimeoutFlag, "confirm-timeout", defaultConfirmTimeout, "time to confirm an approval before it is refused, capped to the deadline of the server")
+	cmd.Flags().StringVar(&githubAppClientIDFlag, "github-app-client-id", "",
+		"client ID of a GitHub App to log in with through the device flow instead of LGTM_GITHUB_TOKEN")
+	cmd.Flags().StringVar(&githubHostFlag, "github-host", github.DefaultHost,
+		"web host of the GitHub instance to approve PRs on, e.g. the host of a GitHub Enterprise Server")
+	cmd.Flags().StringVar(&githubAPIURLFlag, "github-api-url", "",
+		"base URL of the GitHub REST API (derived from --github-host if empty, https://{host}/api/v3 for GitHub Enterprise Server)")
+	cmd.Flags().StringSliceVar(&githubAffiliationFlag, "github-affiliation", nil,
+		"only register for the repositories you own, collaborate on or access as an organization member (owner, collaborator, organization_member)")
+	cmd.Flags().StringSliceVar(&githubOrgsFlag, "github-org", nil,
+		"register for the repositories of these organizations, only them unless --github-affiliation is set")
+	cmd.Flags().StringVar(&forgeFlag, "forge", forge.ProviderGitHub, "forge to approve change requests on (github, gitlab, gitea for Gitea and Forgejo)")
+	cmd.Flags().StringVar(&gitlabHostFlag, "gitlab-host", gitlab.DefaultHost,
+		"web host of the GitLab instance to approve merge requests on, with --forge gitlab")
+	cmd.Flags().StringVar(&gitlabAPIURLFlag, "gitlab-api-url", "",
+		"base URL of the GitLab REST API (derived from --gitlab-host if empty, https://{host}/api/v4)")
+	cmd.Flags().StringVar(&giteaHostFlag, "gitea-host", "",
+		"web host of the Gitea or Forgejo instance to approve PRs on, with --forge gitea")
+	cmd.Flags().StringVar(&giteaAPIURLFlag, "gitea-api-url", "",
+		"base URL of the Gitea REST API (derived from --gitea-host if empty, https://{host}/api/v1)")
 
 	return cmd
 }
+
+// newGithubProvider creates the provider of the GitHub instance configured with the flags, authenticated with
+// LGTM_GITHUB_TOKEN or, if not set, with the user-to-server tokens of the GitHub App configured with the flags.
+func newGithubProvider() forge.Provider {
+	githubToken := os.Getenv("LGTM_GITHUB_TOKEN")
+	if githubToken == "" && githubAppClientIDFlag == "" {
+		fmt.Println("LGTM_GITHUB_TOKEN env var or --github-app-client-id must be provided. " +
+			"Make sure the token has the 'repo' and 'read:user' permissions and that the token is authorized " +
+			"on all orgs you want to be an approver for.")
+		os.Exit(1)
+	}
+
+	repoOptions := github.RepoListOptions{Affiliations: githubAffiliationFlag, Orgs: githubOrgsFlag}
+	if err := repoOptions.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	githubAPIURL := githubAPIURLFlag
+	if githubAPIURL == "" {
+		githubAPIURL = github.APIURLForHost(githubHostFlag)
+	}
+
+	// Approve with the short-lived user-to-server tokens of a GitHub App rather than a personal access token
+	var ghClient *github.Client
+	if githubToken != "" {
+		ghClient = github.NewClient(githubToken, githubAPIURL, nil)
+	} else {
+		tokenPath, err := github.GetTokenFilePath()
+		if err != nil {
+			log.Fatal(err)
+		}
+		ghClient, err = github.AuthenticateDevice(context.Background(), tokenPath, &oauth2.Config{
+			ClientID:     githubAppClientIDFlag,
+			ClientSecret: os.Getenv("LGTM_GITHUB_APP_CLIENT_SECRET"),
+			Endpoint:     github.OAuthEndpointForHost(githubHostFlag),
+		}, githubAPIURL, os.Stdout, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	provider := github.NewProvider(ghClient, githubHostFlag)
+	provider.SetRepoListOptions(repoOptions)
+	return provider
+}
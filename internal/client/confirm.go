@@ -0,0 +1,99 @@
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clems4ever/lgtm/internal/forge"
+	"github.com/clems4ever/lgtm/internal/protocol"
+)
+
+// Confirmer asks a human to confirm an approval before it is submitted to GitHub.
+type Confirmer interface {
+	// Confirm shows the summary of the approval request and returns true only if the approval
+	// has been explicitly confirmed before the timeout.
+	Confirm(summary string, timeout time.Duration) bool
+}
+
+// TerminalConfirmer asks for confirmations on a terminal, one at a time.
+type TerminalConfirmer struct {
+	out io.Writer
+
+	// Serializes the prompts when several requests arrive at the same time.
+	mu    sync.Mutex
+	lines chan string
+}
+
+// NewTerminalConfirmer creates a TerminalConfirmer reading answers from in and writing prompts to out.
+func NewTerminalConfirmer(in io.Reader, out io.Writer) *TerminalConfirmer {
+	tc := &TerminalConfirmer{
+		out:   out,
+		lines: make(chan string),
+	}
+	go func() {
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			tc.lines <- scanner.Text()
+		}
+		close(tc.lines)
+	}()
+	return tc
+}
+
+// Confirm implements Confirmer.
+func (tc *TerminalConfirmer) Confirm(summary string, timeout time.Duration) bool {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	// Discard what was typed while no question was asked.
+	for drained := false; !drained; {
+		select {
+		case _, ok := <-tc.lines:
+			drained = !ok
+		default:
+			drained = true
+		}
+	}
+
+	fmt.Fprintf(tc.out, "%s\nApprove? [y/N] (refused automatically in %s): ", summary, timeout)
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case line, ok := <-tc.lines:
+		if !ok {
+			fmt.Fprintln(tc.out, "\ninput closed, refusing.")
+			return false
+		}
+		answer := strings.ToLower(strings.TrimSpace(line))
+		return answer == "y" || answer == "yes"
+	case <-timer.C:
+		fmt.Fprintln(tc.out, "\n⌛ no answer, refusing.")
+		return false
+	}
+}
+
+// formatApprovalSummary renders the information shown to the approver when confirming an approval.
+func formatApprovalSummary(req protocol.ApproveRequestMessage, pr *forge.Change) string {
+	requester := req.Requester
+	if requester == "" {
+		requester = "unknown"
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🔔 Approval requested for %s\n", req.Link)
+	fmt.Fprintf(&sb, "   Title:         %s\n", stripControlChars(pr.Title))
+	fmt.Fprintf(&sb, "   Author:        %s\n", pr.Author)
+	fmt.Fprintf(&sb, "   Changes:       +%d -%d in %d file(s)\n", pr.Additions, pr.Deletions, pr.ChangedFiles)
+	fmt.Fprintf(&sb, "   Requester:     %s", requester)
+	if !req.RequestedAt.IsZero() {
+		fmt.Fprintf(&sb, " at %s", req.RequestedAt.Local().Format(time.DateTime))
+	}
+	if req.Justification != "" {
+		fmt.Fprintf(&sb, "\n   Justification: %s", stripControlChars(req.Justification))
+	}
+	return sb.String()
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clems4ever/lgtm/internal/forge"
+	"github.com/clems4ever/lgtm/internal/github"
+	"github.com/clems4ever/lgtm/internal/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTerminalConfirmer(t *testing.T) {
+	in, w := io.Pipe()
+	defer w.Close()
+	var out strings.Builder
+	tc := NewTerminalConfirmer(in, &out)
+
+	answer := func(line string) {
+		go func() {
+			_, _ = io.WriteString(w, line+"\n")
+		}()
+	}
+
+	answer("y")
+	require.True(t, tc.Confirm("summary", 5*time.Second))
+
+	answer("no")
+	require.False(t, tc.Confirm("summary", 5*time.Second))
+
+	// Without answer, the approval is refused when the timeout expires.
+	require.False(t, tc.Confirm("summary", 50*time.Millisecond))
+	require.Contains(t, out.String(), "no answer")
+}
+
+func TestTerminalConfirmer_ClosedInput(t *testing.T) {
+	tc := NewTerminalConfirmer(strings.NewReader(""), io.Discard)
+	// Let the input be closed before asking.
+	time.Sleep(50 * time.Millisecond)
+	require.False(t, tc.Confirm("summary", 5*time.Second))
+}
+
+func TestFormatApprovalSummary(t *testing.T) {
+	pr := &forge.Change{Title: "Fix bug", Author: "octocat", Additions: 10, Deletions: 2, ChangedFiles: 3}
+
+	summary := formatApprovalSummary(protocol.ApproveRequestMessage{
+		Link:          github.PRLink{Owner: "foo", Repo: "bar", PRNumber: 1},
+		Requester:     "carol",
+		Justification: "hotfix for the outage",
+	}, pr)
+	require.Contains(t, summary, "https://github.com/foo/bar/pull/1")
+	require.Contains(t, summary, "Fix bug")
+	require.Contains(t, summary, "octocat")
+	require.Contains(t, summary, "+10 -2 in 3 file(s)")
+	require.Contains(t, summary, "carol")
+	require.Contains(t, summary, "hotfix for the outage")
+
+	summary = formatApprovalSummary(protocol.ApproveRequestMessage{Justification: "\x1b[2Jhotfix"}, pr)
+	require.NotContains(t, summary, "\x1b")
+}
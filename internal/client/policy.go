@@ -0,0 +1,170 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/clems4ever/lgtm/internal/common"
+	"github.com/clems4ever/lgtm/internal/forge"
+	"github.com/clems4ever/lgtm/internal/github"
+	"github.com/clems4ever/lgtm/internal/protocol"
+	"gopkg.in/yaml.v3"
+)
+
+// Policy is the local approval policy of the client. Every PR must satisfy all the rules to be approved.
+// Zero values do not restrict anything.
+type Policy struct {
+	// MaxChangedLines is the maximum number of added and deleted lines.
+	MaxChangedLines int `yaml:"max_changed_lines" json:"max_changed_lines"`
+	// ForbiddenPaths lists glob patterns (e.g. "**/migrations/**") of paths that must not be changed.
+	ForbiddenPaths []string `yaml:"forbidden_paths" json:"forbidden_paths"`
+	// AllowedBaseBranches lists glob patterns of the branches PRs may target.
+	AllowedBaseBranches []string `yaml:"allowed_base_branches" json:"allowed_base_branches"`
+	// AllowedAuthors restricts the GitHub users whose PRs may be approved.
+	AllowedAuthors []string `yaml:"allowed_authors" json:"allowed_authors"`
+	// DeniedAuthors lists GitHub users whose PRs must never be approved.
+	DeniedAuthors []string `yaml:"denied_authors" json:"denied_authors"`
+	// RequireGreenChecks refuses PRs whose head commit has failing or pending checks.
+	RequireGreenChecks bool `yaml:"require_green_checks" json:"require_green_checks"`
+	// RequiredChecks restricts the checks that must pass to the ones with those names.
+	// If empty, every check of the head commit must pass.
+	RequiredChecks []string `yaml:"required_checks" json:"required_checks"`
+	// ChecksWaitTimeout is the time to wait for pending checks to complete before refusing the PR.
+	ChecksWaitTimeout time.Duration `yaml:"checks_wait_timeout" json:"checks_wait_timeout"`
+	// AllowedRequesters restricts the GitHub users whose approval requests are accepted.
+	AllowedRequesters []string `yaml:"allowed_requesters" json:"allowed_requesters"`
+	// RequireJustification refuses requests submitted without a justification.
+	RequireJustification bool `yaml:"require_justification" json:"require_justification"`
+	// MaxRequestAge refuses requests submitted longer ago, e.g. requests queued while the approver was offline.
+	MaxRequestAge time.Duration `yaml:"max_request_age" json:"max_request_age"`
+	// Repos lists glob patterns of the repositories to register for, "!"-prefixed patterns exclude repositories
+	// (e.g. "acme/*" and "!acme/legacy-*"), see RepoFilter. All the repositories the user can approve for if empty.
+	Repos []string `yaml:"repos" json:"repos"`
+}
+
+// LoadPolicy reads the YAML or JSON policy file at the given path.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	var p Policy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file: %w", err)
+	}
+	if p.MaxChangedLines < 0 {
+		return nil, fmt.Errorf("invalid policy file: max_changed_lines must not be negative")
+	}
+	if p.ChecksWaitTimeout < 0 {
+		return nil, fmt.Errorf("invalid policy file: checks_wait_timeout must not be negative")
+	}
+	if p.MaxRequestAge < 0 {
+		return nil, fmt.Errorf("invalid policy file: max_request_age must not be negative")
+	}
+	if _, err := ParseRepoFilter(p.Repos); err != nil {
+		return nil, fmt.Errorf("invalid policy file: %w", err)
+	}
+	return &p, nil
+}
+
+// NeedsFiles tells whether the policy needs the list of files changed by the PR to be evaluated.
+func (p *Policy) NeedsFiles() bool {
+	return len(p.ForbiddenPaths) > 0
+}
+
+// NeedsGitHub tells whether the policy has rules only GitHub provides the data for: the changed lines,
+// the changed files and the checks.
+func (p *Policy) NeedsGitHub() bool {
+	return p.NeedsFiles() || p.MaxChangedLines > 0 || p.RequireGreenChecks
+}
+
+// Evaluate checks the approval request and the PR against the policy and returns the reason why the PR
+// must not be approved, or nil if it can be approved. files is only used when NeedsFiles returns true.
+func (p *Policy) Evaluate(req protocol.ApproveRequestMessage, pr *forge.Change, files []string) *protocol.Rejection {
+	if len(p.AllowedRequesters) > 0 && !containsLogin(p.AllowedRequesters, req.Requester) {
+		return &protocol.Rejection{
+			Code:    protocol.RejectionRequesterNotAllowed,
+			Message: fmt.Sprintf("requests from %q are not accepted", req.Requester),
+		}
+	}
+
+	if p.RequireJustification && strings.TrimSpace(req.Justification) == "" {
+		return &protocol.Rejection{
+			Code:    protocol.RejectionJustificationRequired,
+			Message: "a justification is required",
+		}
+	}
+
+	if p.MaxRequestAge > 0 && !req.RequestedAt.IsZero() {
+		if age := time.Since(req.RequestedAt); age > p.MaxRequestAge {
+			return &protocol.Rejection{
+				Code:    protocol.RejectionRequestExpired,
+				Message: fmt.Sprintf("requested %s ago, at most %s allowed", age.Round(time.Second), p.MaxRequestAge),
+			}
+		}
+	}
+
+	author := pr.Author
+	if containsLogin(p.DeniedAuthors, author) ||
+		(len(p.AllowedAuthors) > 0 && !containsLogin(p.AllowedAuthors, author)) {
+		return &protocol.Rejection{
+			Code:    protocol.RejectionAuthorNotAllowed,
+			Message: fmt.Sprintf("PRs authored by %s are not approved", author),
+		}
+	}
+
+	if len(p.AllowedBaseBranches) > 0 && !slices.ContainsFunc(p.AllowedBaseBranches, func(pattern string) bool {
+		return common.MatchGlob(pattern, pr.BaseBranch)
+	}) {
+		return &protocol.Rejection{
+			Code:    protocol.RejectionBaseBranchNotAllowed,
+			Message: fmt.Sprintf("base branch %s is not allowed", pr.BaseBranch),
+		}
+	}
+
+	if changed := pr.Additions + pr.Deletions; p.MaxChangedLines > 0 && changed > p.MaxChangedLines {
+		return &protocol.Rejection{
+			Code:    protocol.RejectionTooManyChanges,
+			Message: fmt.Sprintf("%d lines changed, at most %d allowed", changed, p.MaxChangedLines),
+		}
+	}
+
+	for _, f := range files {
+		for _, pattern := range p.ForbiddenPaths {
+			if common.MatchGlob(pattern, f) {
+				return &protocol.Rejection{
+					Code:    protocol.RejectionForbiddenPath,
+					Message: fmt.Sprintf("%s matches forbidden path %s", f, pattern),
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checksRejection returns the reason why a PR with the given checks must not be approved, or nil if they are green.
+func checksRejection(summary *github.ChecksSummary) *protocol.Rejection {
+	switch summary.State {
+	case github.ChecksFailure:
+		return &protocol.Rejection{
+			Code:    protocol.RejectionChecksFailing,
+			Message: fmt.Sprintf("checks failing: %s", strings.Join(summary.Failing, ", ")),
+		}
+	case github.ChecksPending:
+		return &protocol.Rejection{
+			Code:    protocol.RejectionChecksPending,
+			Message: fmt.Sprintf("checks pending: %s", strings.Join(summary.Pending, ", ")),
+		}
+	}
+	return nil
+}
+
+// containsLogin tells whether the list contains the GitHub login. GitHub logins are case-insensitive.
+func containsLogin(logins []string, login string) bool {
+	return slices.ContainsFunc(logins, func(l string) bool {
+		return strings.EqualFold(l, login)
+	})
+}
@@ -0,0 +1,127 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/clems4ever/lgtm/internal/forge"
+	"github.com/clems4ever/lgtm/internal/github"
+	"github.com/clems4ever/lgtm/internal/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPR(author, base string, additions, deletions int) *forge.Change {
+	return &forge.Change{Author: author, BaseBranch: base, Additions: additions, Deletions: deletions}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+max_changed_lines: 200
+forbidden_paths: ["**/migrations/**"]
+allowed_base_branches: [main, "release/*"]
+denied_authors: [mallory]
+require_green_checks: true
+checks_wait_timeout: 5m
+repos: ["acme/*", "!acme/legacy-*"]
+`), 0600))
+
+	p, err := LoadPolicy(path)
+	require.NoError(t, err)
+	require.Equal(t, 200, p.MaxChangedLines)
+	require.True(t, p.NeedsFiles())
+	require.Equal(t, []string{"main", "release/*"}, p.AllowedBaseBranches)
+	require.True(t, p.RequireGreenChecks)
+	require.Equal(t, 5*time.Minute, p.ChecksWaitTimeout)
+	require.Equal(t, []string{"acme/*", "!acme/legacy-*"}, p.Repos)
+}
+
+func TestPolicy_Evaluate(t *testing.T) {
+	p := &Policy{
+		MaxChangedLines:     100,
+		ForbiddenPaths:      []string{"**/migrations/**"},
+		AllowedBaseBranches: []string{"main", "release/*"},
+		AllowedAuthors:      []string{"alice", "Mallory"},
+		DeniedAuthors:       []string{"mallory"},
+	}
+
+	tests := []struct {
+		name  string
+		pr    *forge.Change
+		files []string
+		want  protocol.RejectionCode
+	}{
+		{"allowed", newTestPR("alice", "main", 50, 50), []string{"main.go"}, ""},
+		{"release branch", newTestPR("alice", "release/1.0", 1, 1), nil, ""},
+		{"denied author", newTestPR("mallory", "main", 1, 1), nil, protocol.RejectionAuthorNotAllowed},
+		{"author not allowed", newTestPR("bob", "main", 1, 1), nil, protocol.RejectionAuthorNotAllowed},
+		{"base branch", newTestPR("alice", "develop", 1, 1), nil, protocol.RejectionBaseBranchNotAllowed},
+		{"too many changes", newTestPR("alice", "main", 60, 41), nil, protocol.RejectionTooManyChanges},
+		{"forbidden path", newTestPR("alice", "main", 1, 1), []string{"db/migrations/001.sql"}, protocol.RejectionForbiddenPath},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rejection := p.Evaluate(protocol.ApproveRequestMessage{}, tt.pr, tt.files)
+			if tt.want == "" {
+				require.Nil(t, rejection)
+				return
+			}
+			require.NotNil(t, rejection)
+			require.Equal(t, tt.want, rejection.Code)
+			require.NotEmpty(t, rejection.Message)
+		})
+	}
+}
+
+func TestPolicy_EvaluateRequest(t *testing.T) {
+	p := &Policy{
+		AllowedRequesters:    []string{"Carol"},
+		RequireJustification: true,
+		MaxRequestAge:        time.Hour,
+	}
+	pr := newTestPR("alice", "main", 1, 1)
+	valid := protocol.ApproveRequestMessage{Requester: "carol", Justification: "hotfix", RequestedAt: time.Now()}
+
+	tests := []struct {
+		name   string
+		modify func(*protocol.ApproveRequestMessage)
+		want   protocol.RejectionCode
+	}{
+		{"allowed", func(*protocol.ApproveRequestMessage) {}, ""},
+		{"requester not allowed", func(m *protocol.ApproveRequestMessage) { m.Requester = "dave" }, protocol.RejectionRequesterNotAllowed},
+		{"no justification", func(m *protocol.ApproveRequestMessage) { m.Justification = "  " }, protocol.RejectionJustificationRequired},
+		{"expired", func(m *protocol.ApproveRequestMessage) { m.RequestedAt = time.Now().Add(-2 * time.Hour) }, protocol.RejectionRequestExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.modify(&req)
+			rejection := p.Evaluate(req, pr, nil)
+			if tt.want == "" {
+				require.Nil(t, rejection)
+				return
+			}
+			require.NotNil(t, rejection)
+			require.Equal(t, tt.want, rejection.Code)
+		})
+	}
+}
+
+func TestPolicy_EmptyAllowsEverything(t *testing.T) {
+	p := &Policy{}
+	require.False(t, p.NeedsFiles())
+	require.Nil(t, p.Evaluate(protocol.ApproveRequestMessage{}, newTestPR("anyone", "anything", 10000, 10000), nil))
+}
+
+func TestChecksRejection(t *testing.T) {
+	require.Nil(t, checksRejection(&github.ChecksSummary{State: github.ChecksSuccess}))
+
+	r := checksRejection(&github.ChecksSummary{State: github.ChecksFailure, Failing: []string{"build", "test"}})
+	require.Equal(t, protocol.RejectionChecksFailing, r.Code)
+	require.Equal(t, "checks failing: build, test", r.Message)
+
+	r = checksRejection(&github.ChecksSummary{State: github.ChecksPending, Pending: []string{"e2e"}})
+	require.Equal(t, protocol.RejectionChecksPending, r.Code)
+}
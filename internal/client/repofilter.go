@@ -0,0 +1,68 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/clems4ever/lgtm/internal/common"
+)
+
+// RepoFilter selects the repositories the client registers for with glob patterns such as "acme/*".
+// Patterns prefixed with "!" exclude the repositories they match, e.g. "!acme/legacy-*". A repository is
+// selected if it matches one of the include patterns, or if there are none, and no exclude pattern.
+type RepoFilter struct {
+	include []string
+	exclude []string
+}
+
+// ParseRepoFilter parses the include and "!"-prefixed exclude patterns of a filter. Repository names are
+// case-insensitive, so are the patterns.
+func ParseRepoFilter(patterns []string) (*RepoFilter, error) {
+	f := &RepoFilter{}
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSpace(p))
+		exclude := strings.HasPrefix(p, "!")
+		p = strings.TrimPrefix(p, "!")
+		if p == "" {
+			return nil, fmt.Errorf("invalid repository pattern: empty pattern")
+		}
+		if exclude {
+			f.exclude = append(f.exclude, p)
+		} else {
+			f.include = append(f.include, p)
+		}
+	}
+	return f, nil
+}
+
+// Match tells whether the repository ("owner/repo") is selected by the filter.
+func (f *RepoFilter) Match(repo string) bool {
+	repo = strings.ToLower(repo)
+	for _, p := range f.exclude {
+		if common.MatchGlob(p, repo) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, p := range f.include {
+		if common.MatchGlob(p, repo) {
+			return true
+		}
+	}
+	return false
+}
+
+// Split returns the repositories selected by the filter and the excluded ones, in their original order.
+// A nil filter selects every repository.
+func (f *RepoFilter) Split(repos []string) (included, excluded []string) {
+	for _, repo := range repos {
+		if f == nil || f.Match(repo) {
+			included = append(included, repo)
+		} else {
+			excluded = append(excluded, repo)
+		}
+	}
+	return included, excluded
+}
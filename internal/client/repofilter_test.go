@@ -0,0 +1,41 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepoFilter(t *testing.T) {
+	repos := []string{"acme/api", "acme/legacy-web", "Acme/Legacy-API", "acme/tools/cli", "other/repo"}
+
+	tests := []struct {
+		name         string
+		patterns     []string
+		wantIncluded []string
+	}{
+		{"no patterns", nil, repos},
+		{"include", []string{"acme/*"}, []string{"acme/api", "acme/legacy-web", "Acme/Legacy-API"}},
+		{"include and exclude", []string{"acme/*", "!acme/legacy-*"}, []string{"acme/api"}},
+		{"exclude only", []string{"!acme/legacy-*"}, []string{"acme/api", "acme/tools/cli", "other/repo"}},
+		{"nested namespaces", []string{"acme/**"}, []string{"acme/api", "acme/legacy-web", "Acme/Legacy-API", "acme/tools/cli"}},
+		{"several includes", []string{"other/*", "acme/api"}, []string{"acme/api", "other/repo"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseRepoFilter(tt.patterns)
+			require.NoError(t, err)
+			included, excluded := f.Split(repos)
+			require.Equal(t, tt.wantIncluded, included)
+			require.Len(t, excluded, len(repos)-len(tt.wantIncluded))
+		})
+	}
+
+	_, err := ParseRepoFilter([]string{"!"})
+	require.Error(t, err)
+
+	var nilFilter *RepoFilter
+	included, excluded := nilFilter.Split(repos)
+	require.Equal(t, repos, included)
+	require.Empty(t, excluded)
+}
@@ -7,8 +7,12 @@ import (
 	"log"
 	"net/url"
 	"os"
+	"regexp"
+	"slices"
+	"strings"
 	"sync"
 	"time"
+	"unicode"
 
 	"github.com/clems4ever/lgtm/internal/protocol"
 	"github.com/gorilla/websocket"
@@ -109,11 +113,21 @@ func (c *Client) connectToWsServerAndListen(ctx context.Context, serverURL *url.
 
 			switch v := msg.Message.(type) {
 			case protocol.ApproveRequestMessage:
-				// Handle an approval request message
-				err = c.handleApproveMessage(conn, msg.RequestID, v)
-				if err != nil {
-					log.Printf("failed to handle message: %s\n", err)
-				}
+				// Handle an approval request message without blocking the reception of other messages
+				// since it may wait for the user to confirm.
+				go func(reqID string) {
+					err := c.handleApproveMessage(conn, reqID, v)
+					if err != nil {
+						log.Printf("failed to handle message: %s\n", err)
+					}
+				}(msg.RequestID)
+			case protocol.RefreshRegistrationMessage:
+				// Discovering the repositories can take a while, do not block the reception of other messages.
+				go func() {
+					if err := c.refreshRegistration(conn); err != nil {
+						log.Printf("failed to refresh registration: %s\n", err)
+					}
+				}()
 			case protocol.PingMessage:
 				// do nothing here, we just make sure the message is supported.
 			default:
@@ -133,7 +147,9 @@ func (c *Client) connectToWsServerAndListen(ctx context.Context, serverURL *url.
 			for {
 				select {
 				case <-ticker.C:
+					c.wsMu.Lock()
 					_, err := protocol.Write(conn, protocol.PingMessage{})
+					c.wsMu.Unlock()
 					if err != nil {
 						log.Println("failed to ping")
 					}
@@ -144,82 +160,338 @@ func (c *Client) connectToWsServerAndListen(ctx context.Context, serverURL *url.
 		}()
 	}
 
+	if c.rediscoverInterval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(c.rediscoverInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					if err := c.refreshRegistration(conn); err != nil {
+						log.Printf("failed to refresh registration: %s\n", err)
+					}
+				case <-connectionClosedC:
+					return
+				}
+			}
+		}()
+	}
+
 	wg.Wait()
 	return fmt.Errorf("disconnected")
 }
 
 // handleApproveMessage processes an ApproveRequestMessage received from the relay server.
-// It attempts to approve the pull request if it has not already been approved by the user.
+// It attempts to approve the change request if it has not already been approved by the user.
+// If the approval fails, the server is notified so that it can route the request to another approver.
 func (c *Client) handleApproveMessage(conn *websocket.Conn, reqID string, msg protocol.ApproveRequestMessage) error {
-	author, err := c.githubClient.GetPRAuthor(msg.Link)
+	log.Printf("📥 Approval of %s requested by %s", msg.Link, formatRequestOrigin(msg))
+
+	// The client can only reach the change requests of the forge instance it is configured for
+	if msg.Link.ProviderName() != c.forge.Name() || !strings.EqualFold(msg.Link.HostName(), c.forge.Host()) {
+		err := fmt.Errorf("PR is hosted on %s %s but this approver is connected to %s %s",
+			msg.Link.ProviderName(), msg.Link.HostName(), c.forge.Name(), c.forge.Host())
+		return c.replyApproveFailure(conn, reqID, err)
+	}
+
+	pr, err := c.forge.GetChange(msg.Link)
 	if err != nil {
-		return fmt.Errorf("failed to get PR author: %w", err)
+		err = fmt.Errorf("failed to get PR: %w", err)
+		return c.replyApproveFailure(conn, reqID, err)
 	}
 
 	// If the author is the same as the current user, respond with an error
-	if author == c.githubUsername {
-		err = protocol.WriteWithRequestID(conn, protocol.ApproveResponseMessage{
+	if pr.Author == c.username {
+		return c.sendApproveResponse(conn, reqID, protocol.ApproveResponseMessage{
 			Response: protocol.ApproveResponseErrSameAuthor,
-		}, reqID)
-		if err != nil {
-			return fmt.Errorf("failed to send response: %w", err)
+		})
+	}
+
+	// Never approve commits pushed after the approval was requested
+	if msg.HeadSHA != "" && pr.HeadSHA != msg.HeadSHA {
+		reason := fmt.Sprintf("head moved from %s to %s since the approval was requested", msg.HeadSHA, pr.HeadSHA)
+		log.Printf("❌ PR %s not approved: %s", msg.Link, reason)
+		return c.sendApproveResponse(conn, reqID, protocol.ApproveResponseMessage{
+			Response:  protocol.ApproveResponseRejected,
+			Reason:    reason,
+			Rejection: &protocol.Rejection{Code: protocol.RejectionHeadMoved, Message: reason},
+		})
+	}
+
+	// Check the PR against the local policy
+	if c.policy != nil && c.policy.NeedsGitHub() && c.githubClient == nil {
+		err := fmt.Errorf("the local policy can only be evaluated on GitHub")
+		return c.replyApproveFailure(conn, reqID, err)
+	}
+	if c.policy != nil {
+		var files []string
+		if c.policy.NeedsFiles() {
+			files, err = c.githubClient.GetPRFiles(msg.Link)
+			if err != nil {
+				err = fmt.Errorf("failed to get PR files: %w", err)
+				return c.replyApproveFailure(conn, reqID, err)
+			}
+		}
+		rejection := c.policy.Evaluate(msg, pr, files)
+		if rejection == nil && c.policy.RequireGreenChecks {
+			// Waiting for the checks past the deadline of the server is useless, another approver is tried
+			ctx := c.ctx
+			if !msg.Deadline.IsZero() {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithDeadline(ctx, msg.Deadline)
+				defer cancel()
+			}
+			summary, err := c.githubClient.WaitForChecks(ctx, msg.Link.Owner, msg.Link.Repo, pr.HeadSHA,
+				c.policy.RequiredChecks, c.policy.ChecksWaitTimeout, c.checksPollInterval)
+			if err != nil {
+				err = fmt.Errorf("failed to get PR checks: %w", err)
+				return c.replyApproveFailure(conn, reqID, err)
+			}
+			rejection = checksRejection(summary)
+		}
+		if rejection != nil {
+			log.Printf("❌ PR %s not approved: %s", msg.Link, rejection.Message)
+			return c.sendApproveResponse(conn, reqID, protocol.ApproveResponseMessage{
+				Response:  protocol.ApproveResponseRejected,
+				Reason:    rejection.Message,
+				Rejection: rejection,
+			})
 		}
-		return nil
 	}
 
-	// Optionally, check if already approved (commented out)
-	// alreadyApproved, err := c.githubClient.IsPRAproved(msg.Link)
-	// if err != nil {
-	// 	return fmt.Errorf("failed to check PR approval: %w", err)
-	// }
-	// if alreadyApproved {
-	// 	log.Printf("PR %s already approved by this user", msg.Link)
-	// 	return nil
-	// }
+	// In confirm mode, let the user decide whether the PR gets approved
+	if c.confirmer != nil {
+		// The server hands the request over to another approver once its deadline passes, the prompt must
+		// not outlive it
+		timeout := c.confirmTimeout
+		if !msg.Deadline.IsZero() {
+			timeout = min(timeout, time.Until(msg.Deadline))
+		}
+		summary := formatApprovalSummary(msg, pr)
+		if timeout <= 0 || !c.confirmer.Confirm(summary, timeout) {
+			log.Printf("❌ PR %s not approved: refused by the user", msg.Link)
+			return c.sendApproveResponse(conn, reqID, protocol.ApproveResponseMessage{
+				Response: protocol.ApproveResponseRefused,
+				Reason:   "not confirmed by the approver",
+			})
+		}
+		if !msg.Deadline.IsZero() && time.Now().After(msg.Deadline) {
+			log.Printf("❌ PR %s not approved: confirmed after the deadline of the server", msg.Link)
+			return c.sendApproveResponse(conn, reqID, protocol.ApproveResponseMessage{
+				Response: protocol.ApproveResponseRefused,
+				Reason:   "confirmed after the deadline",
+			})
+		}
+	}
 
-	// Attempt to approve the PR
-	err = c.githubClient.ApprovePR(msg.Link, "lgtm")
+	// Some forges refuse a second approval from the same user, report the existing one instead if it applies to
+	// the requested commit
+	state, err := c.forge.GetApprovalState(msg.Link, msg.HeadSHA)
 	if err != nil {
-		return fmt.Errorf("failed to approve PR: %w", err)
+		err = fmt.Errorf("failed to get PR approvals: %w", err)
+		return c.replyApproveFailure(conn, reqID, err)
+	}
+	if slices.Contains(state.Approvers, c.username) {
+		log.Printf("✅ PR %s already approved by this user", msg.Link)
+		return c.sendApproveResponse(conn, reqID, protocol.ApproveResponseMessage{
+			Response: protocol.ApproveResponseSuccess,
+		})
+	}
+
+	// Attempt to approve the PR, pinned to the requested commit if any
+	err = c.forge.Approve(msg.Link, msg.HeadSHA, formatReviewBody(msg))
+	if err != nil {
+		err = fmt.Errorf("failed to approve PR: %w", err)
+		return c.replyApproveFailure(conn, reqID, err)
 	}
 
 	// Respond with success
-	err = protocol.WriteWithRequestID(conn, protocol.ApproveResponseMessage{
+	err = c.sendApproveResponse(conn, reqID, protocol.ApproveResponseMessage{
 		Response: protocol.ApproveResponseSuccess,
-	}, reqID)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to send response: %w", err)
+		return err
 	}
 	log.Printf("✅ PR %s approved successfully!", msg.Link)
 	return nil
 }
 
+// replyApproveFailure notifies the server that the approval failed with the given error.
+// It always returns a non-nil error so that the failure is also reported locally.
+func (c *Client) replyApproveFailure(conn *websocket.Conn, reqID string, cause error) error {
+	err := c.sendApproveResponse(conn, reqID, protocol.ApproveResponseMessage{
+		Response: protocol.ApproveResponseErrFailed,
+		Reason:   cause.Error(),
+	})
+	if err != nil {
+		return fmt.Errorf("%w (%w)", cause, err)
+	}
+	return cause
+}
+
+// sendApproveResponse writes an ApproveResponseMessage to the server for the given request.
+func (c *Client) sendApproveResponse(conn *websocket.Conn, reqID string, resp protocol.ApproveResponseMessage) error {
+	c.wsMu.Lock()
+	defer c.wsMu.Unlock()
+	err := protocol.WriteWithRequestID(conn, resp, reqID)
+	if err != nil {
+		return fmt.Errorf("failed to send response: %w", err)
+	}
+	return nil
+}
+
 // registerApprover registers the client as an approver for its repositories with the server.
 // It retrieves the list of repos this client can approve using the GitHub token and sends a registration message.
 func (c *Client) registerApprover(conn *websocket.Conn) error {
-	repos, err := c.githubClient.GetRepos()
+	repos, err := c.forge.ListRepos()
 	if err != nil {
-		return fmt.Errorf("failed to retrieve repos from github: %w", err)
+		return fmt.Errorf("failed to retrieve repos from %s: %w", c.forge.Name(), err)
 	}
 
-	userLogin, err := c.githubClient.GetAuthenticatedUserLogin()
+	userLogin, err := c.forge.CurrentUser()
 	if err != nil {
 		return fmt.Errorf("failed to retrieve user login: %w", err)
 	}
 
-	fmt.Println("You are registered as approver for the following repositories:")
-	for _, repo := range repos {
-		fmt.Println("- " + repo)
-	}
-	fmt.Println()
+	c.registrationMu.Lock()
+	defer c.registrationMu.Unlock()
+	included, excluded := c.repoFilter.Split(repos)
+	fmt.Print(formatRepoListing(repos, excluded))
 
 	reg := protocol.RegisterRequestMessage{
-		Repos:      repos,
+		Provider:   c.forge.Name(),
+		Host:       c.forge.Host(),
+		Repos:      included,
 		GithubUser: userLogin,
 	}
 	if _, err := protocol.Write(conn, reg); err != nil {
 		return fmt.Errorf("failed to write json message: %w", err)
 	}
+	c.registeredRepos = included
 
 	return nil
 }
+
+// refreshRegistration discovers the repositories of the approver again and, if they changed since the last
+// registration, sends the repositories to add and remove to the server without reconnecting.
+func (c *Client) refreshRegistration(conn *websocket.Conn) error {
+	repos, err := c.forge.ListRepos()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve repos from %s: %w", c.forge.Name(), err)
+	}
+
+	c.registrationMu.Lock()
+	defer c.registrationMu.Unlock()
+	included, _ := c.repoFilter.Split(repos)
+	added, removed := diffRepos(c.registeredRepos, included)
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+
+	c.wsMu.Lock()
+	_, err = protocol.Write(conn, protocol.UpdateRegistrationMessage{Added: added, Removed: removed})
+	c.wsMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to write json message: %w", err)
+	}
+	c.registeredRepos = included
+
+	for _, repo := range added {
+		log.Printf("➕ Registered as approver for %s", repo)
+	}
+	for _, repo := range removed {
+		log.Printf("➖ No longer registered as approver for %s", repo)
+	}
+	return nil
+}
+
+// diffRepos returns the repositories of next which are not in prev, and the ones of prev which are not in next.
+func diffRepos(prev, next []string) (added, removed []string) {
+	for _, repo := range next {
+		if !slices.Contains(prev, repo) {
+			added = append(added, repo)
+		}
+	}
+	for _, repo := range prev {
+		if !slices.Contains(next, repo) {
+			removed = append(removed, repo)
+		}
+	}
+	return added, removed
+}
+
+// formatRepoListing lists the repositories the approver can approve for, marking the ones excluded by the
+// repository filter.
+func formatRepoListing(repos, excluded []string) string {
+	var sb strings.Builder
+	sb.WriteString("You are registered as approver for the following repositories:\n")
+	for _, repo := range repos {
+		if slices.Contains(excluded, repo) {
+			fmt.Fprintf(&sb, "- %s (excluded)\n", repo)
+		} else {
+			fmt.Fprintf(&sb, "- %s\n", repo)
+		}
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// formatRequestOrigin describes who requested the approval, when and why.
+func formatRequestOrigin(msg protocol.ApproveRequestMessage) string {
+	requester := msg.Requester
+	if requester == "" {
+		requester = "unknown"
+	}
+	var sb strings.Builder
+	sb.WriteString(requester)
+	if !msg.RequestedAt.IsZero() {
+		fmt.Fprintf(&sb, " at %s", msg.RequestedAt.UTC().Format(time.RFC3339))
+	}
+	if msg.Justification != "" {
+		fmt.Fprintf(&sb, " (justification: %q)", stripControlChars(msg.Justification))
+	}
+	return sb.String()
+}
+
+// formatReviewBody renders the body of the approval review, recording who requested it and why.
+func formatReviewBody(msg protocol.ApproveRequestMessage) string {
+	if msg.Requester == "" {
+		return "lgtm"
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "lgtm\n\nApproval requested by @%s", msg.Requester)
+	if !msg.RequestedAt.IsZero() {
+		fmt.Fprintf(&sb, " at %s", msg.RequestedAt.UTC().Format(time.RFC3339))
+	}
+	sb.WriteString(".")
+	if msg.Justification != "" {
+		// Quote every line of the justification so that it cannot break the layout of the review, and keep the
+		// requester from notifying users and teams through the approver's review.
+		justification := escapeMentions(stripControlChars(msg.Justification))
+		sb.WriteString("\n\n> ")
+		sb.WriteString(strings.ReplaceAll(justification, "\n", "\n> "))
+	}
+	return sb.String()
+}
+
+// stripControlChars removes the control characters but the line feeds and tabs from text chosen by the requester,
+// such as terminal escape sequences.
+func stripControlChars(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\n' && r != '\t' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// mentionRegexp matches the @mentions of users and teams, but not the email addresses.
+var mentionRegexp = regexp.MustCompile(`(^|[^\w])(@[\w-]+(?:/[\w.-]+)?)`)
+
+// escapeMentions wraps the @mentions in code spans so that the forges do not notify the mentioned users.
+func escapeMentions(s string) string {
+	return mentionRegexp.ReplaceAllString(s, "$1`$2`")
+}
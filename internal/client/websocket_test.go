@@ -0,0 +1,160 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clems4ever/lgtm/internal/forge"
+	"github.com/clems4ever/lgtm/internal/protocol"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatReviewBody(t *testing.T) {
+	require.Equal(t, "lgtm", formatReviewBody(protocol.ApproveRequestMessage{}))
+
+	body := formatReviewBody(protocol.ApproveRequestMessage{
+		Requester:     "carol",
+		Justification: "hotfix\nfor the outage",
+		RequestedAt:   time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+	require.Equal(t, "lgtm\n\nApproval requested by @carol at 2025-01-02T03:04:05Z.\n\n> hotfix\n> for the outage", body)
+
+	// Mentions do not notify anyone and escape sequences are dropped.
+	body = formatReviewBody(protocol.ApproveRequestMessage{
+		Requester:     "carol",
+		Justification: "ping @alice and @acme/ops-team,\x1b[31m mail carol@example.com\r",
+	})
+	require.Equal(t, "lgtm\n\nApproval requested by @carol.\n\n> ping `@alice` and `@acme/ops-team`,[31m mail carol@example.com", body)
+}
+
+func TestFormatRequestOrigin(t *testing.T) {
+	origin := formatRequestOrigin(protocol.ApproveRequestMessage{Requester: "carol", Justification: "hot\x1b]0;pwned\x07fix"})
+	require.Equal(t, `carol (justification: "hot]0;pwnedfix")`, origin)
+}
+
+func TestFormatRepoListing(t *testing.T) {
+	listing := formatRepoListing([]string{"acme/api", "acme/legacy-web"}, []string{"acme/legacy-web"})
+	require.Equal(t, "You are registered as approver for the following repositories:\n"+
+		"- acme/api\n"+
+		"- acme/legacy-web (excluded)\n\n", listing)
+}
+
+func TestDiffRepos(t *testing.T) {
+	added, removed := diffRepos([]string{"foo/a", "foo/b"}, []string{"foo/b", "foo/c"})
+	require.Equal(t, []string{"foo/c"}, added)
+	require.Equal(t, []string{"foo/a"}, removed)
+
+	added, removed = diffRepos([]string{"foo/a"}, []string{"foo/a"})
+	require.Empty(t, added)
+	require.Empty(t, removed)
+}
+
+// fakeForge is a forge.Provider whose repositories and change request can be changed by the tests.
+type fakeForge struct {
+	forge.Provider
+	repos    []string
+	change   forge.Change
+	approved int
+}
+
+func (f *fakeForge) Name() string                 { return forge.ProviderGitHub }
+func (f *fakeForge) Host() string                 { return "github.com" }
+func (f *fakeForge) ListRepos() ([]string, error) { return f.repos, nil }
+func (f *fakeForge) GetChange(forge.ChangeLink) (*forge.Change, error) {
+	return &f.change, nil
+}
+func (f *fakeForge) GetApprovalState(forge.ChangeLink, string) (*forge.ApprovalState, error) {
+	return &forge.ApprovalState{}, nil
+}
+func (f *fakeForge) Approve(forge.ChangeLink, string, string) error {
+	f.approved++
+	return nil
+}
+
+// fakeConfirmer confirms every approval after the given delay and records the timeout it was given.
+type fakeConfirmer struct {
+	delay   time.Duration
+	timeout time.Duration
+}
+
+func (f *fakeConfirmer) Confirm(summary string, timeout time.Duration) bool {
+	f.timeout = timeout
+	time.Sleep(f.delay)
+	return true
+}
+
+// dialRecorder connects to a websocket server which forwards the messages it receives to the returned channel.
+func dialRecorder(t *testing.T) (*websocket.Conn, chan protocol.Message) {
+	received := make(chan protocol.Message, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			var msg protocol.Message
+			if err := protocol.Read(conn, &msg); err != nil {
+				return
+			}
+			received <- msg
+		}
+	}))
+	t.Cleanup(ts.Close)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn, received
+}
+
+func TestHandleApproveMessage_ConfirmCappedToDeadline(t *testing.T) {
+	conn, received := dialRecorder(t)
+	link := forge.ChangeLink{Provider: forge.ProviderGitHub, Host: "github.com", Owner: "acme", Repo: "api", PRNumber: 1}
+	f := &fakeForge{change: forge.Change{Author: "bob", HeadSHA: "abc"}}
+	confirmer := &fakeConfirmer{}
+	c := &Client{forge: f, username: "alice", confirmer: confirmer, confirmTimeout: time.Minute}
+
+	// The prompt does not outlive the deadline of the server
+	msg := protocol.ApproveRequestMessage{Link: link, HeadSHA: "abc", Deadline: time.Now().Add(time.Second)}
+	require.NoError(t, c.handleApproveMessage(conn, "1", msg))
+	require.LessOrEqual(t, confirmer.timeout, time.Second)
+	require.Equal(t, 1, f.approved)
+	require.Equal(t, protocol.ApproveResponseSuccess, (<-received).Message.(protocol.ApproveResponseMessage).Response)
+
+	// A confirmation arriving after the deadline is not submitted, another approver took over
+	confirmer.delay = 100 * time.Millisecond
+	msg.Deadline = time.Now().Add(50 * time.Millisecond)
+	require.NoError(t, c.handleApproveMessage(conn, "2", msg))
+	require.Equal(t, 1, f.approved)
+	require.Equal(t, protocol.ApproveResponseRefused, (<-received).Message.(protocol.ApproveResponseMessage).Response)
+}
+
+func TestRefreshRegistration(t *testing.T) {
+	conn, received := dialRecorder(t)
+
+	f := &fakeForge{repos: []string{"acme/api", "acme/web", "acme/legacy-app"}}
+	filter, err := ParseRepoFilter([]string{"!acme/legacy-*"})
+	require.NoError(t, err)
+	c := &Client{forge: f, repoFilter: filter, registeredRepos: []string{"acme/api", "acme/old"}}
+
+	require.NoError(t, c.refreshRegistration(conn))
+	select {
+	case msg := <-received:
+		require.Equal(t, protocol.UpdateRegistrationMessage{Added: []string{"acme/web"}, Removed: []string{"acme/old"}}, msg.Message)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no registration update received")
+	}
+	require.Equal(t, []string{"acme/api", "acme/web"}, c.registeredRepos)
+
+	// Nothing is sent when the repositories did not change
+	require.NoError(t, c.refreshRegistration(conn))
+	select {
+	case msg := <-received:
+		t.Fatalf("unexpected message %+v", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
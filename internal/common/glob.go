@@ -0,0 +1,42 @@
+package common
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MatchGlob tells whether the slash-separated name matches the glob pattern.
+// '*' matches any sequence of characters except '/', '**' matches any sequence of characters
+// including '/', "**/" matches zero or more directories and '?' matches one character except '/'.
+func MatchGlob(pattern, name string) bool {
+	return globToRegexp(pattern).MatchString(name)
+}
+
+// globToRegexp converts a glob pattern into an anchored regular expression.
+func globToRegexp(pattern string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					sb.WriteString("(?:.*/)?")
+				} else {
+					sb.WriteString(".*")
+				}
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	// Every special character is either translated or quoted, so the expression is always valid.
+	return regexp.MustCompile(sb.String())
+}
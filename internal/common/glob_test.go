@@ -0,0 +1,29 @@
+package common
+
+import "testing"
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"acme/*", "acme/api", true},
+		{"acme/*", "acme/api/v2", false},
+		{"acme/legacy-*", "acme/legacy-billing", true},
+		{"**/migrations/**", "db/migrations/001.sql", true},
+		{"**/migrations/**", "migrations/001.sql", true},
+		{"**/migrations/**", "db/migration/001.sql", false},
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/main.go", false},
+		{"release/?", "release/1", true},
+		{"release/?", "release/10", false},
+		{"main", "main", true},
+		{"main", "maintenance", false},
+	}
+	for _, tt := range tests {
+		if got := MatchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Package forge abstracts the code hosting platforms, such as GitHub and GitLab, whose change requests lgtm approves.
+package forge
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrUnsupportedHost is returned when a change request URL does not point to any of the configured forges.
+var ErrUnsupportedHost = fmt.Errorf("unsupported host")
+
+// Change holds the metadata of a change request used to decide whether to approve it.
+type Change struct {
+	Title string
+	// State is "open" while the change request can still be merged.
+	State  string
+	Author string
+	// BaseBranch is the branch the change request is merged into.
+	BaseBranch string
+	// HeadSHA is the current head commit of the change request.
+	HeadSHA      string
+	Additions    int
+	Deletions    int
+	ChangedFiles int
+}
+
+// ApprovalState holds the approvals a change request collected.
+type ApprovalState struct {
+	// Approved tells whether the change request is approved according to the rules of the forge.
+	Approved bool
+	// Approvers are the users whose approval is currently valid.
+	Approvers []string
+}
+
+// Provider is a forge instance, accessed on behalf of one user.
+type Provider interface {
+	// Name returns the provider of the forge, e.g. ProviderGitHub.
+	Name() string
+	// Host returns the web host of the forge instance.
+	Host() string
+	// ParseChangeURL parses the URL of a change request of the forge instance.
+	ParseChangeURL(rawURL string) (ChangeLink, error)
+	// CurrentUser returns the username of the authenticated user.
+	CurrentUser() (string, error)
+	// GetChange returns the change request, including its author and its head commit.
+	GetChange(link ChangeLink) (*Change, error)
+	// Approve approves the change request on behalf of the authenticated user. If headSHA is not empty, the approval
+	// only applies to that commit. message is attached to the approval.
+	Approve(link ChangeLink, headSHA, message string) error
+	// ListRepos returns the "owner/repo" names of the repositories whose change requests the authenticated user can approve.
+	ListRepos() ([]string, error)
+	// GetApprovalState returns the approvals of the change request. If headSHA is not empty, only the approvals
+	// that apply to that commit are reported.
+	GetApprovalState(link ChangeLink, headSHA string) (*ApprovalState, error)
+}
+
+// ParseChangeURL parses the URL of a change request with the provider serving its host.
+// ErrUnsupportedHost is returned if none of the providers serves the host.
+func ParseChangeURL(rawURL string, providers ...Provider) (ChangeLink, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return ChangeLink{}, fmt.Errorf("invalid URL: %w", err)
+	}
+	for _, p := range providers {
+		if strings.EqualFold(u.Host, p.Host()) {
+			return p.ParseChangeURL(rawURL)
+		}
+	}
+	return ChangeLink{}, fmt.Errorf("%w %q", ErrUnsupportedHost, u.Host)
+}
@@ -0,0 +1,84 @@
+package forge
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// ProviderGitHub identifies github.com and GitHub Enterprise Server.
+	ProviderGitHub = "github"
+	// ProviderGitLab identifies gitlab.com and self-hosted GitLab instances.
+	ProviderGitLab = "gitlab"
+	// ProviderGitea identifies self-hosted Gitea and Forgejo instances, which share the same API.
+	ProviderGitea = "gitea"
+)
+
+// defaultHosts are the web hosts of the public instances of the providers. Gitea has no public instance.
+var defaultHosts = map[string]string{
+	ProviderGitHub: "github.com",
+	ProviderGitLab: "gitlab.com",
+}
+
+// ChangeLink identifies a change request, e.g. a GitHub pull request or a GitLab merge request, by provider,
+// host, repository and number. Links persisted before providers were introduced have no provider and no host,
+// they point to github.com.
+type ChangeLink struct {
+	Provider string `json:",omitempty"` // Forge provider, github if empty
+	Host     string // Web host of the forge instance, the public instance of the provider if empty
+	Owner    string // Repository owner, the namespace of the project on GitLab (e.g. "group/subgroup")
+	Repo     string // Repository name
+	PRNumber int    // Number of the pull request, or IID of the merge request
+}
+
+// ProviderName returns the provider of the change request, github if the provider is not set.
+func (l *ChangeLink) ProviderName() string {
+	if l.Provider == "" {
+		return ProviderGitHub
+	}
+	return l.Provider
+}
+
+// HostName returns the web host of the forge instance of the change request, the public instance of the
+// provider if the host is not set.
+func (l *ChangeLink) HostName() string {
+	if l.Host == "" {
+		return defaultHosts[l.ProviderName()]
+	}
+	return l.Host
+}
+
+// RepoFullName returns the "owner/repo" string for the change request.
+// This is useful for identifying the repository in a concise format.
+func (l *ChangeLink) RepoFullName() string {
+	return fmt.Sprintf("%s/%s", l.Owner, l.Repo)
+}
+
+// RepoID returns the provider-qualified identifier of the repository of the change request, see RepoID.
+func (l *ChangeLink) RepoID() string {
+	return RepoID(l.ProviderName(), l.HostName(), l.RepoFullName())
+}
+
+// String returns the canonical URL of the change request.
+func (l ChangeLink) String() string {
+	switch l.ProviderName() {
+	case ProviderGitLab:
+		return fmt.Sprintf("https://%s/%s/%s/-/merge_requests/%d", l.HostName(), l.Owner, l.Repo, l.PRNumber)
+	case ProviderGitea:
+		return fmt.Sprintf("https://%s/%s/%s/pulls/%d", l.HostName(), l.Owner, l.Repo, l.PRNumber)
+	}
+	return fmt.Sprintf("https://%s/%s/%s/pull/%d", l.HostName(), l.Owner, l.Repo, l.PRNumber)
+}
+
+// RepoID returns the "provider:host/owner/repo" string identifying a repository, given as "owner/repo", across
+// providers and instances, e.g. "github:github.com/foo/bar" or "gitlab:gitlab.example.com/group/project".
+// If provider is empty, github is used. If host is empty, the public instance of the provider is used.
+func RepoID(provider, host, fullName string) string {
+	if provider == "" {
+		provider = ProviderGitHub
+	}
+	if host == "" {
+		host = defaultHosts[provider]
+	}
+	return provider + ":" + strings.ToLower(host) + "/" + fullName
+}
@@ -0,0 +1,47 @@
+package forge
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestChangeLink_Defaults(t *testing.T) {
+	// Links persisted before providers were introduced point to github.com
+	var link ChangeLink
+	if err := json.Unmarshal([]byte(`{"Owner":"foo","Repo":"bar","PRNumber":1}`), &link); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if link.RepoID() != "github:github.com/foo/bar" {
+		t.Errorf("unexpected repo ID %q", link.RepoID())
+	}
+	if link.String() != "https://github.com/foo/bar/pull/1" {
+		t.Errorf("unexpected URL %q", link.String())
+	}
+}
+
+func TestChangeLink_GitLab(t *testing.T) {
+	link := ChangeLink{Provider: ProviderGitLab, Owner: "group/sub", Repo: "project", PRNumber: 4}
+	if link.RepoID() != "gitlab:gitlab.com/group/sub/project" {
+		t.Errorf("unexpected repo ID %q", link.RepoID())
+	}
+	if link.String() != "https://gitlab.com/group/sub/project/-/merge_requests/4" {
+		t.Errorf("unexpected URL %q", link.String())
+	}
+}
+
+func TestRepoID(t *testing.T) {
+	tests := []struct {
+		provider, host, fullName string
+		want                     string
+	}{
+		{"", "", "foo/bar", "github:github.com/foo/bar"},
+		{"github", "GHE.example.com", "foo/bar", "github:ghe.example.com/foo/bar"},
+		{"gitlab", "", "group/project", "gitlab:gitlab.com/group/project"},
+		{"gitlab", "gitlab.example.com", "group/project", "gitlab:gitlab.example.com/group/project"},
+	}
+	for _, tt := range tests {
+		if got := RepoID(tt.provider, tt.host, tt.fullName); got != tt.want {
+			t.Errorf("RepoID(%q, %q, %q) = %q, want %q", tt.provider, tt.host, tt.fullName, got, tt.want)
+		}
+	}
+}
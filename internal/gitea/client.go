@@ -0,0 +1,93 @@
+// Package gitea implements the forge provider of Gitea and of its fork Forgejo, whose pull requests are the
+// change requests of the gitea provider.
+package gitea
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/clems4ever/lgtm/internal/forge"
+)
+
+// pageSize is the number of items requested per page, the default maximum of Gitea instances.
+const pageSize = 50
+
+// Client represents a Gitea or Forgejo API client, handling authentication and requests.
+// It implements forge.Provider for the instance it is configured for.
+type Client struct {
+	httpClient  *http.Client // HTTP client used for requests
+	accessToken string       // Gitea access token
+	host        string       // Web host of the instance (e.g. "git.example.com")
+	apiBaseURL  string       // Base URL for the API (e.g., "https://git.example.com/api/v1")
+}
+
+var _ forge.Provider = (*Client)(nil)
+
+// NewClient creates a new Gitea API client with the given access token, web host, API base URL, and optional
+// HTTP client. Gitea instances are self-hosted, so the host is required. If apiBaseURL is empty, it is derived
+// from the host. If httpClient is nil, http.DefaultClient is used.
+//
+// Parameters:
+// - accessToken: Gitea access token for authorization.
+// - host: Web host of the Gitea or Forgejo instance.
+// - apiBaseURL: Base URL for API requests.
+// - httpClient: Optional HTTP client for making requests.
+//
+// Returns:
+// - A new instance of the Gitea API client.
+func NewClient(accessToken, host, apiBaseURL string, httpClient *http.Client) *Client {
+	if apiBaseURL == "" {
+		apiBaseURL = APIURLForHost(host)
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		httpClient:  httpClient,
+		accessToken: accessToken,
+		host:        strings.ToLower(host),
+		apiBaseURL:  apiBaseURL,
+	}
+}
+
+// APIURLForHost returns the base URL of the REST API of the Gitea or Forgejo instance with the given web host.
+func APIURLForHost(host string) string {
+	return "https://" + host + "/api/v1"
+}
+
+// Name returns forge.ProviderGitea.
+func (c *Client) Name() string {
+	return forge.ProviderGitea
+}
+
+// Host returns the web host of the instance.
+func (c *Client) Host() string {
+	return c.host
+}
+
+// doNewRequest creates and executes an HTTP request with the authorization header of the client.
+func (c *Client) doNewRequest(method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.apiBaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "token "+c.accessToken)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.httpClient.Do(req)
+}
+
+// decodeJSONResponse decodes the body of a successful Gitea API response into v and closes it.
+func decodeJSONResponse(resp *http.Response, v any) error {
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Gitea API error: %s", string(data))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
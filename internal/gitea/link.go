@@ -0,0 +1,59 @@
+package gitea
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/clems4ever/lgtm/internal/forge"
+)
+
+// ParsePullRequestURL extracts host, owner, repo, and PR number from a Gitea or Forgejo pull request URL,
+// e.g. https://git.example.com/owner/repo/pulls/42.
+//
+// Parameters:
+// - link: The pull request URL to parse.
+//
+// Returns:
+// - A ChangeLink containing the host, owner, repo, and PR number.
+// - An error if the URL is invalid or does not match the expected pull request format.
+func ParsePullRequestURL(link string) (forge.ChangeLink, error) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return forge.ChangeLink{}, fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Host == "" {
+		return forge.ChangeLink{}, fmt.Errorf("invalid PR link format: missing host")
+	}
+
+	// Expect: /{owner}/{repo}/pulls/{number}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 4 || parts[0] == "" || parts[1] == "" || parts[2] != "pulls" {
+		return forge.ChangeLink{}, fmt.Errorf("invalid PR link format")
+	}
+	prNumber, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return forge.ChangeLink{}, fmt.Errorf("invalid PR number: %w", err)
+	}
+
+	return forge.ChangeLink{
+		Provider: forge.ProviderGitea,
+		Host:     strings.ToLower(u.Host),
+		Owner:    parts[0],
+		Repo:     parts[1],
+		PRNumber: prNumber,
+	}, nil
+}
+
+// ParseChangeURL parses the URL of a pull request of the Gitea or Forgejo instance.
+func (c *Client) ParseChangeURL(rawURL string) (forge.ChangeLink, error) {
+	link, err := ParsePullRequestURL(rawURL)
+	if err != nil {
+		return forge.ChangeLink{}, err
+	}
+	if link.Host != c.host {
+		return forge.ChangeLink{}, fmt.Errorf("%w %q", forge.ErrUnsupportedHost, link.Host)
+	}
+	return link, nil
+}
@@ -0,0 +1,60 @@
+package gitea
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/clems4ever/lgtm/internal/forge"
+)
+
+func TestParsePullRequestURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    forge.ChangeLink
+		wantErr bool
+	}{
+		{url: "https://git.example.com/foo/bar/pulls/12",
+			want: forge.ChangeLink{Provider: "gitea", Host: "git.example.com", Owner: "foo", Repo: "bar", PRNumber: 12}},
+		{url: "https://Codeberg.org/foo/bar/pulls/3/files",
+			want: forge.ChangeLink{Provider: "gitea", Host: "codeberg.org", Owner: "foo", Repo: "bar", PRNumber: 3}},
+		{url: "https://git.example.com/foo/bar/pull/12", wantErr: true},
+		{url: "https://git.example.com/foo/bar/issues/12", wantErr: true},
+		{url: "https://git.example.com/foo/bar/pulls/abc", wantErr: true},
+		{url: "/foo/bar/pulls/12", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePullRequestURL(tt.url)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %+v", tt.url, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.url, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestClient_ParseChangeURL(t *testing.T) {
+	c := NewClient("dummy", "git.example.com", "", nil)
+
+	link, err := forge.ParseChangeURL("https://git.example.com/foo/bar/pulls/1", c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if link.RepoID() != "gitea:git.example.com/foo/bar" {
+		t.Errorf("unexpected repo ID %q", link.RepoID())
+	}
+	if link.String() != "https://git.example.com/foo/bar/pulls/1" {
+		t.Errorf("unexpected URL %q", link.String())
+	}
+
+	if _, err := forge.ParseChangeURL("https://codeberg.org/foo/bar/pulls/1", c); !errors.Is(err, forge.ErrUnsupportedHost) {
+		t.Errorf("expected ErrUnsupportedHost, got %v", err)
+	}
+}
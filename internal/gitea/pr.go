@@ -0,0 +1,186 @@
+package gitea
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/clems4ever/lgtm/internal/forge"
+)
+
+// PullRequest holds the metadata of a pull request.
+type PullRequest struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	// State is open or closed, merged pull requests are closed.
+	State string `json:"state"`
+	User  struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+	Head struct {
+		SHA string `json:"sha"`
+	} `json:"head"`
+	Additions    int `json:"additions"`
+	Deletions    int `json:"deletions"`
+	ChangedFiles int `json:"changed_files"`
+}
+
+// Author returns the login of the author of the pull request.
+func (pr *PullRequest) Author() string {
+	return pr.User.Login
+}
+
+// Review is a review submitted on a pull request.
+type Review struct {
+	ID   int `json:"id"`
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	// State is APPROVED, REQUEST_CHANGES, COMMENT, PENDING or REQUEST_REVIEW.
+	State string `json:"state"`
+	// Dismissed is true once a maintainer dismissed the review.
+	Dismissed bool `json:"dismissed"`
+	// Stale is true once commits were pushed after the review.
+	Stale bool `json:"stale"`
+	// CommitID is the head commit of the pull request when the review was submitted.
+	CommitID string `json:"commit_id"`
+}
+
+// GetPullRequest retrieves the metadata of the given pull request.
+//
+// Parameters:
+// - link: A ChangeLink representing the pull request.
+//
+// Returns:
+// - The pull request metadata.
+// - An error if the API request fails or the response cannot be parsed.
+func (c *Client) GetPullRequest(link forge.ChangeLink) (*PullRequest, error) {
+	resp, err := c.doNewRequest("GET", fmt.Sprintf("/repos/%s/%s/pulls/%d", link.Owner, link.Repo, link.PRNumber), nil)
+	if err != nil {
+		return nil, err
+	}
+	var pr PullRequest
+	if err := decodeJSONResponse(resp, &pr); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// GetChange returns the pull request.
+func (c *Client) GetChange(link forge.ChangeLink) (*forge.Change, error) {
+	pr, err := c.GetPullRequest(link)
+	if err != nil {
+		return nil, err
+	}
+	return &forge.Change{
+		Title:        pr.Title,
+		State:        pr.State,
+		Author:       pr.Author(),
+		BaseBranch:   pr.Base.Ref,
+		HeadSHA:      pr.Head.SHA,
+		Additions:    pr.Additions,
+		Deletions:    pr.Deletions,
+		ChangedFiles: pr.ChangedFiles,
+	}, nil
+}
+
+// Approve submits an approving review on the pull request, pinned to headSHA if not empty.
+//
+// Parameters:
+// - link: A ChangeLink representing the pull request.
+// - headSHA: The SHA of the commit to approve. If empty, the review applies to the current head of the PR.
+// - message: The body of the review.
+//
+// Returns:
+// - An error if the API request fails or the response indicates an error.
+func (c *Client) Approve(link forge.ChangeLink, headSHA, message string) error {
+	body, err := json.Marshal(struct {
+		Event    string `json:"event"`
+		Body     string `json:"body"`
+		CommitID string `json:"commit_id,omitempty"`
+	}{Event: "APPROVED", Body: message, CommitID: headSHA})
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("/repos/%s/%s/pulls/%d/reviews", link.Owner, link.Repo, link.PRNumber)
+	resp, err := c.doNewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Gitea API error: %s", string(data))
+	}
+	return nil
+}
+
+// ListReviews returns the reviews submitted on the pull request, oldest first.
+//
+// Parameters:
+// - link: A ChangeLink representing the pull request.
+//
+// Returns:
+// - The reviews of the pull request.
+// - An error if the API request fails or the response cannot be parsed.
+func (c *Client) ListReviews(link forge.ChangeLink) ([]Review, error) {
+	var reviews []Review
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("/repos/%s/%s/pulls/%d/reviews?limit=%d&page=%d", link.Owner, link.Repo, link.PRNumber, pageSize, page)
+		resp, err := c.doNewRequest("GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		var l []Review
+		if err := decodeJSONResponse(resp, &l); err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, l...)
+		if len(l) < pageSize {
+			return reviews, nil
+		}
+	}
+}
+
+// GetApprovalState returns the users whose latest review approves the current head of the pull request, which
+// must be headSHA if it is not empty. Dismissed reviews and reviews of other commits are ignored. The pull request
+// is considered approved if it has at least one approval and no pending request for changes.
+func (c *Client) GetApprovalState(link forge.ChangeLink, headSHA string) (*forge.ApprovalState, error) {
+	reviews, err := c.ListReviews(link)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the latest approval or request for changes of each user counts, comments do not change it.
+	latest := make(map[string]string)
+	var users []string
+	for _, r := range reviews {
+		if r.State != "APPROVED" && r.State != "REQUEST_CHANGES" {
+			continue
+		}
+		if _, ok := latest[r.User.Login]; !ok {
+			users = append(users, r.User.Login)
+		}
+		latest[r.User.Login] = r.State
+		if r.Dismissed || r.Stale || (headSHA != "" && r.CommitID != headSHA) {
+			latest[r.User.Login] = ""
+		}
+	}
+
+	state := &forge.ApprovalState{}
+	changesRequested := false
+	for _, u := range users {
+		switch latest[u] {
+		case "APPROVED":
+			state.Approvers = append(state.Approvers, u)
+		case "REQUEST_CHANGES":
+			changesRequested = true
+		}
+	}
+	state.Approved = len(state.Approvers) > 0 && !changesRequested
+	return state, nil
+}
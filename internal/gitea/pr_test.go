@@ -0,0 +1,92 @@
+package gitea
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/clems4ever/lgtm/internal/forge"
+	"github.com/clems4ever/lgtm/internal/test"
+)
+
+func newTestClient(t *testing.T) (*Client, *test.GiteaMockServer) {
+	srv := test.NewGiteaMockServer(t)
+	t.Cleanup(srv.Close)
+	srv.AddUser("alice", "alice-token", []test.Repo{
+		{FullName: "foo/bar", Permissions: test.RepoPermissions{Push: true}},
+		{FullName: "foo/readonly"},
+	})
+	srv.AddUser("bob", "bob-token", nil)
+	srv.AddPullRequest("foo/bar", 1, test.GiteaPullRequest{Title: "Fix", Author: "bob", Base: "main", HeadSHA: "abc"})
+	return NewClient("alice-token", srv.Host(), srv.APIURL(), nil), srv
+}
+
+var testLink = forge.ChangeLink{Provider: forge.ProviderGitea, Owner: "foo", Repo: "bar", PRNumber: 1}
+
+func TestGetChange(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	change, err := c.GetChange(testLink)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := forge.Change{Title: "Fix", State: "open", Author: "bob", BaseBranch: "main", HeadSHA: "abc"}
+	if *change != want {
+		t.Errorf("got %+v, want %+v", *change, want)
+	}
+
+	if _, err := c.GetChange(forge.ChangeLink{Owner: "foo", Repo: "bar", PRNumber: 2}); err == nil {
+		t.Error("expected an error for a missing pull request")
+	}
+}
+
+func TestApprove_PinsCommit(t *testing.T) {
+	c, srv := newTestClient(t)
+
+	if err := c.Approve(testLink, "abc", "lgtm"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reviews := srv.Reviews("foo/bar", 1)
+	if len(reviews) != 1 || reviews[0] != (test.GiteaReview{User: "alice", State: "APPROVED", Body: "lgtm", CommitID: "abc"}) {
+		t.Errorf("unexpected reviews %+v", reviews)
+	}
+}
+
+func TestGetApprovalState(t *testing.T) {
+	c, srv := newTestClient(t)
+
+	state, err := c.GetApprovalState(testLink, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state.Approved || len(state.Approvers) != 0 {
+		t.Errorf("expected no approval, got %+v", state)
+	}
+
+	if err := c.Approve(testLink, "abc", "lgtm"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	state, err = c.GetApprovalState(testLink, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !state.Approved || !slices.Equal(state.Approvers, []string{"alice"}) {
+		t.Errorf("expected the approval of alice, got %+v", state)
+	}
+	state, err = c.GetApprovalState(testLink, "def")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(state.Approvers) != 0 {
+		t.Errorf("expected the approval of another commit to be ignored, got %+v", state)
+	}
+
+	// Pushing a new commit makes the approval stale
+	srv.AddPullRequest("foo/bar", 1, test.GiteaPullRequest{Title: "Fix", Author: "bob", Base: "main", HeadSHA: "def"})
+	state, err = c.GetApprovalState(testLink, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state.Approved || len(state.Approvers) != 0 {
+		t.Errorf("expected the stale approval to be ignored, got %+v", state)
+	}
+}
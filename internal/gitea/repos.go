@@ -0,0 +1,72 @@
+package gitea
+
+import (
+	"fmt"
+)
+
+// RepoPermissions are the permissions of the authenticated user on a repository.
+type RepoPermissions struct {
+	Admin bool `json:"admin"`
+	Push  bool `json:"push"`
+	Pull  bool `json:"pull"`
+}
+
+// Repo is a repository with the permissions of the authenticated user on it.
+type Repo struct {
+	FullName    string          `json:"full_name"`
+	Permissions RepoPermissions `json:"permissions"`
+}
+
+// ListRepos returns the repositories the authenticated user can approve PRs for, i.e. the repositories the
+// user can push to, whether owned, shared with the user or owned by one of the user's organizations.
+func (c *Client) ListRepos() ([]string, error) {
+	var result []string
+	for page := 1; ; page++ {
+		resp, err := c.doNewRequest("GET", fmt.Sprintf("/user/repos?limit=%d&page=%d", pageSize, page), nil)
+		if err != nil {
+			return nil, err
+		}
+		var repos []Repo
+		if err := decodeJSONResponse(resp, &repos); err != nil {
+			return nil, err
+		}
+		for _, repo := range repos {
+			// Only include repos where the user has push access (can approve PRs)
+			if repo.Permissions.Push {
+				result = append(result, repo.FullName)
+			}
+		}
+		if len(repos) < pageSize {
+			return result, nil
+		}
+	}
+}
+
+// GetCollaboratorPermission returns the permission of the user on the repository: "owner", "admin", "write",
+// "read" or "none".
+func (c *Client) GetCollaboratorPermission(owner, repo, username string) (string, error) {
+	resp, err := c.doNewRequest("GET", fmt.Sprintf("/repos/%s/%s/collaborators/%s/permission", owner, repo, username), nil)
+	if err != nil {
+		return "", err
+	}
+	var p struct {
+		Permission string `json:"permission"`
+	}
+	if err := decodeJSONResponse(resp, &p); err != nil {
+		return "", err
+	}
+	return p.Permission, nil
+}
+
+// HasWriteAccess tells whether the user can push to the repository.
+func (c *Client) HasWriteAccess(owner, repo, username string) (bool, error) {
+	permission, err := c.GetCollaboratorPermission(owner, repo, username)
+	if err != nil {
+		return false, err
+	}
+	switch permission {
+	case "owner", "admin", "write":
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,73 @@
+package gitea
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/clems4ever/lgtm/internal/test"
+)
+
+func TestListRepos_OnlyWritable(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	repos, err := c.ListRepos()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(repos, []string{"foo/bar"}) {
+		t.Errorf("unexpected repos %v", repos)
+	}
+}
+
+func TestListRepos_Paginates(t *testing.T) {
+	srv := test.NewGiteaMockServer(t)
+	defer srv.Close()
+	var repos []test.Repo
+	for i := 0; i < 2*pageSize+1; i++ {
+		repos = append(repos, test.Repo{FullName: fmt.Sprintf("foo/r%d", i), Permissions: test.RepoPermissions{Push: true}})
+	}
+	srv.AddUser("alice", "alice-token", repos)
+
+	got, err := NewClient("alice-token", srv.Host(), srv.APIURL(), nil).ListRepos()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != len(repos) || got[len(got)-1] != repos[len(repos)-1].FullName {
+		t.Errorf("expected %d repos, got %d", len(repos), len(got))
+	}
+}
+
+func TestHasWriteAccess(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	for _, tt := range []struct {
+		repo, user string
+		want       bool
+	}{
+		{"bar", "alice", true},
+		{"readonly", "alice", false},
+		{"bar", "bob", false},
+	} {
+		got, err := c.HasWriteAccess("foo", tt.repo, tt.user)
+		if err != nil {
+			t.Fatalf("%s on %s: unexpected error: %v", tt.user, tt.repo, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s on %s: got %v, want %v", tt.user, tt.repo, got, tt.want)
+		}
+	}
+}
+
+func TestCurrentUser(t *testing.T) {
+	c, srv := newTestClient(t)
+
+	user, err := c.CurrentUser()
+	if err != nil || user != "alice" {
+		t.Errorf("got %q, %v", user, err)
+	}
+
+	if _, err := NewClient("bad-token", srv.Host(), srv.APIURL(), nil).CurrentUser(); err == nil {
+		t.Error("expected an error with an invalid token")
+	}
+}
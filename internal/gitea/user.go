@@ -0,0 +1,21 @@
+package gitea
+
+import "fmt"
+
+// CurrentUser returns the login of the authenticated user.
+func (c *Client) CurrentUser() (string, error) {
+	resp, err := c.doNewRequest("GET", "/user", nil)
+	if err != nil {
+		return "", err
+	}
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := decodeJSONResponse(resp, &user); err != nil {
+		return "", err
+	}
+	if user.Login == "" {
+		return "", fmt.Errorf("Gitea API error: empty login")
+	}
+	return user.Login, nil
+}
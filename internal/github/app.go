@@ -0,0 +1,200 @@
+package github
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// appJWTLifetime is the lifetime of the JWTs authenticating as a GitHub App. GitHub accepts at most 10 minutes.
+	appJWTLifetime = 9 * time.Minute
+	// appJWTClockDrift backdates the JWTs to tolerate a clock drift with GitHub.
+	appJWTClockDrift = 60 * time.Second
+	// installationTokenRefreshMargin is how long before their expiry installation tokens are refreshed.
+	installationTokenRefreshMargin = 5 * time.Minute
+)
+
+// App authenticates as a GitHub App to create installation tokens.
+type App struct {
+	appID      int64
+	privateKey *rsa.PrivateKey
+	apiBaseURL string
+	httpClient *http.Client
+	clock      func() time.Time
+}
+
+// NewApp creates a GitHub App authenticating with its ID and private key.
+// If apiBaseURL is empty, "https://api.github.com" is used.
+// If httpClient is nil, http.DefaultClient is used.
+//
+// Parameters:
+// - appID: The ID of the GitHub App.
+// - privateKey: A private key of the GitHub App, see ParsePrivateKey.
+// - apiBaseURL: Base URL for GitHub API requests.
+// - httpClient: Optional HTTP client for making requests.
+//
+// Returns:
+// - A new instance of the GitHub App.
+func NewApp(appID int64, privateKey *rsa.PrivateKey, apiBaseURL string, httpClient *http.Client) *App {
+	if apiBaseURL == "" {
+		apiBaseURL = defaultAPIBaseURL
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &App{
+		appID:      appID,
+		privateKey: privateKey,
+		apiBaseURL: apiBaseURL,
+		httpClient: httpClient,
+		clock:      time.Now,
+	}
+}
+
+// ParsePrivateKey parses the PEM-encoded private key of a GitHub App, in PKCS#1 or PKCS#8 format.
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found in private key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// JWT returns a JSON Web Token authenticating as the GitHub App, signed with RS256.
+func (a *App) JWT() (string, error) {
+	now := a.clock()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-appJWTClockDrift).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": strconv.FormatInt(a.appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign JWT: %w", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// InstallationTokenOptions narrows down the access of an installation token.
+type InstallationTokenOptions struct {
+	// Repositories restricts the token to these repositories of the installation, given by name. All
+	// the repositories of the installation are accessible if empty.
+	Repositories []string `json:"repositories,omitempty"`
+	// Permissions restricts the token to these permissions (e.g. "pull_requests": "write"). The permissions
+	// granted to the installation apply if empty.
+	Permissions map[string]string `json:"permissions,omitempty"`
+}
+
+// InstallationToken is a short-lived token acting as an installation of a GitHub App.
+type InstallationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CreateInstallationToken exchanges a JWT of the GitHub App for a token acting as the given installation.
+//
+// Parameters:
+// - installationID: The ID of the installation of the GitHub App.
+// - opts: The repositories and permissions the token is restricted to.
+//
+// Returns:
+// - The installation token, valid for one hour.
+// - An error if the API request fails or the response cannot be parsed.
+func (a *App) CreateInstallationToken(installationID int64, opts InstallationTokenOptions) (*InstallationToken, error) {
+	jwt, err := a.JWT()
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/app/installations/%d/access_tokens", a.apiBaseURL, installationID),
+		bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 201 {
+		data, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("GitHub API error: %s", string(data))
+	}
+	var token InstallationToken
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// installationTokenSource supplies the tokens of an installation, creating a new one shortly before the
+// current one expires.
+type installationTokenSource struct {
+	app            *App
+	installationID int64
+	opts           InstallationTokenOptions
+
+	mu      sync.Mutex
+	current *InstallationToken
+}
+
+// InstallationTokenSource returns a TokenSource supplying the tokens of the given installation of the GitHub App.
+// Tokens are created on first use and refreshed transparently before they expire.
+func (a *App) InstallationTokenSource(installationID int64, opts InstallationTokenOptions) TokenSource {
+	return &installationTokenSource{app: a, installationID: installationID, opts: opts}
+}
+
+// Token returns a valid installation token, creating a new one if the current one is about to expire.
+func (s *installationTokenSource) Token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current == nil || !s.app.clock().Add(installationTokenRefreshMargin).Before(s.current.ExpiresAt) {
+		token, err := s.app.CreateInstallationToken(s.installationID, s.opts)
+		if err != nil {
+			return "", fmt.Errorf("failed to create installation token: %w", err)
+		}
+		s.current = token
+	}
+	return s.current.Token, nil
+}
@@ -0,0 +1,140 @@
+package github
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func generateTestKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return key
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	key := generateTestKey(t)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, block := range map[string]*pem.Block{
+		"pkcs1": {Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)},
+		"pkcs8": {Type: "PRIVATE KEY", Bytes: pkcs8},
+	} {
+		parsed, err := ParsePrivateKey(pem.EncodeToMemory(block))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if !parsed.Equal(key) {
+			t.Errorf("%s: parsed key does not match", name)
+		}
+	}
+	if _, err := ParsePrivateKey([]byte("not a key")); err == nil {
+		t.Error("expected an error for an invalid key")
+	}
+}
+
+func TestAppJWT(t *testing.T) {
+	key := generateTestKey(t)
+	app := NewApp(42, key, "", nil)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	app.clock = func() time.Time { return now }
+
+	jwt, err := app.JWT()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("expected 3 parts, got %d", len(parts))
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+		t.Errorf("invalid signature: %v", err)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claims struct {
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+		Iss string `json:"iss"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.Iss != "42" || claims.Iat != now.Add(-time.Minute).Unix() || claims.Exp != now.Add(9*time.Minute).Unix() {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+}
+
+func TestInstallationTokenSource_Refresh(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	exchanges := 0
+	var opts InstallationTokenOptions
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/app/installations/7/access_tokens" ||
+			!strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			http.NotFound(w, r)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		exchanges++
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token": "token-%d", "expires_at": %q}`, exchanges, now.Add(time.Hour).Format(time.RFC3339))
+	}))
+	defer ts.Close()
+
+	app := NewApp(42, generateTestKey(t), ts.URL, ts.Client())
+	clock := now
+	app.clock = func() time.Time { return clock }
+	source := app.InstallationTokenSource(7, InstallationTokenOptions{Permissions: map[string]string{"pull_requests": "write"}})
+
+	for _, tt := range []struct {
+		elapsed time.Duration
+		want    string
+	}{
+		{0, "token-1"},
+		// The token is reused while it is valid for long enough.
+		{50 * time.Minute, "token-1"},
+		// It is refreshed shortly before it expires.
+		{56 * time.Minute, "token-2"},
+	} {
+		clock = now.Add(tt.elapsed)
+		token, err := source.Token()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if token != tt.want {
+			t.Errorf("after %s: got %q, want %q", tt.elapsed, token, tt.want)
+		}
+	}
+	if opts.Permissions["pull_requests"] != "write" {
+		t.Errorf("expected the permissions to be requested, got %v", opts)
+	}
+}
@@ -3,6 +3,7 @@ package github
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 
 	"golang.org/x/oauth2"
@@ -23,6 +24,28 @@ type AuthCallback interface {
 	AuthCodeWaiter   // Interface for waiting for an authentication code
 }
 
+// OAuthURLForHost returns the base URL of the OAuth endpoints of the GitHub instance with the given web host,
+// github.com if empty.
+func OAuthURLForHost(host string) string {
+	if host == "" {
+		host = DefaultHost
+	}
+	return "https://" + host + "/login/oauth"
+}
+
+// OAuthEndpointForHost returns the OAuth2 endpoint of the GitHub instance with the given web host, including
+// the device authorization endpoint.
+func OAuthEndpointForHost(host string) oauth2.Endpoint {
+	if host == "" {
+		host = DefaultHost
+	}
+	return oauth2.Endpoint{
+		AuthURL:       OAuthURLForHost(host) + "/authorize",
+		TokenURL:      OAuthURLForHost(host) + "/access_token",
+		DeviceAuthURL: "https://" + host + "/login/device/code",
+	}
+}
+
 // Authenticate performs the OAuth2 authentication flow with GitHub.
 // It first attempts to load an existing token from disk. If no token is found,
 // it initiates the OAuth2 flow by opening a browser for the user to log in and retrieve an auth code.
@@ -51,7 +74,7 @@ func Authenticate(
 	token, err := LoadToken(tokenPath)
 	if err == nil {
 		fmt.Println("✅ Github token loaded from disk")
-		return NewClient(token.AccessToken, baseURL, client), nil
+		return newRefreshingClient(ctx, tokenPath, oauthConfig, token, baseURL, client), nil
 	}
 
 	// Generate the URL for the OAuth2 authorization flow
@@ -77,5 +100,68 @@ func Authenticate(
 	fmt.Println("✅ Logged in and token saved to disk")
 
 	// Return a new GitHub client initialized with the access token
-	return NewClient(token.AccessToken, baseURL, client), nil
+	return newRefreshingClient(ctx, tokenPath, oauthConfig, token, baseURL, client), nil
+}
+
+// AuthenticateDevice performs the OAuth2 device flow with GitHub, suited to terminals and GitHub Apps.
+// It first attempts to load an existing token from disk. If no token is found, it prints the URL to open
+// and the code to enter to out, then waits for the user to authorize the application.
+// The retrieved token is saved to disk and refreshed transparently when it expires, as is the case for
+// the user-to-server tokens of GitHub Apps.
+//
+// Parameters:
+// - ctx: Context for managing the lifecycle of the authentication process.
+// - tokenPath: Path to the file where the token is stored.
+// - oauthConfig: OAuth2 configuration for GitHub, with the device authorization endpoint.
+// - baseURL: Base URL for GitHub API requests.
+// - out: Writer the instructions for the user are printed to.
+// - client: HTTP client for making requests.
+//
+// Returns:
+// - A GitHub client initialized with the retrieved token.
+// - An error if the authentication process fails.
+func AuthenticateDevice(
+	ctx context.Context,
+	tokenPath string,
+	oauthConfig *oauth2.Config,
+	baseURL string,
+	out io.Writer,
+	client *http.Client,
+) (*Client, error) {
+	token, err := LoadToken(tokenPath)
+	if err == nil {
+		fmt.Fprintln(out, "✅ Github token loaded from disk")
+		return newRefreshingClient(ctx, tokenPath, oauthConfig, token, baseURL, client), nil
+	}
+
+	if client != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, client)
+	}
+	deviceAuth, err := oauthConfig.DeviceAuth(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start device authorization: %w", err)
+	}
+	fmt.Fprintf(out, "🔑 Open %s and enter the code %s\n", deviceAuth.VerificationURI, deviceAuth.UserCode)
+
+	token, err = oauthConfig.DeviceAccessToken(ctx, deviceAuth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access token: %w", err)
+	}
+	if err := SaveToken(tokenPath, token); err != nil {
+		return nil, fmt.Errorf("failed to save token on disk: %w", err)
+	}
+	fmt.Fprintln(out, "✅ Logged in and token saved to disk")
+	return newRefreshingClient(ctx, tokenPath, oauthConfig, token, baseURL, client), nil
+}
+
+// newRefreshingClient creates a GitHub client using the token, refreshed when it expires and saved to tokenPath.
+func newRefreshingClient(ctx context.Context, tokenPath string, oauthConfig *oauth2.Config, token *oauth2.Token,
+	baseURL string, client *http.Client) *Client {
+	if client != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, client)
+	}
+	tokenSource := NewUserTokenSource(ctx, oauthConfig, token, func(token *oauth2.Token) error {
+		return SaveToken(tokenPath, token)
+	})
+	return NewClientWithTokenSource(tokenSource, baseURL, client)
 }
@@ -0,0 +1,198 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+)
+
+// ChecksState is the aggregated state of the commit statuses and check runs of a commit.
+type ChecksState string
+
+const (
+	// ChecksSuccess indicates that all the checks passed.
+	ChecksSuccess ChecksState = "success"
+	// ChecksPending indicates that no check failed but some did not complete yet.
+	ChecksPending ChecksState = "pending"
+	// ChecksFailure indicates that at least one check failed.
+	ChecksFailure ChecksState = "failure"
+)
+
+// ChecksSummary is the aggregated result of the commit statuses and check runs of a commit.
+type ChecksSummary struct {
+	State ChecksState
+	// Failing lists the names of the failing checks.
+	Failing []string
+	// Pending lists the names of the checks that did not complete yet, including the missing required ones.
+	Pending []string
+}
+
+// GetCombinedStatus returns the state of every commit status of the commit, indexed by context.
+func (c *Client) GetCombinedStatus(owner, repo, sha string) (map[string]string, error) {
+	statuses := make(map[string]string)
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("/repos/%s/%s/commits/%s/status?per_page=100&page=%d", owner, repo, sha, page)
+		resp, err := c.doNewRequest("GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		var combined struct {
+			Statuses []struct {
+				Context string `json:"context"`
+				State   string `json:"state"`
+			} `json:"statuses"`
+		}
+		if err := decodeJSONResponse(resp, &combined); err != nil {
+			return nil, err
+		}
+		for _, s := range combined.Statuses {
+			statuses[s.Context] = s.State
+		}
+		if len(combined.Statuses) < 100 {
+			return statuses, nil
+		}
+	}
+}
+
+// CheckRun is a check run reported by a GitHub App (e.g. GitHub Actions) on a commit.
+type CheckRun struct {
+	Name string `json:"name"`
+	// Status is queued, in_progress or completed.
+	Status string `json:"status"`
+	// Conclusion is set once the check run is completed (success, failure, neutral, skipped, ...).
+	Conclusion string `json:"conclusion"`
+}
+
+// ListCheckRuns returns the check runs of the commit.
+func (c *Client) ListCheckRuns(owner, repo, sha string) ([]CheckRun, error) {
+	var runs []CheckRun
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("/repos/%s/%s/commits/%s/check-runs?per_page=100&page=%d", owner, repo, sha, page)
+		resp, err := c.doNewRequest("GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		var body struct {
+			CheckRuns []CheckRun `json:"check_runs"`
+		}
+		if err := decodeJSONResponse(resp, &body); err != nil {
+			return nil, err
+		}
+		runs = append(runs, body.CheckRuns...)
+		if len(body.CheckRuns) < 100 {
+			return runs, nil
+		}
+	}
+}
+
+// GetChecksSummary aggregates the commit statuses and check runs of the commit.
+// If required is not empty, only the checks with those names are considered and the missing ones are pending.
+// Otherwise, every check of the commit must pass.
+func (c *Client) GetChecksSummary(owner, repo, sha string, required []string) (*ChecksSummary, error) {
+	statuses, err := c.GetCombinedStatus(owner, repo, sha)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit statuses: %w", err)
+	}
+	runs, err := c.ListCheckRuns(owner, repo, sha)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list check runs: %w", err)
+	}
+
+	// Index the state of every check, using the same vocabulary for statuses and check runs. A commit status and
+	// a check run can have the same name, each of them must pass.
+	type checkKey struct {
+		name     string
+		checkRun bool
+	}
+	states := make(map[checkKey]ChecksState)
+	for context, state := range statuses {
+		key := checkKey{name: context}
+		switch state {
+		case "success":
+			states[key] = ChecksSuccess
+		case "pending":
+			states[key] = ChecksPending
+		default:
+			states[key] = ChecksFailure
+		}
+	}
+	for _, run := range runs {
+		var state ChecksState
+		switch {
+		case run.Status != "completed":
+			state = ChecksPending
+		case run.Conclusion == "success" || run.Conclusion == "neutral" || run.Conclusion == "skipped":
+			state = ChecksSuccess
+		default:
+			state = ChecksFailure
+		}
+		// A failure is never hidden by another check run with the same name.
+		key := checkKey{name: run.Name, checkRun: true}
+		if states[key] != ChecksFailure {
+			states[key] = state
+		}
+	}
+
+	summary := &ChecksSummary{}
+	names := make(map[string]struct{})
+	for key, state := range states {
+		names[key.name] = struct{}{}
+		if len(required) > 0 && !slices.Contains(required, key.name) {
+			continue
+		}
+		switch state {
+		case ChecksFailure:
+			summary.Failing = append(summary.Failing, key.name)
+		case ChecksPending:
+			summary.Pending = append(summary.Pending, key.name)
+		}
+	}
+	for _, name := range required {
+		if _, ok := names[name]; !ok {
+			summary.Pending = append(summary.Pending, name)
+		}
+	}
+	sort.Strings(summary.Failing)
+	sort.Strings(summary.Pending)
+	summary.Failing = slices.Compact(summary.Failing)
+	summary.Pending = slices.Compact(summary.Pending)
+
+	switch {
+	case len(summary.Failing) > 0:
+		summary.State = ChecksFailure
+	case len(summary.Pending) > 0:
+		summary.State = ChecksPending
+	default:
+		summary.State = ChecksSuccess
+	}
+	return summary, nil
+}
+
+// WaitForChecks polls the checks of the commit every pollInterval until none of them is pending or the timeout
+// expires, in which case the last pending summary is returned. A zero timeout returns the current summary.
+// If the context is done before, its error is returned.
+func (c *Client) WaitForChecks(ctx context.Context, owner, repo, sha string, required []string,
+	timeout, pollInterval time.Duration) (*ChecksSummary, error) {
+	deadline := time.Now().Add(timeout)
+	var ticker *time.Ticker
+	for {
+		summary, err := c.GetChecksSummary(owner, repo, sha, required)
+		if err != nil {
+			return nil, err
+		}
+		if summary.State != ChecksPending || time.Now().Add(pollInterval).After(deadline) {
+			return summary, nil
+		}
+		if ticker == nil {
+			ticker = time.NewTicker(pollInterval)
+			defer ticker.Stop()
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
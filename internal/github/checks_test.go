@@ -0,0 +1,114 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newChecksTestClient(t *testing.T, statuses, checkRuns string) *Client {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/repos/foo/bar/commits/abc/status":
+			fmt.Fprintf(w, `{"statuses":%s}`, statuses)
+		case "/repos/foo/bar/commits/abc/check-runs":
+			fmt.Fprintf(w, `{"check_runs":%s}`, checkRuns)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	return &Client{
+		httpClient:  ts.Client(),
+		accessToken: "dummy",
+		apiBaseURL:  ts.URL,
+	}
+}
+
+func TestGetChecksSummary(t *testing.T) {
+	client := newChecksTestClient(t,
+		`[{"context":"ci/lint","state":"success"},{"context":"ci/e2e","state":"pending"}]`,
+		`[{"name":"build","status":"completed","conclusion":"success"},
+		  {"name":"docs","status":"completed","conclusion":"skipped"},
+		  {"name":"test","status":"completed","conclusion":"failure"}]`)
+
+	summary, err := client.GetChecksSummary("foo", "bar", "abc", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := &ChecksSummary{State: ChecksFailure, Failing: []string{"test"}, Pending: []string{"ci/e2e"}}
+	if !reflect.DeepEqual(summary, want) {
+		t.Errorf("expected %+v, got %+v", want, summary)
+	}
+
+	// Only the required checks are considered and the missing ones are pending.
+	summary, err = client.GetChecksSummary("foo", "bar", "abc", []string{"build", "ci/lint", "deploy"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want = &ChecksSummary{State: ChecksPending, Pending: []string{"deploy"}}
+	if !reflect.DeepEqual(summary, want) {
+		t.Errorf("expected %+v, got %+v", want, summary)
+	}
+
+	summary, err = client.GetChecksSummary("foo", "bar", "abc", []string{"build", "docs"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.State != ChecksSuccess {
+		t.Errorf("expected success, got %+v", summary)
+	}
+}
+
+func TestWaitForChecks_Timeout(t *testing.T) {
+	client := newChecksTestClient(t, `[]`, `[{"name":"build","status":"in_progress"}]`)
+
+	start := time.Now()
+	summary, err := client.WaitForChecks(context.Background(), "foo", "bar", "abc", nil, 50*time.Millisecond, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.State != ChecksPending {
+		t.Errorf("expected pending, got %+v", summary)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("expected to wait for the checks, returned after %s", elapsed)
+	}
+}
+
+func TestWaitForChecks_ContextDone(t *testing.T) {
+	client := newChecksTestClient(t, `[]`, `[{"name":"build","status":"in_progress"}]`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := client.WaitForChecks(ctx, "foo", "bar", "abc", nil, time.Minute, 5*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the context error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected to stop with the context, returned after %s", elapsed)
+	}
+}
+
+func TestGetChecksSummary_StatusAndCheckRunWithTheSameName(t *testing.T) {
+	// A passing check run does not hide a pending commit status of the same name.
+	client := newChecksTestClient(t, `[{"context":"build","state":"pending"}]`,
+		`[{"name":"build","status":"completed","conclusion":"success"}]`)
+
+	summary, err := client.GetChecksSummary("foo", "bar", "abc", []string{"build"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.State != ChecksPending || !reflect.DeepEqual(summary.Pending, []string{"build"}) {
+		t.Errorf("expected build to be pending, got %+v", summary)
+	}
+}
@@ -1,8 +1,10 @@
 package github
 
 import (
+	"fmt"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -13,7 +15,11 @@ const defaultAPIBaseURL = "https://api.github.com"
 type Client struct {
 	httpClient  *http.Client // HTTP client used for requests
 	accessToken string       // GitHub OAuth access token
+	tokenSource TokenSource  // Source of short-lived tokens, used instead of accessToken if set
 	apiBaseURL  string       // Base URL for GitHub API (e.g., "https://api.github.com")
+
+	repoPagesMu sync.Mutex
+	repoPages   map[string]*repoPage // Pages of repository listings by path, revalidated with their ETag
 }
 
 // NewClient creates a new GitHub API client with the given access token, API base URL, and optional HTTP client.
@@ -41,6 +47,16 @@ func NewClient(accessToken string, apiBaseURL string, httpClient *http.Client) *
 	}
 }
 
+// NewClientWithTokenSource creates a new GitHub API client authenticated with the tokens supplied by the
+// token source, such as GitHub App installation tokens or user-to-server tokens.
+// If apiBaseURL is empty, "https://api.github.com" is used.
+// If httpClient is nil, http.DefaultClient is used.
+func NewClientWithTokenSource(tokenSource TokenSource, apiBaseURL string, httpClient *http.Client) *Client {
+	c := NewClient("", apiBaseURL, httpClient)
+	c.tokenSource = tokenSource
+	return c
+}
+
 // newRequest creates a new HTTP request with the correct base URL and authorization headers.
 //
 // Parameters:
@@ -56,7 +72,14 @@ func (c *Client) newRequest(method, path string, body io.Reader) (*http.Request,
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Authorization", "token "+c.accessToken)
+	token := c.accessToken
+	if c.tokenSource != nil {
+		token, err = c.tokenSource.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get GitHub token: %w", err)
+		}
+	}
+	req.Header.Set("Authorization", "token "+token)
 	req.Header.Set("Accept", "application/vnd.github+json")
 	return req, nil
 }
@@ -0,0 +1,198 @@
+package github
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/clems4ever/lgtm/internal/common"
+)
+
+// codeownersPaths are the locations where GitHub looks for a CODEOWNERS file, in order of precedence.
+var codeownersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// CodeownersRule associates a path pattern with its owners.
+type CodeownersRule struct {
+	Pattern string
+	// Owners are GitHub users ("@user"), teams ("@org/team") or emails, as written in the file.
+	Owners []string
+
+	// globs are the common.MatchGlob patterns of the paths covered by the rule.
+	globs []string
+}
+
+// Codeowners is a parsed CODEOWNERS file.
+type Codeowners struct {
+	Rules []CodeownersRule
+}
+
+// ParseCodeowners parses the content of a CODEOWNERS file.
+// Lines with an invalid pattern are skipped, as GitHub does.
+func ParseCodeowners(content string) *Codeowners {
+	co := &Codeowners{}
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		globs, err := codeownersPatternToGlobs(fields[0])
+		if err != nil {
+			continue
+		}
+		co.Rules = append(co.Rules, CodeownersRule{
+			Pattern: fields[0],
+			Owners:  fields[1:],
+			globs:   globs,
+		})
+	}
+	return co
+}
+
+// OwnersFor returns the owners of the given file path. As in GitHub, the last matching rule takes precedence.
+// A matching rule without owners means the path has no owner.
+func (co *Codeowners) OwnersFor(path string) []string {
+	path = strings.TrimPrefix(path, "/")
+	for i := len(co.Rules) - 1; i >= 0; i-- {
+		if co.Rules[i].matches(path) {
+			return co.Rules[i].Owners
+		}
+	}
+	return nil
+}
+
+// matches tells whether the rule covers the given file path.
+func (r *CodeownersRule) matches(path string) bool {
+	for _, g := range r.globs {
+		if common.MatchGlob(g, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// codeownersPatternToGlobs converts a gitignore-style CODEOWNERS pattern into the globs matching the file paths
+// it covers.
+func codeownersPatternToGlobs(pattern string) ([]string, error) {
+	anchored := strings.HasPrefix(pattern, "/")
+	p := strings.TrimPrefix(pattern, "/")
+	directory := strings.HasSuffix(p, "/")
+	p = strings.TrimSuffix(p, "/")
+	if p == "" {
+		return nil, fmt.Errorf("empty pattern")
+	}
+	// A pattern containing a slash other than a trailing one is relative to the repository root.
+	if !anchored && !strings.Contains(p, "/") {
+		p = "**/" + p
+	}
+	if directory {
+		// A directory pattern only matches the files inside the directory.
+		return []string{p + "/**"}, nil
+	}
+	// A wildcard in the last segment only matches the entries of the parent directory, e.g. "docs/*" covers
+	// "docs/a.md" but not "docs/a/b.md".
+	if strings.ContainsAny(p[strings.LastIndex(p, "/")+1:], "*?") {
+		return []string{p}, nil
+	}
+	// Otherwise the pattern matches a file or everything inside a matching directory.
+	return []string{p, p + "/**"}, nil
+}
+
+// GetCodeowners fetches and parses the CODEOWNERS file of the repository from the given branch, the default
+// branch if ref is empty. The CODEOWNERS file of the base branch of a pull request is the one that applies to it.
+// It returns nil without error if the repository has no CODEOWNERS file.
+func (c *Client) GetCodeowners(owner, repo, ref string) (*Codeowners, error) {
+	for _, path := range codeownersPaths {
+		content, err := c.getFileContent(owner, repo, path, ref)
+		if err != nil {
+			return nil, err
+		}
+		if content != nil {
+			return ParseCodeowners(string(content)), nil
+		}
+	}
+	return nil, nil
+}
+
+// getFileContent returns the content of a file of the repository at the given ref, or nil if it does not exist.
+// The default branch is used if ref is empty.
+func (c *Client) getFileContent(owner, repo, path, ref string) ([]byte, error) {
+	var query string
+	if ref != "" {
+		query = "?" + url.Values{"ref": {ref}}.Encode()
+	}
+	url := fmt.Sprintf("/repos/%s/%s/contents/%s%s", owner, repo, path, query)
+	resp, err := c.doNewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != 200 {
+		data, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("GitHub API error: %s", string(data))
+	}
+	var file struct {
+		Content  string `json:"content"`
+		Encoding string `json:"encoding"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&file); err != nil {
+		return nil, err
+	}
+	if file.Encoding != "base64" {
+		return nil, fmt.Errorf("unsupported encoding %q for %s", file.Encoding, path)
+	}
+	// The API wraps the base64 content on several lines.
+	content, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(file.Content, "\n", ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return content, nil
+}
+
+// GetTeamMembers returns the logins of the members of the given team.
+// The token needs the 'read:org' permission.
+func (c *Client) GetTeamMembers(org, teamSlug string) ([]string, error) {
+	var members []string
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("/orgs/%s/teams/%s/members?per_page=100&page=%d", org, teamSlug, page)
+		resp, err := c.doNewRequest("GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		var users []struct {
+			Login string `json:"login"`
+		}
+		err = decodeJSONResponse(resp, &users)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			members = append(members, u.Login)
+		}
+		if len(users) < 100 {
+			return members, nil
+		}
+	}
+}
+
+// decodeJSONResponse checks the status of the response, decodes its JSON body into v and closes it.
+func decodeJSONResponse(resp *http.Response, v any) error {
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("GitHub API error: %s", string(data))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
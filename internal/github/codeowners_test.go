@@ -0,0 +1,125 @@
+package github
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+const testCodeowners = `
+# Default owners
+*                @acme/core
+*.go             @gopher   # Go files
+/docs/           docs@example.com
+apps/**/config   @ops
+/build/          
+vendor           @acme/deps
+/scripts/*       @tooling
+`
+
+func TestCodeowners_OwnersFor(t *testing.T) {
+	co := ParseCodeowners(testCodeowners)
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"README.md", []string{"@acme/core"}},
+		{"main.go", []string{"@gopher"}},
+		{"internal/server/cmd.go", []string{"@gopher"}},
+		{"docs/index.md", []string{"docs@example.com"}},
+		{"sub/docs/index.md", []string{"@acme/core"}},
+		{"apps/config", []string{"@ops"}},
+		{"apps/web/prod/config", []string{"@ops"}},
+		{"apps/web/prod/config/values.yaml", []string{"@ops"}},
+		{"build/Makefile", nil},
+		{"vendor/lib/lib.go", []string{"@acme/deps"}},
+		{"third_party/vendor/lib.c", []string{"@acme/deps"}},
+		{"scripts/build.sh", []string{"@tooling"}},
+		{"scripts/ci/run.sh", []string{"@acme/core"}},
+	}
+	for _, tt := range tests {
+		if got := co.OwnersFor(tt.path); !reflect.DeepEqual(got, tt.want) && !(len(got) == 0 && len(tt.want) == 0) {
+			t.Errorf("OwnersFor(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestGetCodeowners(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repos/foo/bar/contents/CODEOWNERS" {
+			owner := "@alice"
+			if r.URL.Query().Get("ref") == "release" {
+				owner = "@bob"
+			}
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"encoding":"base64","content":%q}`, base64.StdEncoding.EncodeToString([]byte("* "+owner+"\n")))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer ts.Close()
+
+	client := &Client{
+		httpClient:  ts.Client(),
+		accessToken: "dummy",
+		apiBaseURL:  ts.URL,
+	}
+
+	co, err := client.GetCodeowners("foo", "bar", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if co == nil {
+		t.Fatal("expected CODEOWNERS, got nil")
+	}
+	if owners := co.OwnersFor("main.go"); len(owners) != 1 || owners[0] != "@alice" {
+		t.Errorf("expected [@alice], got %v", owners)
+	}
+
+	co, err = client.GetCodeowners("foo", "bar", "release")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if owners := co.OwnersFor("main.go"); len(owners) != 1 || owners[0] != "@bob" {
+		t.Errorf("expected [@bob] on the release branch, got %v", owners)
+	}
+
+	co, err = client.GetCodeowners("foo", "nocodeowners", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if co != nil {
+		t.Errorf("expected nil CODEOWNERS, got %+v", co)
+	}
+}
+
+func TestGetPRFiles(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repos/foo/bar/pulls/1/files" {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `[{"filename":"a.go"},{"filename":"b.go","previous_filename":"old/b.go"}]`)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer ts.Close()
+
+	client := &Client{
+		httpClient:  ts.Client(),
+		accessToken: "dummy",
+		apiBaseURL:  ts.URL,
+	}
+
+	files, err := client.GetPRFiles(PRLink{Owner: "foo", Repo: "bar", PRNumber: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"a.go", "b.go", "old/b.go"}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("expected %v, got %v", want, files)
+	}
+}
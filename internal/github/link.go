@@ -5,36 +5,34 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+
+	"github.com/clems4ever/lgtm/internal/forge"
 )
 
-// PRLink represents a GitHub pull request link with owner, repo, and PR number.
-// It provides methods to retrieve the full repository name and the canonical PR URL.
-type PRLink struct {
-	Owner    string // Repository owner
-	Repo     string // Repository name
-	PRNumber int    // Pull request number
-}
+// DefaultHost is the web host of github.com, as opposed to the hosts of GitHub Enterprise Server instances.
+const DefaultHost = "github.com"
 
-// RepoFullName returns the "owner/repo" string for the PR.
-// This is useful for identifying the repository in a concise format.
-func (l *PRLink) RepoFullName() string {
-	return fmt.Sprintf("%s/%s", l.Owner, l.Repo)
-}
+// PRLink is a link to a GitHub pull request. GitHub pull requests are the change requests of the github provider.
+type PRLink = forge.ChangeLink
 
-// String returns the canonical GitHub PR URL for this PRLink.
-// The URL is constructed using the owner, repo, and PR number.
-func (l PRLink) String() string {
-	return fmt.Sprintf("https://github.com/%s/%s/pull/%d", l.Owner, l.Repo, l.PRNumber)
+// APIURLForHost returns the base URL of the REST API of the GitHub instance with the given web host:
+// https://api.github.com for github.com and https://{host}/api/v3 for GitHub Enterprise Server.
+func APIURLForHost(host string) string {
+	if host == "" || strings.EqualFold(host, DefaultHost) {
+		return defaultAPIBaseURL
+	}
+	return "https://" + host + "/api/v3"
 }
 
-// ParsePullRequestURL extracts owner, repo, and PR number from a GitHub PR URL.
-// It validates the URL format and ensures it corresponds to a pull request.
+// ParsePullRequestURL extracts host, owner, repo, and PR number from a GitHub PR URL.
+// It validates the URL format and ensures it corresponds to a pull request. The URL can point to
+// github.com or to a GitHub Enterprise Server instance, callers check that the host is the expected one.
 //
 // Parameters:
 // - link: The GitHub PR URL to parse.
 //
 // Returns:
-// - A PRLink containing the owner, repo, and PR number.
+// - A PRLink containing the host, owner, repo, and PR number.
 // - An error if the URL is invalid or does not match the expected PR format.
 func ParsePullRequestURL(link string) (PRLink, error) {
 	u, err := url.Parse(link)
@@ -42,6 +40,9 @@ func ParsePullRequestURL(link string) (PRLink, error) {
 		return PRLink{}, fmt.Errorf("invalid URL: %w", err)
 	}
 	parts := strings.Split(u.Path, "/")
+	if u.Host == "" {
+		return PRLink{}, fmt.Errorf("invalid PR link format: missing host")
+	}
 	// Expect: /{owner}/{repo}/pull/{number}
 	if len(parts) < 5 || parts[3] != "pull" {
 		return PRLink{}, fmt.Errorf("invalid PR link format")
@@ -55,9 +56,44 @@ func ParsePullRequestURL(link string) (PRLink, error) {
 	}
 
 	prLink := PRLink{
+		Provider: forge.ProviderGitHub,
+		Host:     strings.ToLower(u.Host),
 		Owner:    owner,
 		Repo:     repo,
 		PRNumber: prNumber,
 	}
 	return prLink, nil
 }
+
+// ParseRemoteURL extracts the host, owner and repo from the URL of a GitHub git remote.
+// It accepts HTTPS URLs (https://github.com/owner/repo.git), SSH URLs (ssh://git@github.com/owner/repo.git)
+// and scp-like SSH URLs (git@github.com:owner/repo.git), pointing to github.com or a GitHub Enterprise Server.
+//
+// Parameters:
+// - remote: The URL of the git remote.
+//
+// Returns:
+// - The lowercased web host, the owner and the name of the repository.
+// - An error if the URL does not point to a repository.
+func ParseRemoteURL(remote string) (string, string, string, error) {
+	remote = strings.TrimSpace(remote)
+	var host, path string
+	if u, err := url.Parse(remote); err == nil && u.Scheme != "" && u.Host != "" {
+		host, path = u.Hostname(), u.Path
+	} else if before, after, ok := strings.Cut(remote, ":"); ok && !strings.Contains(remote, "://") {
+		// scp-like syntax: [user@]host:owner/repo
+		_, host, _ = strings.Cut(before, "@")
+		if host == "" {
+			host = before
+		}
+		path = after
+	} else {
+		return "", "", "", fmt.Errorf("invalid remote URL %q", remote)
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimSuffix(path, ".git"), "/"), "/")
+	if host == "" || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", "", fmt.Errorf("remote URL %q does not point to a repository", remote)
+	}
+	return strings.ToLower(host), parts[0], parts[1], nil
+}
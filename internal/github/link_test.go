@@ -14,12 +14,17 @@ func TestParsePullRequestURL(t *testing.T) {
 	}{
 		{
 			input:   "https://github.com/owner/repo/pull/123",
-			want:    github.PRLink{Owner: "owner", Repo: "repo", PRNumber: 123},
+			want:    github.PRLink{Provider: "github", Host: "github.com", Owner: "owner", Repo: "repo", PRNumber: 123},
 			wantErr: false,
 		},
 		{
 			input:   "https://github.com/foo/bar/pull/1",
-			want:    github.PRLink{Owner: "foo", Repo: "bar", PRNumber: 1},
+			want:    github.PRLink{Provider: "github", Host: "github.com", Owner: "foo", Repo: "bar", PRNumber: 1},
+			wantErr: false,
+		},
+		{
+			input:   "https://GHE.example.com/foo/bar/pull/7",
+			want:    github.PRLink{Provider: "github", Host: "ghe.example.com", Owner: "foo", Repo: "bar", PRNumber: 7},
 			wantErr: false,
 		},
 		{
@@ -62,6 +67,42 @@ func TestPRLink_String(t *testing.T) {
 	}
 }
 
+func TestPRLink_StringEnterprise(t *testing.T) {
+	link := github.PRLink{Host: "ghe.example.com", Owner: "foo", Repo: "bar", PRNumber: 42}
+	want := "https://ghe.example.com/foo/bar/pull/42"
+	if got := link.String(); got != want {
+		t.Errorf("PRLink.String() = %q, want %q", got, want)
+	}
+}
+
+func TestPRLink_RepoID(t *testing.T) {
+	tests := []struct {
+		link github.PRLink
+		want string
+	}{
+		{link: github.PRLink{Owner: "foo", Repo: "bar"}, want: "github:github.com/foo/bar"},
+		{link: github.PRLink{Host: "ghe.example.com", Owner: "foo", Repo: "bar"}, want: "github:ghe.example.com/foo/bar"},
+	}
+	for _, tt := range tests {
+		if got := tt.link.RepoID(); got != tt.want {
+			t.Errorf("PRLink.RepoID() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestAPIURLForHost(t *testing.T) {
+	tests := map[string]string{
+		"":                "https://api.github.com",
+		"github.com":      "https://api.github.com",
+		"ghe.example.com": "https://ghe.example.com/api/v3",
+	}
+	for host, want := range tests {
+		if got := github.APIURLForHost(host); got != want {
+			t.Errorf("APIURLForHost(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
 func TestPRLink_RepoFullName(t *testing.T) {
 	link := github.PRLink{Owner: "foo", Repo: "bar", PRNumber: 42}
 	want := "foo/bar"
@@ -69,3 +110,39 @@ func TestPRLink_RepoFullName(t *testing.T) {
 		t.Errorf("PRLink.RepoFullName() = %q, want %q", got, want)
 	}
 }
+
+func TestParseRemoteURL(t *testing.T) {
+	tests := []struct {
+		input   string
+		host    string
+		owner   string
+		repo    string
+		wantErr bool
+	}{
+		{input: "https://github.com/foo/bar.git", host: "github.com", owner: "foo", repo: "bar"},
+		{input: "https://github.com/foo/bar", host: "github.com", owner: "foo", repo: "bar"},
+		{input: "git@github.com:foo/bar.git", host: "github.com", owner: "foo", repo: "bar"},
+		{input: "ssh://git@github.com/foo/bar.git", host: "github.com", owner: "foo", repo: "bar"},
+		{input: "git@ghe.example.com:foo/bar.git", host: "ghe.example.com", owner: "foo", repo: "bar"},
+		{input: "ssh://git@ghe.example.com:2222/foo/bar.git", host: "ghe.example.com", owner: "foo", repo: "bar"},
+		{input: "https://github.com/foo", wantErr: true},
+		{input: "not a url", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		host, owner, repo, err := github.ParseRemoteURL(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseRemoteURL(%q) expected error, got nil", tt.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseRemoteURL(%q) unexpected error: %v", tt.input, err)
+			continue
+		}
+		if host != tt.host || owner != tt.owner || repo != tt.repo {
+			t.Errorf("ParseRemoteURL(%q) = %s/%s/%s, want %s/%s/%s", tt.input, host, owner, repo, tt.host, tt.owner, tt.repo)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package github
+
+import (
+	"fmt"
+	"io"
+)
+
+// GetUserOrgs returns the logins of the organizations the authenticated user is a member of.
+// The token needs the 'read:org' permission to list private memberships.
+func (c *Client) GetUserOrgs() ([]string, error) {
+	var orgs []string
+	for page := 1; ; page++ {
+		resp, err := c.doNewRequest("GET", fmt.Sprintf("/user/orgs?per_page=100&page=%d", page), nil)
+		if err != nil {
+			return nil, err
+		}
+		var l []struct {
+			Login string `json:"login"`
+		}
+		if err := decodeJSONResponse(resp, &l); err != nil {
+			return nil, err
+		}
+		for _, o := range l {
+			orgs = append(orgs, o.Login)
+		}
+		if len(l) < 100 {
+			return orgs, nil
+		}
+	}
+}
+
+// GetUserTeams returns the teams the authenticated user is a member of, as "org/team-slug".
+// The token needs the 'read:org' permission.
+func (c *Client) GetUserTeams() ([]string, error) {
+	var teams []string
+	for page := 1; ; page++ {
+		resp, err := c.doNewRequest("GET", fmt.Sprintf("/user/teams?per_page=100&page=%d", page), nil)
+		if err != nil {
+			return nil, err
+		}
+		var l []struct {
+			Slug         string `json:"slug"`
+			Organization struct {
+				Login string `json:"login"`
+			} `json:"organization"`
+		}
+		if err := decodeJSONResponse(resp, &l); err != nil {
+			return nil, err
+		}
+		for _, t := range l {
+			teams = append(teams, t.Organization.Login+"/"+t.Slug)
+		}
+		if len(l) < 100 {
+			return teams, nil
+		}
+	}
+}
+
+// IsOrgMember tells whether the user is a member of the organization. Private memberships are only visible
+// if the authenticated user is a member of the organization.
+func (c *Client) IsOrgMember(org, username string) (bool, error) {
+	resp, err := c.doNewRequest("GET", fmt.Sprintf("/orgs/%s/members/%s", org, username), nil)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case 204:
+		return true, nil
+	case 404:
+		return false, nil
+	default:
+		data, _ := io.ReadAll(resp.Body)
+		return false, fmt.Errorf("GitHub API error: %s", string(data))
+	}
+}
+
+// IsTeamMember tells whether the user is an active member of the team of the organization, given by its slug.
+// The token needs the 'read:org' permission.
+func (c *Client) IsTeamMember(org, teamSlug, username string) (bool, error) {
+	resp, err := c.doNewRequest("GET", fmt.Sprintf("/orgs/%s/teams/%s/memberships/%s", org, teamSlug, username), nil)
+	if err != nil {
+		return false, err
+	}
+	if resp.StatusCode == 404 {
+		resp.Body.Close()
+		return false, nil
+	}
+	var membership struct {
+		State string `json:"state"`
+	}
+	if err := decodeJSONResponse(resp, &membership); err != nil {
+		return false, err
+	}
+	return membership.State == "active", nil
+}
@@ -1,10 +1,14 @@
 package github
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/url"
 	"strings"
+
+	"github.com/clems4ever/lgtm/internal/forge"
 )
 
 // GetPRAuthor retrieves the GitHub username of the author of the given pull request.
@@ -47,14 +51,23 @@ func (c *Client) GetPRAuthor(link PRLink) (string, error) {
 //
 // Parameters:
 // - link: A PRLink representing the pull request.
+// - commitID: The SHA of the commit to approve. If empty, GitHub approves the current head of the PR.
 // - message: The approval message to include in the review.
 //
 // Returns:
 // - An error if the API request fails or the response indicates an error.
-func (c *Client) ApprovePR(link PRLink, message string) error {
+func (c *Client) ApprovePR(link PRLink, commitID string, message string) error {
 	url := fmt.Sprintf("/repos/%s/%s/pulls/%d/reviews", link.Owner, link.Repo, link.PRNumber)
-	body := fmt.Sprintf(`{"event":"APPROVE","body":%q}`, message)
-	resp, err := c.doNewRequest("POST", url, strings.NewReader(body))
+	review := struct {
+		Event    string `json:"event"`
+		Body     string `json:"body"`
+		CommitID string `json:"commit_id,omitempty"`
+	}{Event: "APPROVE", Body: message, CommitID: commitID}
+	body, err := json.Marshal(review)
+	if err != nil {
+		return err
+	}
+	resp, err := c.doNewRequest("POST", url, bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
@@ -117,3 +130,196 @@ func (c *Client) IsPRAproved(link PRLink) (bool, error) {
 	}
 	return false, nil
 }
+
+// GetPRFiles returns the paths of the files changed by the pull request.
+// Renamed files are reported under both their previous and new paths.
+//
+// Parameters:
+// - link: A PRLink representing the pull request.
+//
+// Returns:
+// - The list of changed file paths.
+// - An error if the API request fails or the response cannot be parsed.
+func (c *Client) GetPRFiles(link PRLink) ([]string, error) {
+	var paths []string
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("/repos/%s/%s/pulls/%d/files?per_page=100&page=%d", link.Owner, link.Repo, link.PRNumber, page)
+		resp, err := c.doNewRequest("GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		var files []struct {
+			Filename         string `json:"filename"`
+			PreviousFilename string `json:"previous_filename"`
+		}
+		if err := decodeJSONResponse(resp, &files); err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			paths = append(paths, f.Filename)
+			if f.PreviousFilename != "" {
+				paths = append(paths, f.PreviousFilename)
+			}
+		}
+		if len(files) < 100 {
+			return paths, nil
+		}
+	}
+}
+
+// PullRequest holds the metadata of a pull request.
+type PullRequest struct {
+	Title        string `json:"title"`
+	State        string `json:"state"`
+	Additions    int    `json:"additions"`
+	Deletions    int    `json:"deletions"`
+	ChangedFiles int    `json:"changed_files"`
+	User         struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+	Head struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"head"`
+}
+
+// Author returns the GitHub username of the author of the pull request.
+func (pr *PullRequest) Author() string {
+	return pr.User.Login
+}
+
+// GetPullRequest retrieves the metadata of the given pull request.
+//
+// Parameters:
+// - link: A PRLink representing the pull request.
+//
+// Returns:
+// - The pull request metadata.
+// - An error if the API request fails or the response cannot be parsed.
+func (c *Client) GetPullRequest(link PRLink) (*PullRequest, error) {
+	url := fmt.Sprintf("/repos/%s/%s/pulls/%d", link.Owner, link.Repo, link.PRNumber)
+	resp, err := c.doNewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	var pr PullRequest
+	if err := decodeJSONResponse(resp, &pr); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// FindOpenPullRequest returns the open pull request of the repository whose head is the given branch
+// of the repository of headOwner, which is the owner of the repository itself or of a fork.
+//
+// Parameters:
+// - owner: The owner of the repository.
+// - repo: The name of the repository.
+// - headOwner: The owner of the repository holding the head branch.
+// - branch: The name of the head branch of the pull request.
+//
+// Returns:
+// - A PRLink representing the pull request.
+// - An error if the API request fails or if there is not exactly one open pull request for the branch.
+func (c *Client) FindOpenPullRequest(owner, repo, headOwner, branch string) (PRLink, error) {
+	head := headOwner + ":" + branch
+	query := url.Values{}
+	query.Set("state", "open")
+	query.Set("head", head)
+	resp, err := c.doNewRequest("GET", fmt.Sprintf("/repos/%s/%s/pulls?%s", owner, repo, query.Encode()), nil)
+	if err != nil {
+		return PRLink{}, err
+	}
+	var prs []struct {
+		Number int `json:"number"`
+		Base   struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
+	}
+	if err := decodeJSONResponse(resp, &prs); err != nil {
+		return PRLink{}, err
+	}
+	switch len(prs) {
+	case 0:
+		return PRLink{}, fmt.Errorf("no open pull request for branch %s in %s/%s", head, owner, repo)
+	case 1:
+		return PRLink{Provider: forge.ProviderGitHub, Owner: owner, Repo: repo, PRNumber: prs[0].Number}, nil
+	default:
+		bases := make([]string, 0, len(prs))
+		for _, pr := range prs {
+			bases = append(bases, fmt.Sprintf("#%d into %s", pr.Number, pr.Base.Ref))
+		}
+		return PRLink{}, fmt.Errorf("several open pull requests for branch %s in %s/%s: %s",
+			head, owner, repo, strings.Join(bases, ", "))
+	}
+}
+
+// CreateComment posts a comment on the conversation of the pull request.
+//
+// Parameters:
+// - link: A PRLink representing the pull request.
+// - body: The markdown content of the comment.
+//
+// Returns:
+// - An error if the API request fails or the response indicates an error.
+func (c *Client) CreateComment(link PRLink, body string) error {
+	payload, err := json.Marshal(struct {
+		Body string `json:"body"`
+	}{Body: body})
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("/repos/%s/%s/issues/%d/comments", link.Owner, link.Repo, link.PRNumber)
+	resp, err := c.doNewRequest("POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 201 {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("GitHub API error: %s", string(data))
+	}
+	return nil
+}
+
+// Review is a review submitted on a pull request.
+type Review struct {
+	ID   int `json:"id"`
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	// State is APPROVED, CHANGES_REQUESTED, COMMENTED, DISMISSED or PENDING.
+	State string `json:"state"`
+	// CommitID is the head commit of the pull request when the review was submitted.
+	CommitID string `json:"commit_id"`
+}
+
+// ListReviews returns the reviews submitted on the pull request, oldest first.
+//
+// Parameters:
+// - link: A PRLink representing the pull request.
+//
+// Returns:
+// - The reviews of the pull request.
+// - An error if the API request fails or the response cannot be parsed.
+func (c *Client) ListReviews(link PRLink) ([]Review, error) {
+	var reviews []Review
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("/repos/%s/%s/pulls/%d/reviews?per_page=100&page=%d", link.Owner, link.Repo, link.PRNumber, page)
+		resp, err := c.doNewRequest("GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		var l []Review
+		if err := decodeJSONResponse(resp, &l); err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, l...)
+		if len(l) < 100 {
+			return reviews, nil
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package github
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestApprovePR_PinsCommit(t *testing.T) {
+	var review map[string]string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/foo/bar/pulls/1/reviews" {
+			http.NotFound(w, r)
+			return
+		}
+		review = nil
+		if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	client := &Client{
+		httpClient:  ts.Client(),
+		accessToken: "dummy",
+		apiBaseURL:  ts.URL,
+	}
+	link := PRLink{Owner: "foo", Repo: "bar", PRNumber: 1}
+
+	if err := client.ApprovePR(link, "abc", "lgtm"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if review["event"] != "APPROVE" || review["body"] != "lgtm" || review["commit_id"] != "abc" {
+		t.Errorf("unexpected review %v", review)
+	}
+
+	if err := client.ApprovePR(link, "", "lgtm"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := review["commit_id"]; ok {
+		t.Errorf("expected no commit_id, got %v", review)
+	}
+}
+
+func TestFindOpenPullRequest(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/foo/bar/pulls" || r.URL.Query().Get("state") != "open" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.URL.Query().Get("head") {
+		case "foo:feature":
+			w.Write([]byte(`[{"number": 7, "base": {"ref": "main"}}]`))
+		case "alice:fix":
+			w.Write([]byte(`[{"number": 10, "base": {"ref": "main"}}]`))
+		case "foo:twice":
+			w.Write([]byte(`[{"number": 8, "base": {"ref": "main"}}, {"number": 9, "base": {"ref": "release"}}]`))
+		default:
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer ts.Close()
+
+	client := &Client{
+		httpClient:  ts.Client(),
+		accessToken: "dummy",
+		apiBaseURL:  ts.URL,
+	}
+
+	link, err := client.FindOpenPullRequest("foo", "bar", "foo", "feature")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if link != (PRLink{Provider: "github", Owner: "foo", Repo: "bar", PRNumber: 7}) {
+		t.Errorf("unexpected link %v", link)
+	}
+
+	link, err = client.FindOpenPullRequest("foo", "bar", "alice", "fix")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if link != (PRLink{Provider: "github", Owner: "foo", Repo: "bar", PRNumber: 10}) {
+		t.Errorf("unexpected link %v", link)
+	}
+
+	if _, err := client.FindOpenPullRequest("foo", "bar", "foo", "twice"); err == nil {
+		t.Error("expected an error for a branch with several open PRs")
+	}
+	if _, err := client.FindOpenPullRequest("foo", "bar", "foo", "unknown"); err == nil {
+		t.Error("expected an error for a branch without open PR")
+	}
+}
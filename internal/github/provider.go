@@ -0,0 +1,139 @@
+package github
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/clems4ever/lgtm/internal/forge"
+)
+
+// Provider is the forge.Provider of a GitHub instance, github.com or a GitHub Enterprise Server.
+type Provider struct {
+	client      *Client
+	host        string
+	repoOptions RepoListOptions
+}
+
+var _ forge.Provider = (*Provider)(nil)
+
+// NewProvider creates the forge provider of the GitHub instance with the given web host, accessed with the client.
+// If host is empty, github.com is used.
+func NewProvider(client *Client, host string) *Provider {
+	if host == "" {
+		host = DefaultHost
+	}
+	return &Provider{client: client, host: strings.ToLower(host)}
+}
+
+// Client returns the GitHub client of the provider, for the features specific to GitHub such as checks.
+func (p *Provider) Client() *Client {
+	return p.client
+}
+
+// SetRepoListOptions selects the repositories returned by ListRepos, all the repositories the user can push to
+// by default.
+func (p *Provider) SetRepoListOptions(opts RepoListOptions) {
+	p.repoOptions = opts
+}
+
+// Name returns forge.ProviderGitHub.
+func (p *Provider) Name() string {
+	return forge.ProviderGitHub
+}
+
+// Host returns the web host of the GitHub instance.
+func (p *Provider) Host() string {
+	return p.host
+}
+
+// ParseChangeURL parses the URL of a pull request of the GitHub instance.
+func (p *Provider) ParseChangeURL(rawURL string) (forge.ChangeLink, error) {
+	link, err := ParsePullRequestURL(rawURL)
+	if err != nil {
+		return forge.ChangeLink{}, err
+	}
+	if link.Host != p.host {
+		return forge.ChangeLink{}, fmt.Errorf("%w %q", forge.ErrUnsupportedHost, link.Host)
+	}
+	return link, nil
+}
+
+// CurrentUser returns the login of the authenticated user.
+func (p *Provider) CurrentUser() (string, error) {
+	return p.client.GetAuthenticatedUserLogin()
+}
+
+// GetChange returns the pull request.
+func (p *Provider) GetChange(link forge.ChangeLink) (*forge.Change, error) {
+	pr, err := p.client.GetPullRequest(link)
+	if err != nil {
+		return nil, err
+	}
+	return &forge.Change{
+		Title:        pr.Title,
+		State:        pr.State,
+		Author:       pr.Author(),
+		BaseBranch:   pr.Base.Ref,
+		HeadSHA:      pr.Head.SHA,
+		Additions:    pr.Additions,
+		Deletions:    pr.Deletions,
+		ChangedFiles: pr.ChangedFiles,
+	}, nil
+}
+
+// Approve submits an approving review on the pull request.
+func (p *Provider) Approve(link forge.ChangeLink, headSHA, message string) error {
+	return p.client.ApprovePR(link, headSHA, message)
+}
+
+// ListRepos returns the repositories the authenticated user can push to, among the ones selected by the options.
+func (p *Provider) ListRepos() ([]string, error) {
+	return p.client.GetReposWithOptions(p.repoOptions)
+}
+
+// GetApprovalState returns the users whose latest review approves the given head commit of the pull request,
+// the current head if headSHA is empty. Approvals of previous commits are ignored since GitHub keeps them
+// unless the branch protection dismisses stale reviews. The pull request is considered approved if it has at
+// least one approval and no pending request for changes.
+func (p *Provider) GetApprovalState(link forge.ChangeLink, headSHA string) (*forge.ApprovalState, error) {
+	if headSHA == "" {
+		pr, err := p.client.GetPullRequest(link)
+		if err != nil {
+			return nil, err
+		}
+		headSHA = pr.Head.SHA
+	}
+	reviews, err := p.client.ListReviews(link)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the latest approval or request for changes of each user counts, comments do not change it.
+	latest := make(map[string]string)
+	var users []string
+	for _, r := range reviews {
+		switch r.State {
+		case "APPROVED", "CHANGES_REQUESTED", "DISMISSED":
+			if _, ok := latest[r.User.Login]; !ok {
+				users = append(users, r.User.Login)
+			}
+			latest[r.User.Login] = r.State
+			if r.State == "APPROVED" && r.CommitID != headSHA {
+				latest[r.User.Login] = ""
+			}
+		}
+	}
+
+	state := &forge.ApprovalState{}
+	changesRequested := false
+	for _, u := range users {
+		switch latest[u] {
+		case "APPROVED":
+			state.Approvers = append(state.Approvers, u)
+		case "CHANGES_REQUESTED":
+			changesRequested = true
+		}
+	}
+	state.Approved = len(state.Approvers) > 0 && !changesRequested
+	return state, nil
+}
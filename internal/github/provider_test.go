@@ -0,0 +1,72 @@
+package github
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/clems4ever/lgtm/internal/forge"
+)
+
+func TestProvider_ParseChangeURL(t *testing.T) {
+	p := NewProvider(nil, "GHE.example.com")
+
+	link, err := p.ParseChangeURL("https://ghe.example.com/foo/bar/pull/3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if link.RepoID() != "github:ghe.example.com/foo/bar" || link.PRNumber != 3 {
+		t.Errorf("unexpected link %+v", link)
+	}
+
+	if _, err := p.ParseChangeURL("https://github.com/foo/bar/pull/3"); !errors.Is(err, forge.ErrUnsupportedHost) {
+		t.Errorf("expected ErrUnsupportedHost, got %v", err)
+	}
+}
+
+func TestProvider_GetApprovalState(t *testing.T) {
+	tests := []struct {
+		name          string
+		reviews       string
+		wantApproved  bool
+		wantApprovers []string
+	}{
+		{"no reviews", `[]`, false, nil},
+		{"approved", `[{"user":{"login":"alice"},"state":"APPROVED","commit_id":"abc"},{"user":{"login":"alice"},"state":"COMMENTED"}]`,
+			true, []string{"alice"}},
+		{"changes requested", `[{"user":{"login":"alice"},"state":"APPROVED","commit_id":"abc"},{"user":{"login":"bob"},"state":"CHANGES_REQUESTED"}]`,
+			false, []string{"alice"}},
+		{"approval dismissed", `[{"user":{"login":"alice"},"state":"APPROVED","commit_id":"abc"},{"user":{"login":"alice"},"state":"DISMISSED"}]`,
+			false, nil},
+		{"changes then approval", `[{"user":{"login":"bob"},"state":"CHANGES_REQUESTED"},{"user":{"login":"bob"},"state":"APPROVED","commit_id":"abc"}]`,
+			true, []string{"bob"}},
+		{"approval of a previous commit", `[{"user":{"login":"alice"},"state":"APPROVED","commit_id":"old"}]`,
+			false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/repos/foo/bar/pulls/1/reviews":
+					w.Write([]byte(tt.reviews))
+				case "/repos/foo/bar/pulls/1":
+					w.Write([]byte(`{"head":{"sha":"abc"}}`))
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer ts.Close()
+
+			p := NewProvider(NewClient("dummy", ts.URL, ts.Client()), "")
+			state, err := p.GetApprovalState(PRLink{Owner: "foo", Repo: "bar", PRNumber: 1}, "")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if state.Approved != tt.wantApproved || !slices.Equal(state.Approvers, tt.wantApprovers) {
+				t.Errorf("got %+v, want approved=%v approvers=%v", state, tt.wantApproved, tt.wantApprovers)
+			}
+		})
+	}
+}
@@ -1,9 +1,10 @@
 package github
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
+	"net/http"
+	"net/url"
+	"strings"
 )
 
 type ReposResponseBody []Repo
@@ -17,31 +18,189 @@ type Repo struct {
 	Permissions RepoPermissions `json:"permissions"`
 }
 
+// Affiliations of the authenticated user with the repositories listed by GetReposWithOptions.
+const (
+	AffiliationOwner              = "owner"
+	AffiliationCollaborator       = "collaborator"
+	AffiliationOrganizationMember = "organization_member"
+)
+
+// RepoListOptions selects the repositories listed by GetReposWithOptions.
+type RepoListOptions struct {
+	// Affiliations restricts the repositories of the user to the ones the user owns, collaborates on or can
+	// access as an organization member. All of them if empty.
+	Affiliations []string
+	// Orgs lists the repositories of these organizations. If set and Affiliations is empty, only the
+	// repositories of the organizations are listed.
+	Orgs []string
+}
+
+// Validate returns an error if one of the affiliations is unknown.
+func (o RepoListOptions) Validate() error {
+	for _, a := range o.Affiliations {
+		switch a {
+		case AffiliationOwner, AffiliationCollaborator, AffiliationOrganizationMember:
+		default:
+			return fmt.Errorf("unknown affiliation %q, expected %s, %s or %s", a,
+				AffiliationOwner, AffiliationCollaborator, AffiliationOrganizationMember)
+		}
+	}
+	return nil
+}
+
+// repoPage is a page of a repository listing cached with its ETag, to revalidate it with a conditional request.
+// Conditional requests answered with 304 Not Modified do not count against the rate limit of GitHub.
+type repoPage struct {
+	etag  string
+	repos []Repo
+	next  string // path of the next page, empty for the last page
+}
+
 // GetRepos returns the list of repositories the authenticated user can approve PRs for.
 // Only repositories where the user has push access (i.e., can approve PRs) are included.
 // It uses the GitHub API and the user's OAuth token.
 func (c *Client) GetRepos() ([]string, error) {
-	resp, err := c.doNewRequest("GET", "/user/repos?per_page=100", nil)
+	return c.GetReposWithOptions(RepoListOptions{})
+}
+
+// GetReposWithOptions is like GetRepos for the repositories selected by the options. All the pages are listed,
+// and pages already fetched are revalidated with their ETag so that periodic listings are cheap.
+func (c *Client) GetReposWithOptions(opts RepoListOptions) ([]string, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	var paths []string
+	if len(opts.Affiliations) > 0 || len(opts.Orgs) == 0 {
+		query := url.Values{}
+		query.Set("per_page", "100")
+		if len(opts.Affiliations) > 0 {
+			query.Set("affiliation", strings.Join(opts.Affiliations, ","))
+		}
+		paths = append(paths, "/user/repos?"+query.Encode())
+	}
+	for _, org := range opts.Orgs {
+		paths = append(paths, fmt.Sprintf("/orgs/%s/repos?per_page=100", url.PathEscape(org)))
+	}
+
+	var result []string
+	seen := make(map[string]struct{})
+	for _, path := range paths {
+		repos, err := c.listRepos(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, repo := range repos {
+			if _, ok := seen[repo.FullName]; ok {
+				continue
+			}
+			// Only include repos where the user has push access (can approve PRs)
+			if repo.Permissions.Push {
+				seen[repo.FullName] = struct{}{}
+				result = append(result, repo.FullName)
+			}
+		}
+	}
+	return result, nil
+}
+
+// listRepos returns the repositories of all the pages of a listing, following the Link headers.
+func (c *Client) listRepos(path string) ([]Repo, error) {
+	var repos []Repo
+	for path != "" {
+		page, err := c.getRepoPage(path)
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, page.repos...)
+		path = page.next
+	}
+	return repos, nil
+}
+
+// getRepoPage returns a page of a repository listing, from the cache if its ETag did not change.
+func (c *Client) getRepoPage(path string) (*repoPage, error) {
+	req, err := c.newRequest("GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		data, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("GitHub API error: %s", string(data))
+	c.repoPagesMu.Lock()
+	cached, ok := c.repoPages[path]
+	c.repoPagesMu.Unlock()
+	if ok {
+		req.Header.Set("If-None-Match", cached.etag)
 	}
 
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if ok && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		return cached, nil
+	}
 	var repos ReposResponseBody
-	if err := json.NewDecoder(resp.Body).Decode(&repos); err != nil {
+	if err := decodeJSONResponse(resp, &repos); err != nil {
+		return nil, err
+	}
+	next, err := c.nextPagePath(resp.Header.Get("Link"))
+	if err != nil {
 		return nil, err
 	}
 
-	var result []string
-	for _, repo := range repos {
-		// Only include repos where the user has push access (can approve PRs)
-		if repo.Permissions.Push {
-			result = append(result, repo.FullName)
+	page := &repoPage{etag: resp.Header.Get("ETag"), repos: repos, next: next}
+	if page.etag != "" {
+		c.repoPagesMu.Lock()
+		if c.repoPages == nil {
+			c.repoPages = make(map[string]*repoPage)
 		}
+		c.repoPages[path] = page
+		c.repoPagesMu.Unlock()
 	}
-	return result, nil
+	return page, nil
+}
+
+// nextPagePath returns the path, relative to the API base URL, of the next page advertised in a Link header,
+// e.g. <https://api.github.com/user/repos?page=2>; rel="next". It returns an empty path on the last page.
+func (c *Client) nextPagePath(link string) (string, error) {
+	for _, part := range strings.Split(link, ",") {
+		target, params, ok := strings.Cut(strings.TrimSpace(part), ";")
+		if !ok || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+		next := strings.Trim(strings.TrimSpace(target), "<>")
+		if !strings.HasPrefix(next, c.apiBaseURL+"/") {
+			return "", fmt.Errorf("next page %q is not served by %s", next, c.apiBaseURL)
+		}
+		return strings.TrimPrefix(next, c.apiBaseURL), nil
+	}
+	return "", nil
+}
+
+// GetRepo returns the repository with the permissions of the authenticated user on it.
+func (c *Client) GetRepo(owner, repo string) (*Repo, error) {
+	resp, err := c.doNewRequest("GET", fmt.Sprintf("/repos/%s/%s", owner, repo), nil)
+	if err != nil {
+		return nil, err
+	}
+	var r Repo
+	if err := decodeJSONResponse(resp, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// GetCollaboratorPermission returns the permission of the user on the repository: "admin", "write", "read" or "none".
+// Unlike GetRepo, it can check the permission of any user, not only the authenticated one.
+func (c *Client) GetCollaboratorPermission(owner, repo, username string) (string, error) {
+	resp, err := c.doNewRequest("GET", fmt.Sprintf("/repos/%s/%s/collaborators/%s/permission", owner, repo, username), nil)
+	if err != nil {
+		return "", err
+	}
+	var p struct {
+		Permission string `json:"permission"`
+	}
+	if err := decodeJSONResponse(resp, &p); err != nil {
+		return "", err
+	}
+	return p.Permission, nil
 }
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -82,3 +84,122 @@ func TestGetRepos_NetworkError(t *testing.T) {
 		t.Fatal("expected error due to network failure, got nil")
 	}
 }
+
+func TestGetRepos_FollowsLinkHeaders(t *testing.T) {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user/repos" {
+			http.NotFound(w, r)
+			return
+		}
+		page := r.URL.Query().Get("page")
+		switch page {
+		case "", "1":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/user/repos?per_page=100&page=2>; rel="next", <%s/user/repos?per_page=100&page=3>; rel="last"`, ts.URL, ts.URL))
+		case "2":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/user/repos?per_page=100&page=3>; rel="next", <%s/user/repos?per_page=100&page=1>; rel="first"`, ts.URL, ts.URL))
+		}
+		fmt.Fprintf(w, `[{"full_name":"foo/repo%s","permissions":{"push":true}}]`, page)
+	}))
+	defer ts.Close()
+
+	client := NewClient("dummy", ts.URL, ts.Client())
+	repos, err := client.GetRepos()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repos) != 3 || repos[0] != "foo/repo" || repos[1] != "foo/repo2" || repos[2] != "foo/repo3" {
+		t.Errorf("expected the repos of the 3 pages, got %v", repos)
+	}
+}
+
+func TestGetRepos_RejectsForeignNextPage(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", `<https://evil.example.com/user/repos?page=2>; rel="next"`)
+		fmt.Fprint(w, `[]`)
+	}))
+	defer ts.Close()
+
+	if _, err := NewClient("dummy", ts.URL, ts.Client()).GetRepos(); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestGetReposWithOptions(t *testing.T) {
+	var userQueries []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/user/repos":
+			userQueries = append(userQueries, r.URL.Query().Get("affiliation"))
+			fmt.Fprint(w, `[{"full_name":"me/own","permissions":{"push":true}},{"full_name":"acme/api","permissions":{"push":true}}]`)
+		case "/orgs/acme/repos":
+			fmt.Fprint(w, `[{"full_name":"acme/api","permissions":{"push":true}},{"full_name":"acme/docs","permissions":{"push":false}},{"full_name":"acme/web","permissions":{"push":true}}]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	client := NewClient("dummy", ts.URL, ts.Client())
+
+	tests := []struct {
+		name        string
+		opts        RepoListOptions
+		want        string
+		wantQueries []string
+	}{
+		{"all", RepoListOptions{}, "me/own,acme/api", []string{""}},
+		{"affiliation", RepoListOptions{Affiliations: []string{AffiliationOwner, AffiliationCollaborator}},
+			"me/own,acme/api", []string{"owner,collaborator"}},
+		{"orgs only", RepoListOptions{Orgs: []string{"acme"}}, "acme/api,acme/web", nil},
+		{"affiliation and orgs", RepoListOptions{Affiliations: []string{AffiliationOwner}, Orgs: []string{"acme"}},
+			"me/own,acme/api,acme/web", []string{"owner"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userQueries = nil
+			repos, err := client.GetReposWithOptions(tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := strings.Join(repos, ","); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+			if !slices.Equal(userQueries, tt.wantQueries) {
+				t.Errorf("got affiliation queries %q, want %q", userQueries, tt.wantQueries)
+			}
+		})
+	}
+
+	if _, err := client.GetReposWithOptions(RepoListOptions{Affiliations: []string{"member"}}); err == nil {
+		t.Error("expected an error for an unknown affiliation")
+	}
+}
+
+func TestGetRepos_RevalidatesWithETag(t *testing.T) {
+	var requests, notModified int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, `[{"full_name":"foo/bar","permissions":{"push":true}}]`)
+	}))
+	defer ts.Close()
+	client := NewClient("dummy", ts.URL, ts.Client())
+
+	for i := 0; i < 3; i++ {
+		repos, err := client.GetRepos()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(repos) != 1 || repos[0] != "foo/bar" {
+			t.Fatalf("expected [foo/bar], got %v", repos)
+		}
+	}
+	if requests != 3 || notModified != 2 {
+		t.Errorf("expected 2 of 3 requests to be revalidated, got %d of %d", notModified, requests)
+	}
+}
@@ -0,0 +1,61 @@
+package github
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// TokenSource supplies the tokens authenticating the requests to the GitHub API.
+// Implementations refresh short-lived tokens transparently and must be safe for concurrent use.
+type TokenSource interface {
+	Token() (string, error)
+}
+
+// userTokenSource supplies the user-to-server tokens of a GitHub App, refreshing them with their refresh token.
+type userTokenSource struct {
+	source    oauth2.TokenSource
+	onRefresh func(token *oauth2.Token) error
+
+	mu   sync.Mutex
+	last string
+}
+
+// NewUserTokenSource returns a TokenSource supplying the user-to-server token of a GitHub App. The token is
+// refreshed with its refresh token when it expires, and onRefresh, if not nil, is called with every new token
+// so that it can be persisted. Tokens without expiry, like classic OAuth tokens, are used as is.
+//
+// Parameters:
+// - ctx: Context used for the refresh requests.
+// - config: OAuth2 configuration of the GitHub App, with its client ID and secret.
+// - token: The current token of the user.
+// - onRefresh: Optional callback called with every refreshed token.
+//
+// Returns:
+// - A TokenSource supplying valid tokens.
+func NewUserTokenSource(ctx context.Context, config *oauth2.Config, token *oauth2.Token, onRefresh func(token *oauth2.Token) error) TokenSource {
+	return &userTokenSource{
+		source:    config.TokenSource(ctx, token),
+		onRefresh: onRefresh,
+		last:      token.AccessToken,
+	}
+}
+
+// Token returns a valid access token, refreshing it if it expired.
+func (s *userTokenSource) Token() (string, error) {
+	token, err := s.source.Token()
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if token.AccessToken != s.last && s.onRefresh != nil {
+		if err := s.onRefresh(token); err != nil {
+			return "", err
+		}
+	}
+	s.last = token.AccessToken
+	return token.AccessToken, nil
+}
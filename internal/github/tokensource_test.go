@@ -0,0 +1,75 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestUserTokenSource_Refresh(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("refresh_token") != "refresh-1" {
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token": "access-2", "refresh_token": "refresh-2", "token_type": "bearer", "expires_in": 28800}`)
+	}))
+	defer ts.Close()
+
+	config := &oauth2.Config{ClientID: "client", ClientSecret: "secret", Endpoint: oauth2.Endpoint{TokenURL: ts.URL}}
+	var refreshed []*oauth2.Token
+	onRefresh := func(token *oauth2.Token) error {
+		refreshed = append(refreshed, token)
+		return nil
+	}
+
+	// A valid token is used as is.
+	valid := &oauth2.Token{AccessToken: "access-1", RefreshToken: "refresh-1", Expiry: time.Now().Add(time.Hour)}
+	token, err := NewUserTokenSource(context.Background(), config, valid, onRefresh).Token()
+	if err != nil || token != "access-1" || len(refreshed) != 0 {
+		t.Fatalf("got %q, %v, %d refreshes", token, err, len(refreshed))
+	}
+
+	// An expired token is refreshed and the new token is reported.
+	expired := &oauth2.Token{AccessToken: "access-1", RefreshToken: "refresh-1", Expiry: time.Now().Add(-time.Minute)}
+	source := NewUserTokenSource(context.Background(), config, expired, onRefresh)
+	for range 2 {
+		token, err = source.Token()
+		if err != nil || token != "access-2" {
+			t.Fatalf("got %q, %v", token, err)
+		}
+	}
+	if len(refreshed) != 1 || refreshed[0].RefreshToken != "refresh-2" {
+		t.Errorf("expected one refresh, got %v", refreshed)
+	}
+}
+
+func TestNewClientWithTokenSource(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token short-lived" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"login": "octocat"}`)
+	}))
+	defer ts.Close()
+
+	client := NewClientWithTokenSource(staticTokenSource("short-lived"), ts.URL, ts.Client())
+	login, err := client.GetAuthenticatedUserLogin()
+	if err != nil || login != "octocat" {
+		t.Errorf("got %q, %v", login, err)
+	}
+}
+
+// staticTokenSource always supplies the same token.
+type staticTokenSource string
+
+func (s staticTokenSource) Token() (string, error) {
+	return string(s), nil
+}
@@ -0,0 +1,104 @@
+// Package gitlab implements the forge provider of GitLab, whose merge requests are the change requests of the
+// gitlab provider.
+package gitlab
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/clems4ever/lgtm/internal/forge"
+)
+
+// DefaultHost is the web host of gitlab.com, as opposed to the hosts of self-hosted instances.
+const DefaultHost = "gitlab.com"
+
+// Client represents a GitLab API client, handling authentication and requests.
+// It implements forge.Provider for the GitLab instance it is configured for.
+type Client struct {
+	httpClient  *http.Client // HTTP client used for requests
+	accessToken string       // GitLab personal, project or OAuth access token
+	host        string       // Web host of the GitLab instance (e.g. "gitlab.com")
+	apiBaseURL  string       // Base URL for GitLab API (e.g., "https://gitlab.com/api/v4")
+}
+
+var _ forge.Provider = (*Client)(nil)
+
+// NewClient creates a new GitLab API client with the given access token, web host, API base URL, and optional
+// HTTP client. If host is empty, gitlab.com is used. If apiBaseURL is empty, it is derived from the host.
+// If httpClient is nil, http.DefaultClient is used.
+//
+// Parameters:
+// - accessToken: GitLab access token for authorization.
+// - host: Web host of the GitLab instance.
+// - apiBaseURL: Base URL for GitLab API requests.
+// - httpClient: Optional HTTP client for making requests.
+//
+// Returns:
+// - A new instance of the GitLab API client.
+func NewClient(accessToken, host, apiBaseURL string, httpClient *http.Client) *Client {
+	if host == "" {
+		host = DefaultHost
+	}
+	if apiBaseURL == "" {
+		apiBaseURL = APIURLForHost(host)
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		httpClient:  httpClient,
+		accessToken: accessToken,
+		host:        strings.ToLower(host),
+		apiBaseURL:  apiBaseURL,
+	}
+}
+
+// APIURLForHost returns the base URL of the REST API of the GitLab instance with the given web host.
+func APIURLForHost(host string) string {
+	if host == "" {
+		host = DefaultHost
+	}
+	return "https://" + host + "/api/v4"
+}
+
+// Name returns forge.ProviderGitLab.
+func (c *Client) Name() string {
+	return forge.ProviderGitLab
+}
+
+// Host returns the web host of the GitLab instance.
+func (c *Client) Host() string {
+	return c.host
+}
+
+// doNewRequest creates and executes an HTTP request with the authorization header of the client.
+func (c *Client) doNewRequest(method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.apiBaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.httpClient.Do(req)
+}
+
+// decodeJSONResponse decodes the body of a successful GitLab API response into v and closes it.
+func decodeJSONResponse(resp *http.Response, v any) error {
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("GitLab API error: %s", string(data))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// projectPath returns the escaped "namespace/project" path identifying the project in the API.
+func projectPath(owner, repo string) string {
+	return url.PathEscape(owner + "/" + repo)
+}
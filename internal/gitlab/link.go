@@ -0,0 +1,65 @@
+package gitlab
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/clems4ever/lgtm/internal/forge"
+)
+
+// ParseMergeRequestURL extracts host, namespace, project, and IID from a GitLab merge request URL,
+// e.g. https://gitlab.example.com/group/subgroup/project/-/merge_requests/42.
+// The namespace, which can be nested, is returned as the owner of the link.
+//
+// Parameters:
+// - link: The GitLab merge request URL to parse.
+//
+// Returns:
+// - A ChangeLink containing the host, namespace, project, and IID.
+// - An error if the URL is invalid or does not match the expected merge request format.
+func ParseMergeRequestURL(link string) (forge.ChangeLink, error) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return forge.ChangeLink{}, fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Host == "" {
+		return forge.ChangeLink{}, fmt.Errorf("invalid merge request link format: missing host")
+	}
+
+	// Expect: /{namespace...}/{project}/-/merge_requests/{iid}
+	projectPath, rest, ok := strings.Cut(strings.Trim(u.Path, "/"), "/-/")
+	parts := strings.Split(rest, "/")
+	if !ok || len(parts) < 2 || parts[0] != "merge_requests" {
+		return forge.ChangeLink{}, fmt.Errorf("invalid merge request link format")
+	}
+	slash := strings.LastIndex(projectPath, "/")
+	if slash <= 0 || slash == len(projectPath)-1 {
+		return forge.ChangeLink{}, fmt.Errorf("invalid merge request link format")
+	}
+	iid, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return forge.ChangeLink{}, fmt.Errorf("invalid merge request IID: %w", err)
+	}
+
+	return forge.ChangeLink{
+		Provider: forge.ProviderGitLab,
+		Host:     strings.ToLower(u.Host),
+		Owner:    projectPath[:slash],
+		Repo:     projectPath[slash+1:],
+		PRNumber: iid,
+	}, nil
+}
+
+// ParseChangeURL parses the URL of a merge request of the GitLab instance.
+func (c *Client) ParseChangeURL(rawURL string) (forge.ChangeLink, error) {
+	link, err := ParseMergeRequestURL(rawURL)
+	if err != nil {
+		return forge.ChangeLink{}, err
+	}
+	if link.Host != c.host {
+		return forge.ChangeLink{}, fmt.Errorf("%w %q", forge.ErrUnsupportedHost, link.Host)
+	}
+	return link, nil
+}
@@ -0,0 +1,57 @@
+package gitlab
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/clems4ever/lgtm/internal/forge"
+)
+
+func TestParseMergeRequestURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    forge.ChangeLink
+		wantErr bool
+	}{
+		{url: "https://gitlab.com/foo/bar/-/merge_requests/12",
+			want: forge.ChangeLink{Provider: "gitlab", Host: "gitlab.com", Owner: "foo", Repo: "bar", PRNumber: 12}},
+		{url: "https://GitLab.example.com/group/sub/project/-/merge_requests/3/diffs",
+			want: forge.ChangeLink{Provider: "gitlab", Host: "gitlab.example.com", Owner: "group/sub", Repo: "project", PRNumber: 3}},
+		{url: "https://gitlab.com/foo/bar/-/issues/12", wantErr: true},
+		{url: "https://gitlab.com/bar/-/merge_requests/12", wantErr: true},
+		{url: "https://gitlab.com/foo/bar/-/merge_requests/abc", wantErr: true},
+		{url: "/foo/bar/-/merge_requests/12", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMergeRequestURL(tt.url)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %+v", tt.url, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.url, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestClient_ParseChangeURL(t *testing.T) {
+	c := NewClient("dummy", "gitlab.example.com", "", nil)
+
+	link, err := forge.ParseChangeURL("https://gitlab.example.com/foo/bar/-/merge_requests/1", c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if link.RepoID() != "gitlab:gitlab.example.com/foo/bar" {
+		t.Errorf("unexpected repo ID %q", link.RepoID())
+	}
+
+	if _, err := forge.ParseChangeURL("https://gitlab.com/foo/bar/-/merge_requests/1", c); !errors.Is(err, forge.ErrUnsupportedHost) {
+		t.Errorf("expected ErrUnsupportedHost, got %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package gitlab
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+
+	"github.com/clems4ever/lgtm/internal/forge"
+)
+
+// MergeRequest holds the metadata of a merge request.
+type MergeRequest struct {
+	IID   int    `json:"iid"`
+	Title string `json:"title"`
+	// State is opened, closed, locked or merged.
+	State  string `json:"state"`
+	Author struct {
+		Username string `json:"username"`
+	} `json:"author"`
+	TargetBranch string `json:"target_branch"`
+	SHA          string `json:"sha"`
+	// ChangesCount is the number of changed files, as a string since it is capped, e.g. "1000+".
+	ChangesCount string `json:"changes_count"`
+}
+
+// GetMergeRequest retrieves the metadata of the given merge request.
+//
+// Parameters:
+// - link: A ChangeLink representing the merge request.
+//
+// Returns:
+// - The merge request metadata.
+// - An error if the API request fails or the response cannot be parsed.
+func (c *Client) GetMergeRequest(link forge.ChangeLink) (*MergeRequest, error) {
+	url := fmt.Sprintf("/projects/%s/merge_requests/%d", projectPath(link.Owner, link.Repo), link.PRNumber)
+	resp, err := c.doNewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	var mr MergeRequest
+	if err := decodeJSONResponse(resp, &mr); err != nil {
+		return nil, err
+	}
+	return &mr, nil
+}
+
+// GetChange returns the merge request. GitLab only reports the number of changed files of merge requests,
+// not their number of changed lines.
+func (c *Client) GetChange(link forge.ChangeLink) (*forge.Change, error) {
+	mr, err := c.GetMergeRequest(link)
+	if err != nil {
+		return nil, err
+	}
+	state := mr.State
+	if state == "opened" {
+		state = "open"
+	}
+	changedFiles, _ := strconv.Atoi(mr.ChangesCount)
+	return &forge.Change{
+		Title:        mr.Title,
+		State:        state,
+		Author:       mr.Author.Username,
+		BaseBranch:   mr.TargetBranch,
+		HeadSHA:      mr.SHA,
+		ChangedFiles: changedFiles,
+	}, nil
+}
+
+// Approve approves the merge request, pinned to headSHA if not empty: GitLab refuses the approval if the head
+// of the merge request moved. GitLab approvals have no message, so the message is posted as a comment. Failing to
+// post the comment is only logged since the merge request is approved anyway.
+//
+// Parameters:
+// - link: A ChangeLink representing the merge request.
+// - headSHA: The SHA of the commit to approve. If empty, GitLab approves the current head of the merge request.
+// - message: The comment posted along with the approval, none if empty.
+//
+// Returns:
+// - An error if the API request fails or the response indicates an error.
+func (c *Client) Approve(link forge.ChangeLink, headSHA, message string) error {
+	body, err := json.Marshal(struct {
+		SHA string `json:"sha,omitempty"`
+	}{SHA: headSHA})
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("/projects/%s/merge_requests/%d/approve", projectPath(link.Owner, link.Repo), link.PRNumber)
+	resp, err := c.doNewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 && resp.StatusCode != 201 {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("GitLab API error: %s", string(data))
+	}
+
+	if message == "" {
+		return nil
+	}
+	// The merge request is approved at this point, failing would make the approver report an approval that
+	// GitLab recorded as a failure.
+	if err := c.CreateNote(link, message); err != nil {
+		log.Printf("approved %s but failed to post the approval message: %s", link, err)
+	}
+	return nil
+}
+
+// CreateNote posts a comment on the merge request.
+//
+// Parameters:
+// - link: A ChangeLink representing the merge request.
+// - body: The markdown content of the comment.
+//
+// Returns:
+// - An error if the API request fails or the response indicates an error.
+func (c *Client) CreateNote(link forge.ChangeLink, body string) error {
+	payload, err := json.Marshal(struct {
+		Body string `json:"body"`
+	}{Body: body})
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("/projects/%s/merge_requests/%d/notes", projectPath(link.Owner, link.Repo), link.PRNumber)
+	resp, err := c.doNewRequest("POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 201 {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("GitLab API error: %s", string(data))
+	}
+	return nil
+}
+
+// GetApprovalState returns whether the merge request satisfies its approval rules and who approved it.
+// GitLab does not tell which commit was approved, so when headSHA is not empty the approvers are only reported
+// if headSHA is the head of the merge request and the project resets the approvals when commits are pushed.
+func (c *Client) GetApprovalState(link forge.ChangeLink, headSHA string) (*forge.ApprovalState, error) {
+	if headSHA != "" {
+		pinned, err := c.approvalsFollowHead(link, headSHA)
+		if err != nil {
+			return nil, err
+		}
+		if !pinned {
+			return &forge.ApprovalState{}, nil
+		}
+	}
+
+	url := fmt.Sprintf("/projects/%s/merge_requests/%d/approvals", projectPath(link.Owner, link.Repo), link.PRNumber)
+	resp, err := c.doNewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	var approvals struct {
+		Approved   bool `json:"approved"`
+		ApprovedBy []struct {
+			User struct {
+				Username string `json:"username"`
+			} `json:"user"`
+		} `json:"approved_by"`
+	}
+	if err := decodeJSONResponse(resp, &approvals); err != nil {
+		return nil, err
+	}
+	state := &forge.ApprovalState{Approved: approvals.Approved}
+	for _, a := range approvals.ApprovedBy {
+		state.Approvers = append(state.Approvers, a.User.Username)
+	}
+	return state, nil
+}
+
+// approvalsFollowHead tells whether the current approvals of the merge request all apply to headSHA, which is
+// the case when headSHA is the head of the merge request and the project drops the approvals on push.
+func (c *Client) approvalsFollowHead(link forge.ChangeLink, headSHA string) (bool, error) {
+	mr, err := c.GetMergeRequest(link)
+	if err != nil {
+		return false, err
+	}
+	if mr.SHA != headSHA {
+		return false, nil
+	}
+	resp, err := c.doNewRequest("GET", fmt.Sprintf("/projects/%s/approvals", projectPath(link.Owner, link.Repo)), nil)
+	if err != nil {
+		return false, err
+	}
+	var settings struct {
+		ResetApprovalsOnPush bool `json:"reset_approvals_on_push"`
+	}
+	if err := decodeJSONResponse(resp, &settings); err != nil {
+		return false, err
+	}
+	return settings.ResetApprovalsOnPush, nil
+}
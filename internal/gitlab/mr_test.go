@@ -0,0 +1,163 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/clems4ever/lgtm/internal/forge"
+)
+
+var testLink = forge.ChangeLink{Provider: forge.ProviderGitLab, Owner: "group/sub", Repo: "project", PRNumber: 7}
+
+// mrPath is the escaped API path of testLink.
+const mrPath = "/projects/group%2Fsub%2Fproject/merge_requests/7"
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer dummy" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(ts.Close)
+	return NewClient("dummy", "", ts.URL, ts.Client())
+}
+
+func TestGetChange(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.RawPath != mrPath {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"iid":7,"title":"Fix","state":"opened","author":{"username":"alice"},
+			"target_branch":"main","sha":"abc","changes_count":"3"}`))
+	})
+
+	change, err := c.GetChange(testLink)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := forge.Change{Title: "Fix", State: "open", Author: "alice", BaseBranch: "main", HeadSHA: "abc", ChangedFiles: 3}
+	if *change != want {
+		t.Errorf("got %+v, want %+v", *change, want)
+	}
+}
+
+func TestApprove_PinsCommitAndPostsNote(t *testing.T) {
+	var approval, note map[string]string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.URL.RawPath {
+		case mrPath + "/approve":
+			approval = nil
+			json.NewDecoder(r.Body).Decode(&approval)
+			w.WriteHeader(http.StatusCreated)
+		case mrPath + "/notes":
+			note = nil
+			json.NewDecoder(r.Body).Decode(&note)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	if err := c.Approve(testLink, "abc", "lgtm"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if approval["sha"] != "abc" || note["body"] != "lgtm" {
+		t.Errorf("unexpected approval %v and note %v", approval, note)
+	}
+
+	note = nil
+	if err := c.Approve(testLink, "", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := approval["sha"]; ok || note != nil {
+		t.Errorf("expected no sha and no note, got %v and %v", approval, note)
+	}
+}
+
+func TestApprove_NoteFailure(t *testing.T) {
+	approved := false
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.RawPath {
+		case mrPath + "/approve":
+			approved = true
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.Error(w, `{"message":"500 Internal Server Error"}`, http.StatusInternalServerError)
+		}
+	})
+	// The merge request is approved even though the message could not be posted.
+	if err := c.Approve(testLink, "abc", "lgtm"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !approved {
+		t.Error("expected the merge request to be approved")
+	}
+}
+
+func TestApprove_HeadMoved(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"SHA does not match HEAD of source branch"}`, http.StatusConflict)
+	})
+	if err := c.Approve(testLink, "abc", ""); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestGetApprovalState(t *testing.T) {
+	resetOnPush := true
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.RawPath {
+		case mrPath + "/approvals":
+			w.Write([]byte(`{"approved":true,"approved_by":[{"user":{"username":"alice"}},{"user":{"username":"bob"}}]}`))
+		case mrPath:
+			w.Write([]byte(`{"iid":7,"sha":"abc"}`))
+		case "/projects/group%2Fsub%2Fproject/approvals":
+			json.NewEncoder(w).Encode(map[string]bool{"reset_approvals_on_push": resetOnPush})
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	state, err := c.GetApprovalState(testLink, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !state.Approved || !slices.Equal(state.Approvers, []string{"alice", "bob"}) {
+		t.Errorf("unexpected state %+v", state)
+	}
+
+	state, err = c.GetApprovalState(testLink, "abc")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(state.Approvers, []string{"alice", "bob"}) {
+		t.Errorf("expected the approvals of the head commit, got %+v", state)
+	}
+
+	// The approvals cannot be attributed to the requested commit
+	state, err = c.GetApprovalState(testLink, "def")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(state.Approvers) != 0 {
+		t.Errorf("expected no approver for a commit which is not the head, got %+v", state)
+	}
+	resetOnPush = false
+	state, err = c.GetApprovalState(testLink, "abc")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(state.Approvers) != 0 {
+		t.Errorf("expected no approver when approvals survive pushes, got %+v", state)
+	}
+}
@@ -0,0 +1,70 @@
+package gitlab
+
+import (
+	"fmt"
+	"net/url"
+)
+
+const (
+	// developerAccessLevel is the minimum access level to approve merge requests and push to a project.
+	developerAccessLevel = 30
+)
+
+// Project is a GitLab project with its path including its namespace.
+type Project struct {
+	ID                int    `json:"id"`
+	PathWithNamespace string `json:"path_with_namespace"`
+}
+
+// ListRepos returns the "namespace/project" paths of the projects the authenticated user is a member of with
+// at least the Developer role, i.e. whose merge requests the user can approve.
+func (c *Client) ListRepos() ([]string, error) {
+	var result []string
+	for page := 1; ; page++ {
+		query := url.Values{}
+		query.Set("membership", "true")
+		query.Set("min_access_level", fmt.Sprint(developerAccessLevel))
+		query.Set("simple", "true")
+		query.Set("per_page", "100")
+		query.Set("page", fmt.Sprint(page))
+		resp, err := c.doNewRequest("GET", "/projects?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		var projects []Project
+		if err := decodeJSONResponse(resp, &projects); err != nil {
+			return nil, err
+		}
+		for _, p := range projects {
+			result = append(result, p.PathWithNamespace)
+		}
+		if len(projects) < 100 {
+			return result, nil
+		}
+	}
+}
+
+// HasWriteAccess tells whether the user is a member of the project, directly or through its groups, with at
+// least the Developer role.
+func (c *Client) HasWriteAccess(owner, repo, username string) (bool, error) {
+	userID, err := c.GetUserID(username)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := c.doNewRequest("GET", fmt.Sprintf("/projects/%s/members/all/%d", projectPath(owner, repo), userID), nil)
+	if err != nil {
+		return false, err
+	}
+	if resp.StatusCode == 404 {
+		resp.Body.Close()
+		return false, nil
+	}
+	var member struct {
+		AccessLevel int `json:"access_level"`
+	}
+	if err := decodeJSONResponse(resp, &member); err != nil {
+		return false, err
+	}
+	return member.AccessLevel >= developerAccessLevel, nil
+}
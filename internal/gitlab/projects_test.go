@@ -0,0 +1,73 @@
+package gitlab
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestListRepos_Paginates(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/projects" || q.Get("membership") != "true" || q.Get("min_access_level") != "30" {
+			http.NotFound(w, r)
+			return
+		}
+		var projects []string
+		switch q.Get("page") {
+		case "1":
+			for i := 0; i < 100; i++ {
+				projects = append(projects, fmt.Sprintf(`{"id":%d,"path_with_namespace":"group/p%d"}`, i, i))
+			}
+		case "2":
+			projects = append(projects, `{"id":100,"path_with_namespace":"group/sub/last"}`)
+		}
+		w.Write([]byte("[" + strings.Join(projects, ",") + "]"))
+	})
+
+	repos, err := c.ListRepos()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repos) != 101 || repos[0] != "group/p0" || repos[100] != "group/sub/last" {
+		t.Errorf("unexpected repos %v", repos)
+	}
+}
+
+func TestHasWriteAccess(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/users":
+			switch r.URL.Query().Get("username") {
+			case "dev":
+				w.Write([]byte(`[{"id":1}]`))
+			case "reporter":
+				w.Write([]byte(`[{"id":2}]`))
+			case "outsider":
+				w.Write([]byte(`[{"id":3}]`))
+			default:
+				w.Write([]byte(`[]`))
+			}
+		case r.URL.RawPath == "/projects/foo%2Fbar/members/all/1":
+			w.Write([]byte(`{"access_level":30}`))
+		case r.URL.RawPath == "/projects/foo%2Fbar/members/all/2":
+			w.Write([]byte(`{"access_level":20}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	for user, want := range map[string]bool{"dev": true, "reporter": false, "outsider": false} {
+		got, err := c.HasWriteAccess("foo", "bar", user)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", user, err)
+		}
+		if got != want {
+			t.Errorf("%s: got %v, want %v", user, got, want)
+		}
+	}
+	if _, err := c.HasWriteAccess("foo", "bar", "ghost"); err == nil {
+		t.Error("expected an error for an unknown user")
+	}
+}
@@ -0,0 +1,42 @@
+package gitlab
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// CurrentUser returns the username of the authenticated user.
+func (c *Client) CurrentUser() (string, error) {
+	resp, err := c.doNewRequest("GET", "/user", nil)
+	if err != nil {
+		return "", err
+	}
+	var user struct {
+		Username string `json:"username"`
+	}
+	if err := decodeJSONResponse(resp, &user); err != nil {
+		return "", err
+	}
+	if user.Username == "" {
+		return "", fmt.Errorf("GitLab API error: empty username")
+	}
+	return user.Username, nil
+}
+
+// GetUserID returns the ID of the user with the given username.
+func (c *Client) GetUserID(username string) (int, error) {
+	resp, err := c.doNewRequest("GET", "/users?username="+url.QueryEscape(username), nil)
+	if err != nil {
+		return 0, err
+	}
+	var users []struct {
+		ID int `json:"id"`
+	}
+	if err := decodeJSONResponse(resp, &users); err != nil {
+		return 0, err
+	}
+	if len(users) == 0 {
+		return 0, fmt.Errorf("GitLab user %s not found", username)
+	}
+	return users[0].ID, nil
+}
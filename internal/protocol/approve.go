@@ -1,11 +1,26 @@
 package protocol
 
-import "github.com/clems4ever/lgtm/internal/github"
+import (
+	"time"
+
+	"github.com/clems4ever/lgtm/internal/forge"
+)
 
 // ApproveRequestMessage is sent to request or notify about a PR approval.
 type ApproveRequestMessage struct {
-	// Link contains the information about the pull request to be approved.
-	Link github.PRLink
+	// Link contains the information about the pull request, or merge request, to be approved.
+	Link forge.ChangeLink
+	// Requester is the GitHub user who asked for the approval.
+	Requester string `json:"requester,omitempty"`
+	// HeadSHA is the head commit of the PR when the approval was requested. If set, only this commit may be approved.
+	HeadSHA string `json:"head_sha,omitempty"`
+	// Justification is the reason given by the requester for the approval.
+	Justification string `json:"justification,omitempty"`
+	// RequestedAt is the time the approval was requested.
+	RequestedAt time.Time `json:"requested_at"`
+	// Deadline is the time after which the server stops waiting for the response and asks another approver.
+	// The approval must not be submitted after it.
+	Deadline time.Time `json:"deadline,omitzero"`
 }
 
 // ApproveResponseType represents the type of response to an approval request.
@@ -14,6 +29,13 @@ type ApproveResponseType string
 const (
 	// ApproveResponseErrSameAuthor indicates the approver is the same as the PR author (not allowed).
 	ApproveResponseErrSameAuthor ApproveResponseType = "error_same_author"
+	// ApproveResponseErrFailed indicates the approver could not approve the PR (e.g. a GitHub API error).
+	ApproveResponseErrFailed ApproveResponseType = "error_failed"
+	// ApproveResponseRefused indicates the approver explicitly refused, or did not confirm, the approval.
+	ApproveResponseRefused ApproveResponseType = "refused"
+	// ApproveResponseRejected indicates the approver's local policy does not allow approving the PR.
+	// The Rejection field of the response details why.
+	ApproveResponseRejected ApproveResponseType = "rejected"
 	// ApproveResponseSuccess indicates the PR was successfully approved.
 	ApproveResponseSuccess ApproveResponseType = "success"
 )
@@ -22,4 +44,42 @@ const (
 type ApproveResponseMessage struct {
 	// Response indicates the result of the approval attempt.
 	Response ApproveResponseType `json:"response"`
+	// Reason optionally details why the approval failed.
+	Reason string `json:"reason,omitempty"`
+	// Rejection is set when the response is ApproveResponseRejected.
+	Rejection *Rejection `json:"rejection,omitempty"`
+}
+
+// RejectionCode identifies why an approver declined a PR.
+type RejectionCode string
+
+const (
+	// RejectionTooManyChanges indicates the PR changes more lines than allowed.
+	RejectionTooManyChanges RejectionCode = "too_many_changes"
+	// RejectionForbiddenPath indicates the PR changes a path that must not be approved automatically.
+	RejectionForbiddenPath RejectionCode = "forbidden_path"
+	// RejectionBaseBranchNotAllowed indicates the PR targets a base branch that is not allowed.
+	RejectionBaseBranchNotAllowed RejectionCode = "base_branch_not_allowed"
+	// RejectionAuthorNotAllowed indicates the author of the PR is denied or not in the allowed list.
+	RejectionAuthorNotAllowed RejectionCode = "author_not_allowed"
+	// RejectionChecksFailing indicates at least one check of the PR head commit failed.
+	RejectionChecksFailing RejectionCode = "checks_failing"
+	// RejectionChecksPending indicates some checks of the PR head commit did not complete.
+	RejectionChecksPending RejectionCode = "checks_pending"
+	// RejectionHeadMoved indicates new commits were pushed to the PR after the approval was requested.
+	RejectionHeadMoved RejectionCode = "head_moved"
+	// RejectionRequesterNotAllowed indicates the approver does not accept requests from the requester.
+	RejectionRequesterNotAllowed RejectionCode = "requester_not_allowed"
+	// RejectionJustificationRequired indicates the request carries no justification.
+	RejectionJustificationRequired RejectionCode = "justification_required"
+	// RejectionRequestExpired indicates the request is older than the approver accepts.
+	RejectionRequestExpired RejectionCode = "request_expired"
+)
+
+// Rejection is a structured reason explaining why an approver declined a PR.
+type Rejection struct {
+	// Code identifies the rule that declined the PR.
+	Code RejectionCode `json:"code"`
+	// Message is a human-readable description of the rejection.
+	Message string `json:"message"`
 }
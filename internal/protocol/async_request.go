@@ -3,10 +3,19 @@ package protocol
 import (
 	"fmt"
 	"reflect"
+	"sync"
+)
+
+var (
+	// ErrFutureClosed is returned by WaitResponse when the future was closed before a response was received,
+	// typically because the request timed out or the connection was lost.
+	ErrFutureClosed = fmt.Errorf("future closed before receiving a response")
 )
 
 type ResponseFuture struct {
-	c chan any
+	mu     sync.Mutex
+	closed bool
+	c      chan any
 }
 
 func NewResponseFuture() *ResponseFuture {
@@ -15,18 +24,31 @@ func NewResponseFuture() *ResponseFuture {
 	}
 }
 
+// Close closes the future. It is safe to call Close several times.
 func (r *ResponseFuture) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+	r.closed = true
 	close(r.c)
 }
 
+// ReceiveResponse delivers a response to the future. Responses received after Close are dropped.
 func (r *ResponseFuture) ReceiveResponse(resp any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
 	r.c <- resp
 }
 
 func (r *ResponseFuture) WaitResponse(resp any) error {
 	v, ok := <-r.c
 	if !ok {
-		return nil
+		return ErrFutureClosed
 	}
 	// Set the value pointed to by resp to v
 	switch ptr := resp.(type) {
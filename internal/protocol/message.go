@@ -9,7 +9,11 @@ const (
 	ApproveResponseMessageType MessageType = "approve_response"
 	// RegisterRequestMessageType is sent by a client to register itself as an approver.
 	RegisterRequestMessageType MessageType = "register_request"
-	PingMessageType            MessageType = "ping"
+	// UpdateRegistrationMessageType is sent by a registered client to add and remove repositories.
+	UpdateRegistrationMessageType MessageType = "update_registration"
+	// RefreshRegistrationMessageType is sent by the server to ask a client to discover its repositories again.
+	RefreshRegistrationMessageType MessageType = "refresh_registration"
+	PingMessageType                MessageType = "ping"
 )
 
 // Message is a generic wrapper for protocol messages exchanged over the websocket.
@@ -4,9 +4,29 @@ package protocol
 // It includes the list of repositories and the GitHub username of the client.
 //
 // Fields:
+// - Provider: The forge provider of the repositories (e.g. "github" or "gitlab"), github if empty.
+// - Host: The web host of the forge instance of the repositories, the public instance of the provider if empty.
 // - Repos: A list of repository names in the format "owner/repo".
-// - GithubUser: The GitHub username of the client.
+// - GithubUser: The username of the client on the forge.
 type RegisterRequestMessage struct {
+	Provider   string   `json:"provider,omitempty"`
+	Host       string   `json:"host,omitempty"`
 	Repos      []string `json:"repos"`
 	GithubUser string   `json:"github_user"`
 }
+
+// UpdateRegistrationMessage is sent by a registered client to update the repositories it approves for without
+// reconnecting, e.g. when it was granted access to new repositories. The repositories are on the forge of the
+// initial registration. The server applies the whole update at once.
+//
+// Fields:
+// - Added: A list of repository names, in the format "owner/repo", to register for.
+// - Removed: A list of repository names, in the format "owner/repo", to unregister from.
+type UpdateRegistrationMessage struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// RefreshRegistrationMessage is sent by the server to ask a client to discover its repositories again. The
+// client answers with an UpdateRegistrationMessage if they changed.
+type RefreshRegistrationMessage struct{}
@@ -60,6 +60,20 @@ func readOne(conn *websocket.Conn, msg *Message) error {
 			return fmt.Errorf("failed to unmarshal message: %w", err)
 		}
 		msg.Message = tMsg
+	case UpdateRegistrationMessageType:
+		var tMsg UpdateRegistrationMessage
+		err = json.Unmarshal(mb, &tMsg)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal message: %w", err)
+		}
+		msg.Message = tMsg
+	case RefreshRegistrationMessageType:
+		var tMsg RefreshRegistrationMessage
+		err = json.Unmarshal(mb, &tMsg)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal message: %w", err)
+		}
+		msg.Message = tMsg
 	case PingMessageType:
 		var tMsg PingMessage
 		err = json.Unmarshal(mb, &tMsg)
@@ -101,6 +115,18 @@ func WriteWithRequestID(conn *websocket.Conn, msg any, requestID string) error {
 			RequestID: requestID,
 			Message:   v,
 		})
+	case UpdateRegistrationMessage:
+		return conn.WriteJSON(Message{
+			Type:      UpdateRegistrationMessageType,
+			RequestID: requestID,
+			Message:   v,
+		})
+	case RefreshRegistrationMessage:
+		return conn.WriteJSON(Message{
+			Type:      RefreshRegistrationMessageType,
+			RequestID: requestID,
+			Message:   v,
+		})
 	case PingMessage:
 		return conn.WriteJSON(Message{
 			Type:      PingMessageType,
@@ -112,6 +112,41 @@ func TestWriteAndReadRegisterMessage(t *testing.T) {
 	}
 }
 
+func TestWriteAndReadUpdateRegistrationMessage(t *testing.T) {
+	mc := newMockConn(t)
+	defer mc.close()
+
+	go func() {
+		_, err := Write(mc.serverConn, UpdateRegistrationMessage{Added: []string{"foo/new"}, Removed: []string{"foo/old"}})
+		if err != nil {
+			t.Errorf("Write error: %v", err)
+		}
+		_, err = Write(mc.serverConn, RefreshRegistrationMessage{})
+		if err != nil {
+			t.Errorf("Write error: %v", err)
+		}
+	}()
+
+	var msg Message
+	if err := Read(mc.client, &msg); err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	got, ok := msg.Message.(UpdateRegistrationMessage)
+	if !ok || msg.Type != UpdateRegistrationMessageType {
+		t.Fatalf("expected UpdateRegistrationMessage, got %s %T", msg.Type, msg.Message)
+	}
+	if len(got.Added) != 1 || got.Added[0] != "foo/new" || len(got.Removed) != 1 || got.Removed[0] != "foo/old" {
+		t.Errorf("UpdateRegistrationMessage mismatch: got %+v", got)
+	}
+
+	if err := Read(mc.client, &msg); err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	if _, ok := msg.Message.(RefreshRegistrationMessage); !ok || msg.Type != RefreshRegistrationMessageType {
+		t.Fatalf("expected RefreshRegistrationMessage, got %s %T", msg.Type, msg.Message)
+	}
+}
+
 func TestWriteUnsupportedType(t *testing.T) {
 	mc := newMockConn(t)
 	defer mc.close()
@@ -0,0 +1,76 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/clems4ever/lgtm/internal/api"
+)
+
+// APIClient calls the JSON API of the lgtm server with a personal API token.
+type APIClient struct {
+	serverURL  string
+	token      string
+	httpClient *http.Client
+}
+
+// NewAPIClient creates an APIClient for the server at serverURL authenticated with the given personal API token.
+// If httpClient is nil, a client with a 30s timeout is used.
+func NewAPIClient(serverURL, token string, httpClient *http.Client) *APIClient {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &APIClient{
+		serverURL:  strings.TrimSuffix(serverURL, "/"),
+		token:      token,
+		httpClient: httpClient,
+	}
+}
+
+// Submit submits a PR for approval and returns the initial status of the request.
+func (c *APIClient) Submit(body api.SubmitBodyRequest) (api.RequestStatus, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return api.RequestStatus{}, err
+	}
+	var status api.RequestStatus
+	err = c.do(http.MethodPost, "/api/v1/requests", bytes.NewReader(payload), &status)
+	return status, err
+}
+
+// GetRequest returns the current status of a request.
+func (c *APIClient) GetRequest(id string) (api.RequestStatus, error) {
+	var status api.RequestStatus
+	err := c.do(http.MethodGet, "/api/v1/requests/"+url.PathEscape(id), nil, &status)
+	return status, err
+}
+
+// do sends a request to the API and decodes the JSON response into v.
+// Responses with a non-2xx status are turned into errors carrying the message sent by the server.
+func (c *APIClient) do(method, path string, body io.Reader, v any) error {
+	req, err := http.NewRequest(method, c.serverURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("server responded with %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,105 @@
+package request
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/clems4ever/lgtm/internal/api"
+	"github.com/clems4ever/lgtm/internal/github"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serverURLFlag         string
+	remoteFlag            string
+	requiredApprovalsFlag int
+	justificationFlag     string
+	pollIntervalFlag      time.Duration
+	noWaitFlag            bool
+	githubAPIURLFlag      string
+)
+
+const (
+	defaultServerURL    = "https://lgtm.clems4ever.com"
+	defaultRemote       = "origin"
+	defaultPollInterval = 2 * time.Second
+)
+
+// BuildCommand creates the Cobra command requesting the approval of a PR from the terminal.
+// The PR is either given as an argument or inferred from the current git branch. The command submits it
+// to the server with a personal API token, then waits for the outcome while printing its progress.
+func BuildCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "request [PR_URL]",
+		Short: "Requests the approval of a PR, by default the one of the current git branch",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			apiToken := os.Getenv("LGTM_TOKEN")
+			if apiToken == "" {
+				fmt.Println("LGTM_TOKEN env var must be provided. Create a personal API token from the home page of the lgtm server.")
+				os.Exit(1)
+			}
+
+			var link github.PRLink
+			var err error
+			if len(args) == 1 {
+				link, err = github.ParsePullRequestURL(args[0])
+				if err != nil {
+					log.Fatalf("invalid pull request URL: %v", err)
+				}
+			} else {
+				githubToken := os.Getenv("LGTM_GITHUB_TOKEN")
+				if githubToken == "" {
+					fmt.Println("LGTM_GITHUB_TOKEN env var must be provided to find the PR of the current branch. " +
+						"Pass the URL of the PR otherwise.")
+					os.Exit(1)
+				}
+				link, err = InferPullRequest(func(host string) *github.Client {
+					apiURL := githubAPIURLFlag
+					if apiURL == "" {
+						apiURL = github.APIURLForHost(host)
+					}
+					return github.NewClient(githubToken, apiURL, nil)
+				}, ".", remoteFlag)
+				if err != nil {
+					log.Fatalf("failed to find the PR of the current branch: %v", err)
+				}
+			}
+
+			client := NewAPIClient(serverURLFlag, apiToken, nil)
+			status, err := client.Submit(api.SubmitBodyRequest{
+				PRLink:            link.String(),
+				RequiredApprovals: requiredApprovalsFlag,
+				Justification:     justificationFlag,
+			})
+			if err != nil {
+				log.Fatalf("failed to submit %s: %v", link, err)
+			}
+			fmt.Printf("Submitted %s (request %s)\n", link, status.ID)
+			if noWaitFlag {
+				return
+			}
+
+			status, err = Follow(client, status, pollIntervalFlag, os.Stdout)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if status.State != api.RequestStateApproved {
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&serverURLFlag, "server-url", defaultServerURL, "url to the lgtm server")
+	cmd.Flags().StringVar(&remoteFlag, "remote", defaultRemote, "git remote whose repository holds the PR of the current branch")
+	cmd.Flags().IntVar(&requiredApprovalsFlag, "required-approvals", 0,
+		"number of distinct approvals to collect (the number configured for the repository if lower)")
+	cmd.Flags().StringVar(&justificationFlag, "justification", "", "reason for the approval, shown to the approvers")
+	cmd.Flags().DurationVar(&pollIntervalFlag, "poll-interval", defaultPollInterval, "interval between two reads of the request status")
+	cmd.Flags().StringVar(&githubAPIURLFlag, "github-api-url", "",
+		"base URL of the GitHub REST API used to find the PR of the current branch (derived from the host of the remote if empty)")
+	cmd.Flags().BoolVar(&noWaitFlag, "no-wait", false, "exit right after submitting the PR instead of waiting for the outcome")
+	return cmd
+}
@@ -0,0 +1,69 @@
+package request
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/clems4ever/lgtm/internal/api"
+)
+
+// Follow polls the status of the request every interval until it is approved or failed.
+// Every change of the status is printed to out, along with the outcome of every attempt.
+// It returns the final status of the request.
+func Follow(client *APIClient, status api.RequestStatus, interval time.Duration, out io.Writer) (api.RequestStatus, error) {
+	var lastLine string
+	printedAttempts := 0
+	for {
+		// The attempts are a new list if the request was routed again from scratch.
+		if len(status.Attempts) < printedAttempts {
+			printedAttempts = 0
+		}
+		for _, attempt := range status.Attempts[printedAttempts:] {
+			outcome := attempt.Error
+			if outcome == "" {
+				outcome = string(attempt.Response)
+			}
+			fmt.Fprintf(out, "   %s: %s\n", attempt.Approver, outcome)
+		}
+		printedAttempts = len(status.Attempts)
+
+		if line := describeStatus(status); line != lastLine {
+			fmt.Fprintln(out, line)
+			lastLine = line
+		}
+		if status.Done() {
+			return status, nil
+		}
+
+		time.Sleep(interval)
+		var err error
+		status, err = client.GetRequest(status.ID)
+		if err != nil {
+			return status, fmt.Errorf("failed to get the status of request %s: %w", status.ID, err)
+		}
+	}
+}
+
+// describeStatus renders the status of a request on one line.
+func describeStatus(status api.RequestStatus) string {
+	progress := fmt.Sprintf("(%d/%d approvals collected)", len(status.Approvers), status.RequiredApprovals)
+	switch status.State {
+	case api.RequestStateQueued:
+		expiry := ""
+		if status.ExpiresAt != nil {
+			expiry = fmt.Sprintf(" until %s", status.ExpiresAt.Local().Format(time.DateTime))
+		}
+		return fmt.Sprintf("⏳ No approver is online, %s is queued%s", status.Link, expiry)
+	case api.RequestStateRouting:
+		if status.CurrentApprover != "" {
+			return fmt.Sprintf("⏳ Waiting for %s to approve %s %s", status.CurrentApprover, status.Link, progress)
+		}
+		return fmt.Sprintf("⏳ Looking for an approver for %s %s", status.Link, progress)
+	case api.RequestStateApproved:
+		return fmt.Sprintf("✔ %s has been approved by %s", status.Link, strings.Join(status.Approvers, ", "))
+	default:
+		return fmt.Sprintf("❌ Failed to approve %s: %s", status.Link, status.Error)
+	}
+}
@@ -0,0 +1,88 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clems4ever/lgtm/internal/api"
+	"github.com/clems4ever/lgtm/internal/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubmitAndFollow(t *testing.T) {
+	link := github.PRLink{Owner: "foo", Repo: "bar", PRNumber: 1}
+	request := api.ApprovalRequest{Link: link, Requester: "alice", RequiredApprovals: 1}
+	statuses := []api.RequestStatus{
+		{ApprovalRequest: request, ID: "r1", State: api.RequestStateRouting, CurrentApprover: "bob"},
+		{ApprovalRequest: request, ID: "r1", State: api.RequestStateRouting, CurrentApprover: "bob"},
+		{ApprovalRequest: request, ID: "r1", State: api.RequestStateApproved, Approvers: []string{"carol"},
+			Attempts: []api.RoutingAttempt{{Approver: "bob", Error: "timeout"}, {Approver: "carol", Response: "success"}}},
+	}
+
+	var submitted api.SubmitBodyRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer lgtm_secret" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/requests":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&submitted))
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(api.RequestStatus{ApprovalRequest: request, ID: "r1", State: api.RequestStateRouting})
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/requests/r1":
+			json.NewEncoder(w).Encode(statuses[0])
+			statuses = statuses[1:]
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	_, err := NewAPIClient(ts.URL, "invalid", nil).Submit(api.SubmitBodyRequest{PRLink: link.String()})
+	require.ErrorContains(t, err, "401")
+
+	client := NewAPIClient(ts.URL+"/", "lgtm_secret", nil)
+	status, err := client.Submit(api.SubmitBodyRequest{PRLink: link.String(), Justification: "hotfix"})
+	require.NoError(t, err)
+	require.Equal(t, "hotfix", submitted.Justification)
+
+	var out bytes.Buffer
+	status, err = Follow(client, status, 0, &out)
+	require.NoError(t, err)
+	require.Equal(t, api.RequestStateApproved, status.State)
+	// Unchanged statuses are only printed once.
+	require.Equal(t, "⏳ Looking for an approver for https://github.com/foo/bar/pull/1 (0/1 approvals collected)\n"+
+		"⏳ Waiting for bob to approve https://github.com/foo/bar/pull/1 (0/1 approvals collected)\n"+
+		"   bob: timeout\n"+
+		"   carol: success\n"+
+		"✔ https://github.com/foo/bar/pull/1 has been approved by carol\n", out.String())
+}
+
+func TestFollow_ShrinkingAttempts(t *testing.T) {
+	link := github.PRLink{Owner: "foo", Repo: "bar", PRNumber: 1}
+	request := api.ApprovalRequest{Link: link, Requester: "alice", RequiredApprovals: 1}
+	statuses := []api.RequestStatus{
+		{ApprovalRequest: request, ID: "r1", State: api.RequestStateRouting},
+		{ApprovalRequest: request, ID: "r1", State: api.RequestStateApproved, Approvers: []string{"carol"},
+			Attempts: []api.RoutingAttempt{{Approver: "carol", Response: "success"}}},
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(statuses[0])
+		statuses = statuses[1:]
+	}))
+	defer ts.Close()
+
+	var out bytes.Buffer
+	status, err := Follow(NewAPIClient(ts.URL, "lgtm_secret", nil), api.RequestStatus{
+		ApprovalRequest: request, ID: "r1", State: api.RequestStateRouting,
+		Attempts: []api.RoutingAttempt{{Approver: "bob", Error: "timeout"}},
+	}, 0, &out)
+	require.NoError(t, err)
+	require.Equal(t, api.RequestStateApproved, status.State)
+	require.Contains(t, out.String(), "   bob: timeout\n")
+	require.Contains(t, out.String(), "   carol: success\n")
+}
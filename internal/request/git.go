@@ -0,0 +1,84 @@
+package request
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/clems4ever/lgtm/internal/github"
+)
+
+// InferPullRequest finds the open PR of the branch checked out in the git repository at dir.
+// The repository of the PR is read from the URL of the given remote, the PR is looked up with the GitHub client
+// returned by newGithubClient for the host of the remote, which can be a GitHub Enterprise Server.
+// The head of the PR is the upstream branch of the checked out branch, which can be pushed to a fork, or the
+// checked out branch itself in the repository of the remote if it has no upstream.
+func InferPullRequest(newGithubClient func(host string) *github.Client, dir, remote string) (github.PRLink, error) {
+	remoteURL, err := git(dir, "remote", "get-url", remote)
+	if err != nil {
+		return github.PRLink{}, fmt.Errorf("failed to read the URL of remote %s: %w", remote, err)
+	}
+	host, owner, repo, err := github.ParseRemoteURL(remoteURL)
+	if err != nil {
+		return github.PRLink{}, err
+	}
+
+	// symbolic-ref fails on a detached HEAD, which cannot be the head of a PR anyway
+	branch, err := git(dir, "symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return github.PRLink{}, fmt.Errorf("failed to read the current branch: %w", err)
+	}
+	headOwner, headBranch, err := upstreamBranch(dir, branch, host)
+	if err != nil {
+		return github.PRLink{}, err
+	}
+	if headOwner == "" {
+		headOwner, headBranch = owner, branch
+	}
+	link, err := newGithubClient(host).FindOpenPullRequest(owner, repo, headOwner, headBranch)
+	if err != nil {
+		return github.PRLink{}, err
+	}
+	link.Host = host
+	return link, nil
+}
+
+// upstreamBranch returns the owner of the repository and the name of the branch tracked by the local branch.
+// Empty values are returned if the branch does not track a branch of a remote repository.
+func upstreamBranch(dir, branch, host string) (string, string, error) {
+	// git config exits with an error when the key is not set
+	trackedRemote, err := git(dir, "config", "branch."+branch+".remote")
+	if err != nil || trackedRemote == "." {
+		return "", "", nil
+	}
+	mergeRef, err := git(dir, "config", "branch."+branch+".merge")
+	if err != nil {
+		return "", "", nil
+	}
+	remoteURL, err := git(dir, "remote", "get-url", trackedRemote)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read the URL of remote %s: %w", trackedRemote, err)
+	}
+	upstreamHost, upstreamOwner, _, err := github.ParseRemoteURL(remoteURL)
+	if err != nil {
+		return "", "", err
+	}
+	if upstreamHost != host {
+		return "", "", fmt.Errorf("branch %s tracks remote %s on %s, not on %s", branch, trackedRemote, upstreamHost, host)
+	}
+	return upstreamOwner, strings.TrimPrefix(mergeRef, "refs/heads/"), nil
+}
+
+// git runs a git command in dir and returns its trimmed output.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
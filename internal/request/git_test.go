@@ -0,0 +1,76 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"testing"
+
+	"github.com/clems4ever/lgtm/internal/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInferPullRequest(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "--initial-branch", "feature"},
+		{"remote", "add", "origin", "git@github.com:foo/bar.git"},
+		{"remote", "add", "enterprise", "https://ghe.example.com/foo/bar.git"},
+	} {
+		_, err := git(dir, args...)
+		require.NoError(t, err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/foo/bar/pulls" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.URL.Query().Get("head") {
+		case "foo:feature":
+			w.Write([]byte(`[{"number": 42}]`))
+		case "alice:fix":
+			w.Write([]byte(`[{"number": 43}]`))
+		default:
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer ts.Close()
+
+	var hosts []string
+	newGithubClient := func(host string) *github.Client {
+		hosts = append(hosts, host)
+		return github.NewClient("dummy", ts.URL, ts.Client())
+	}
+
+	link, err := InferPullRequest(newGithubClient, dir, "origin")
+	require.NoError(t, err)
+	require.Equal(t, github.PRLink{Provider: "github", Host: "github.com", Owner: "foo", Repo: "bar", PRNumber: 42}, link)
+
+	link, err = InferPullRequest(newGithubClient, dir, "enterprise")
+	require.NoError(t, err)
+	require.Equal(t, github.PRLink{Provider: "github", Host: "ghe.example.com", Owner: "foo", Repo: "bar", PRNumber: 42}, link)
+	require.Equal(t, []string{"github.com", "ghe.example.com"}, hosts)
+
+	_, err = InferPullRequest(newGithubClient, dir, "upstream")
+	require.Error(t, err)
+
+	// The branch is pushed to a fork under another name.
+	for _, args := range [][]string{
+		{"remote", "add", "fork", "git@github.com:alice/bar.git"},
+		{"config", "branch.feature.remote", "fork"},
+		{"config", "branch.feature.merge", "refs/heads/fix"},
+	} {
+		_, err := git(dir, args...)
+		require.NoError(t, err)
+	}
+	link, err = InferPullRequest(newGithubClient, dir, "origin")
+	require.NoError(t, err)
+	require.Equal(t, github.PRLink{Provider: "github", Host: "github.com", Owner: "foo", Repo: "bar", PRNumber: 43}, link)
+
+	_, err = InferPullRequest(newGithubClient, dir, "enterprise")
+	require.Error(t, err)
+}
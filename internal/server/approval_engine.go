@@ -4,14 +4,38 @@ import (
 	"sync"
 )
 
+// ApproverEventType is the type of an ApproverEvent.
+type ApproverEventType string
+
+const (
+	// ApproverJoined is emitted when the first client of an approver connects.
+	ApproverJoined ApproverEventType = "join"
+	// ApproverLeft is emitted when the last client of an approver disconnects.
+	ApproverLeft ApproverEventType = "leave"
+)
+
+// ApproverEvent notifies that an approver became available or unavailable.
+type ApproverEvent struct {
+	Type ApproverEventType `json:"type"`
+	User string            `json:"user"`
+	// Count is the number of available approvers after the event.
+	Count int `json:"count"`
+}
+
+// approverEventsBufferSize is the number of events buffered per subscriber. The subscribers that do not keep up
+// are dropped and their channel closed rather than missing events.
+const approverEventsBufferSize = 16
+
 type ApprovalEngine struct {
 	mu                 sync.RWMutex
 	availableApprovers map[string]int
+	subscribers        map[chan ApproverEvent]struct{}
 }
 
 func NewApprovalEngine() *ApprovalEngine {
 	return &ApprovalEngine{
 		availableApprovers: make(map[string]int),
+		subscribers:        make(map[chan ApproverEvent]struct{}),
 	}
 }
 
@@ -31,6 +55,7 @@ func (ae *ApprovalEngine) AddApprover(user string) {
 	count, ok := ae.availableApprovers[user]
 	if !ok {
 		ae.availableApprovers[user] = 1
+		ae.publishLocked(ApproverEvent{Type: ApproverJoined, User: user, Count: len(ae.availableApprovers)})
 	} else {
 		ae.availableApprovers[user] = count + 1
 	}
@@ -44,7 +69,36 @@ func (ae *ApprovalEngine) RemoveApprover(user string) {
 		return
 	} else if count == 1 {
 		delete(ae.availableApprovers, user)
+		ae.publishLocked(ApproverEvent{Type: ApproverLeft, User: user, Count: len(ae.availableApprovers)})
 	} else {
 		ae.availableApprovers[user] = count - 1
 	}
 }
+
+// Subscribe returns a channel receiving the approver join and leave events, and a function to unsubscribe.
+// The channel is closed if the subscriber does not keep up with the events.
+func (ae *ApprovalEngine) Subscribe() (<-chan ApproverEvent, func()) {
+	ch := make(chan ApproverEvent, approverEventsBufferSize)
+	ae.mu.Lock()
+	ae.subscribers[ch] = struct{}{}
+	ae.mu.Unlock()
+
+	return ch, func() {
+		ae.mu.Lock()
+		delete(ae.subscribers, ch)
+		ae.mu.Unlock()
+	}
+}
+
+// publishLocked sends the event to every subscriber without blocking. The subscribers whose buffer is full are
+// dropped. The caller must hold the lock.
+func (ae *ApprovalEngine) publishLocked(event ApproverEvent) {
+	for ch := range ae.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(ae.subscribers, ch)
+			close(ch)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApprovalEngine_Subscribe(t *testing.T) {
+	ae := NewApprovalEngine()
+	events, unsubscribe := ae.Subscribe()
+
+	// Only the first client of an approver joining and the last one leaving are notified.
+	ae.AddApprover("alice")
+	ae.AddApprover("alice")
+	ae.AddApprover("bob")
+	ae.RemoveApprover("alice")
+	ae.RemoveApprover("alice")
+
+	require.Equal(t, ApproverEvent{Type: ApproverJoined, User: "alice", Count: 1}, <-events)
+	require.Equal(t, ApproverEvent{Type: ApproverJoined, User: "bob", Count: 2}, <-events)
+	require.Equal(t, ApproverEvent{Type: ApproverLeft, User: "alice", Count: 1}, <-events)
+	require.Empty(t, events)
+
+	unsubscribe()
+	ae.RemoveApprover("bob")
+	require.Empty(t, events)
+}
+
+func TestApprovalEngine_SlowSubscriberIsDropped(t *testing.T) {
+	ae := NewApprovalEngine()
+	events, unsubscribe := ae.Subscribe()
+	defer unsubscribe()
+
+	for range approverEventsBufferSize + 1 {
+		ae.AddApprover("alice")
+		ae.RemoveApprover("alice")
+	}
+
+	// The channel is closed once the buffer is full rather than missing events.
+	for range approverEventsBufferSize {
+		_, ok := <-events
+		require.True(t, ok)
+	}
+	_, ok := <-events
+	require.False(t, ok)
+}
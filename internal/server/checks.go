@@ -0,0 +1,70 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/clems4ever/lgtm/internal/api"
+	"github.com/clems4ever/lgtm/internal/forge"
+	"github.com/clems4ever/lgtm/internal/github"
+	"github.com/clems4ever/lgtm/internal/protocol"
+)
+
+var (
+	// ErrChecksNotGreen is returned when the checks of the PR head commit failed or did not complete in time.
+	ErrChecksNotGreen = fmt.Errorf("checks are not green")
+)
+
+const defaultChecksPollInterval = 15 * time.Second
+
+// ChecksPolicy requires the checks of a PR head commit to be green before the PR is routed to approvers.
+type ChecksPolicy struct {
+	// RequiredChecks restricts the checks that must pass to the ones with those names.
+	// If empty, every check of the head commit must pass.
+	RequiredChecks []string
+	// WaitTimeout is the time to wait for pending checks to complete before failing the request.
+	WaitTimeout time.Duration
+	// PollInterval is the interval between two polls of the checks while waiting.
+	PollInterval time.Duration
+}
+
+// ensureChecksGreen returns an error wrapping ErrChecksNotGreen if the checks policy is enabled and
+// the checks of the PR head commit are failing or still pending after the wait timeout. The head commit is the
+// one the request is pinned to, the current head of the PR if it is not pinned. The change requests of forges
+// other than GitHub are not checked.
+func (s *Server) ensureChecksGreen(req api.ApprovalRequest) error {
+	if s.checks == nil {
+		return nil
+	}
+	link := req.Link
+	if link.ProviderName() != forge.ProviderGitHub {
+		log.Printf("checks are not supported on %s, %s is routed without checking them", link.ProviderName(), link)
+		return nil
+	}
+	headSHA := req.HeadSHA
+	if headSHA == "" {
+		pr, err := s.githubClient.GetPullRequest(link)
+		if err != nil {
+			return fmt.Errorf("failed to get pull request: %w", err)
+		}
+		headSHA = pr.Head.SHA
+	}
+	pollInterval := s.checks.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultChecksPollInterval
+	}
+	summary, err := s.githubClient.WaitForChecks(s.ctx, link.Owner, link.Repo, headSHA,
+		s.checks.RequiredChecks, s.checks.WaitTimeout, pollInterval)
+	if err != nil {
+		return fmt.Errorf("failed to get checks: %w", err)
+	}
+	switch summary.State {
+	case github.ChecksFailure:
+		return fmt.Errorf("%w: %s: %s", ErrChecksNotGreen, protocol.RejectionChecksFailing, strings.Join(summary.Failing, ", "))
+	case github.ChecksPending:
+		return fmt.Errorf("%w: %s: %s", ErrChecksNotGreen, protocol.RejectionChecksPending, strings.Join(summary.Pending, ", "))
+	}
+	return nil
+}
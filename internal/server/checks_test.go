@@ -0,0 +1,45 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/clems4ever/lgtm/internal/api"
+	"github.com/clems4ever/lgtm/internal/github"
+	"github.com/clems4ever/lgtm/internal/protocol"
+	"github.com/clems4ever/lgtm/internal/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestApproval_RequiresGreenChecks(t *testing.T) {
+	githubSrv := test.NewGithubMockServer(t, "")
+	t.Cleanup(githubSrv.Close)
+	githubSrv.AddUser("lgtm-bot", "server-token", nil)
+	githubSrv.SetPRHead("foo/bar", 1, "abc")
+	githubSrv.AddCheckRun("foo/bar", "abc", "build", "completed", "success")
+	githubSrv.AddCheckRun("foo/bar", "abc", "test", "completed", "failure")
+
+	s, wsURL := newTestServer(t, nil, DefaultRetryPolicy())
+	s.githubClient = github.NewClient("server-token", githubSrv.URL(), nil)
+	s.checks = &ChecksPolicy{RequiredChecks: []string{"build", "test"}}
+
+	connectFakeApprover(t, s, wsURL, "alice", []string{"foo/bar"}, respondWith(protocol.ApproveResponseSuccess))
+
+	result, err := s.RequestApproval(api.ApprovalRequest{Link: testLink})
+	require.ErrorIs(t, err, ErrChecksNotGreen)
+	require.ErrorContains(t, err, "checks_failing: test")
+	require.Empty(t, result.Attempts)
+
+	// Only the required checks must pass.
+	s.checks.RequiredChecks = []string{"build"}
+	result, err = s.RequestApproval(api.ApprovalRequest{Link: testLink})
+	require.NoError(t, err)
+	require.Equal(t, []string{"alice"}, result.Approvers)
+
+	// The checks of the commit the request is pinned to apply, not the ones of a newer head.
+	githubSrv.SetPRHead("foo/bar", 1, "def")
+	githubSrv.AddCheckRun("foo/bar", "def", "build", "completed", "success")
+	s.checks.RequiredChecks = nil
+	result, err = s.RequestApproval(api.ApprovalRequest{Link: testLink, HeadSHA: "abc"})
+	require.ErrorIs(t, err, ErrChecksNotGreen)
+	require.Empty(t, result.Attempts)
+}
@@ -2,13 +2,22 @@ package server
 
 import (
 	"embed"
+	"fmt"
 	"io/fs"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/clems4ever/lgtm/internal/common"
+	"github.com/clems4ever/lgtm/internal/forge"
+	"github.com/clems4ever/lgtm/internal/gitea"
+	"github.com/clems4ever/lgtm/internal/github"
+	"github.com/clems4ever/lgtm/internal/gitlab"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/sessions"
 	"github.com/spf13/cobra"
@@ -20,18 +29,51 @@ import (
 var staticAssets embed.FS
 
 var (
-	addrFlag          string // HTTP listen address
-	baseURLFlag       string // Base URL for OAuth2 redirect
-	authServerURLFlag string
-	pingIntervalFlag  time.Duration
+	addrFlag              string // HTTP listen address
+	baseURLFlag           string // Base URL for OAuth2 redirect
+	authServerURLFlag     string
+	pingIntervalFlag      time.Duration
+	routingStrategyFlag   string
+	routingWeightsFlag    string
+	rpcTimeoutFlag        time.Duration
+	routingDeadlineFlag   time.Duration
+	maxAttemptsFlag       int
+	queueTTLFlag          time.Duration
+	queueFileFlag         string
+	requiredApprovalsFlag string
+	policyFileFlag        string
+	codeownersModeFlag    string
+	requireGreenChecks    bool
+	requiredChecksFlag    []string
+	checksWaitTimeoutFlag time.Duration
+	submitterCheckFlag    string
+	forgeAccountsFlag     string
+	allowedOrgsFlag       []string
+	allowedTeamsFlag      []string
+	apiTokensFileFlag     string
+	apiTokenTTLFlag       time.Duration
+	webhookLabelFlag      string
+	webhookCommandFlag    string
+
+	githubAppIDFlag             int64
+	githubAppInstallationIDFlag int64
+	githubAppPermissionsFlag    string
+
+	githubHostFlag   string
+	githubAPIURLFlag string
+
+	gitlabHostFlag   string
+	gitlabAPIURLFlag string
+
+	giteaHostFlag   string
+	giteaAPIURLFlag string
 )
 
 const (
-	defaultAddr          = ":8080"
-	defaultAuthServerURL = "https://github.com/login/oauth"
-	defaultBaseURL       = "https://lgtm.clems4evever.com"
-	defaultGithubAPIURL  = "https://api.github.com"
-	defaultPingInterval  = 10 * time.Second
+	defaultAddr         = ":8080"
+	defaultBaseURL      = "https://lgtm.clems4evever.com"
+	defaultPingInterval = 10 * time.Second
+	defaultQueueTTL     = 24 * time.Hour
 )
 
 // BuildCommand creates the Cobra command for running the server.
@@ -56,16 +98,53 @@ func BuildCommand() *cobra.Command {
 				log.Fatal("LGTM_SESSION_STORE_ENCRYPTION_KEY must be set")
 			}
 
+			allowedTeams, err := ParseAllowedTeams(allowedTeamsFlag)
+			if err != nil {
+				log.Fatal(err)
+			}
+			loginRestriction := &LoginRestriction{AllowedOrgs: allowedOrgsFlag, AllowedTeams: allowedTeams}
+			scopes := []string{"read:user"}
+			if loginRestriction.Enabled() {
+				// Memberships can only be checked with the read:org scope
+				scopes = append(scopes, "read:org")
+			}
+
+			weights, err := ParseRoutingWeights(routingWeightsFlag)
+			if err != nil {
+				log.Fatal(err)
+			}
+			approverRouter, err := NewRouter(RoutingStrategy(routingStrategyFlag), weights, nil, nil)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			// The OAuth server and the API are derived from the GitHub host unless set explicitly
+			githubHost := strings.ToLower(githubHostFlag)
+			authServerURL := authServerURLFlag
+			if authServerURL == "" {
+				authServerURL = github.OAuthURLForHost(githubHost)
+			}
+			githubAPIURL := githubAPIURLFlag
+			if githubAPIURL == "" {
+				githubAPIURL = github.APIURLForHost(githubHost)
+			}
+
 			// Initialize the main server struct with OAuth2 config
 			var server = NewServer(
 				common.OauthConfigBuilder(common.OAuthConfigBuilderArgs{
-					AuthServerBaseURL: authServerURLFlag,
+					AuthServerBaseURL: authServerURL,
 					ClientID:          clientID,
 					ClientSecret:      clientSecret,
-					Scopes:            []string{"read:user"},
+					Scopes:            scopes,
 					RedirectURL:       baseURLFlag + "/callback",
-				}), pingIntervalFlag)
+				}), pingIntervalFlag, approverRouter, RetryPolicy{
+					RPCTimeout:  rpcTimeoutFlag,
+					Deadline:    routingDeadlineFlag,
+					MaxAttempts: maxAttemptsFlag,
+				})
 			defer server.Close()
+			server.githubHost = githubHost
+			server.githubAPIURL = githubAPIURL
 
 			// Initialize the session store for secure cookie-based sessions
 			cookieStore := sessions.NewCookieStore([]byte(sessionStoreEncryptionKey))
@@ -74,6 +153,111 @@ func BuildCommand() *cobra.Command {
 			cookieStore.Options.HttpOnly = true
 			cookieStore.Options.Secure = true // Ensure cookies are sent over HTTPS
 			server.sessionStore = cookieStore
+			server.loginRestriction = loginRestriction
+
+			server.requiredApprovalsByRepo, err = ParseRequiredApprovals(requiredApprovalsFlag, githubHost)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			// Load the policy file and reload it on SIGHUP
+			if policyFileFlag != "" {
+				policy, err := NewPolicyStore(policyFileFlag)
+				if err != nil {
+					log.Fatalf("failed to load policy: %v", err)
+				}
+				server.policy = policy
+
+				sighupC := make(chan os.Signal, 1)
+				signal.Notify(sighupC, syscall.SIGHUP)
+				go func() {
+					for range sighupC {
+						if err := policy.Reload(); err != nil {
+							log.Printf("failed to reload policy, keeping the previous one: %v", err)
+						}
+					}
+				}()
+			}
+
+			server.submitterCheck, err = ParseSubmitterCheckMode(submitterCheckFlag)
+			if err != nil {
+				log.Fatal(err)
+			}
+			server.forgeAccounts, err = ParseForgeAccounts(forgeAccountsFlag)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			// Configure the CODEOWNERS-aware selection of approvers
+			server.codeownersMode, err = ParseCodeownersMode(codeownersModeFlag)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			// Only route PRs whose checks are green
+			if requireGreenChecks {
+				server.checks = &ChecksPolicy{
+					RequiredChecks: requiredChecksFlag,
+					WaitTimeout:    checksWaitTimeoutFlag,
+				}
+			}
+
+			// Trigger approval requests from GitHub webhooks
+			if webhookSecret := os.Getenv("LGTM_WEBHOOK_SECRET"); webhookSecret != "" {
+				server.webhook = &WebhookConfig{
+					Secret:  webhookSecret,
+					Label:   webhookLabelFlag,
+					Command: webhookCommandFlag,
+				}
+			}
+
+			// The policy uses the GitHub client to verify the approvals, if a token is available
+			needsGithubClient := server.codeownersMode != CodeownersModeOff || server.checks != nil || server.webhook != nil
+			if needsGithubClient || server.policy != nil {
+				// Prefer the short-lived installation tokens of a GitHub App to a personal access token
+				if githubAppIDFlag != 0 {
+					server.githubClient, err = newGithubAppClient(githubAppIDFlag, githubAppInstallationIDFlag,
+						os.Getenv("LGTM_GITHUB_APP_PRIVATE_KEY"), githubAppPermissionsFlag, githubAPIURL)
+					if err != nil {
+						log.Fatal(err)
+					}
+				} else if serverGithubToken := os.Getenv("LGTM_SERVER_GITHUB_TOKEN"); serverGithubToken != "" {
+					server.githubClient = github.NewClient(serverGithubToken, githubAPIURL, nil)
+				} else if needsGithubClient {
+					log.Fatal("LGTM_SERVER_GITHUB_TOKEN or --github-app-id must be set when codeowners mode, green checks or webhooks are enabled")
+				} else {
+					log.Println("LGTM_SERVER_GITHUB_TOKEN is not set, the policy trusts the logins declared by the clients")
+				}
+			}
+
+			// Accept the change requests of other forges, fetched with the tokens of the server since users log in with GitHub
+			if serverGitlabToken := os.Getenv("LGTM_SERVER_GITLAB_TOKEN"); serverGitlabToken != "" {
+				server.forges = append(server.forges, gitlab.NewClient(serverGitlabToken, gitlabHostFlag, gitlabAPIURLFlag, nil))
+			}
+			if serverGiteaToken := os.Getenv("LGTM_SERVER_GITEA_TOKEN"); serverGiteaToken != "" {
+				if giteaHostFlag == "" {
+					log.Fatal("--gitea-host must be set with LGTM_SERVER_GITEA_TOKEN")
+				}
+				server.forges = append(server.forges, gitea.NewClient(serverGiteaToken, giteaHostFlag, giteaAPIURLFlag, nil))
+			}
+			if len(server.forges) > 0 && (server.checks != nil || server.codeownersMode != CodeownersModeOff) {
+				log.Println("green checks and CODEOWNERS only apply to GitHub, the change requests of other forges are routed without them")
+			}
+
+			// Initialize the queue of requests waiting for an approver to come online
+			if queueTTLFlag > 0 {
+				queue, err := NewPendingQueue(queueFileFlag, queueTTLFlag, nil)
+				if err != nil {
+					log.Fatalf("failed to initialize queue: %v", err)
+				}
+				server.queue = queue
+			}
+
+			// Load the personal API tokens
+			server.tokens, err = NewTokenStore(apiTokensFileFlag, apiTokenTTLFlag, nil)
+			if err != nil {
+				log.Fatalf("failed to load API tokens: %v", err)
+			}
 
 			// Create a new router for all HTTP routes
 			router := mux.NewRouter()
@@ -89,8 +273,23 @@ func BuildCommand() *cobra.Command {
 			// Define application routes with appropriate middleware
 			router.HandleFunc("/", server.middlewareWebAuthMiddleware(server.handlerHome)).Methods(http.MethodGet)
 			router.HandleFunc("/submit", server.middlewareWebAuthMiddleware(server.handlerSubmit)).Methods(http.MethodPost)
+			router.HandleFunc("/events", server.middlewareWebAuthMiddleware(server.handlerEvents)).Methods(http.MethodGet)
+			router.HandleFunc("/requests/{id}", server.middlewareWebAuthMiddleware(server.handlerGetRequest)).Methods(http.MethodGet)
+			router.HandleFunc("/queue/{id}", server.middlewareWebAuthMiddleware(server.handlerCancelQueuedRequest)).Methods(http.MethodDelete)
+			router.HandleFunc("/tokens", server.middlewareWebAuthMiddleware(server.handlerCreateToken)).Methods(http.MethodPost)
+			router.HandleFunc("/tokens/{id}", server.middlewareWebAuthMiddleware(server.handlerRevokeToken)).Methods(http.MethodDelete)
 			router.HandleFunc("/callback", server.handlerCallback).Methods(http.MethodGet)
 			router.HandleFunc("/ws", apiAuthMiddleware(apiAuthToken, server.wsHandler)).Methods(http.MethodGet)
+			if server.webhook != nil {
+				router.HandleFunc("/webhook", server.handlerWebhook).Methods(http.MethodPost)
+			}
+
+			// Versioned JSON API authenticated with personal API tokens
+			api := router.PathPrefix("/api/v1").Subrouter()
+			api.HandleFunc("/requests", server.middlewareAPITokenAuth(server.handlerSubmit)).Methods(http.MethodPost)
+			api.HandleFunc("/requests/{id}", server.middlewareAPITokenAuth(server.handlerGetRequest)).Methods(http.MethodGet)
+			api.HandleFunc("/repos/{owner}/{repo}/approvers", server.middlewareAPITokenAuth(server.handlerRepoApprovers)).Methods(http.MethodGet)
+			api.HandleFunc("/registrations/refresh", server.middlewareAPITokenAuth(server.handlerRefreshRegistrations)).Methods(http.MethodPost)
 
 			// Custom 404 handler for undefined paths
 			router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -105,7 +304,136 @@ func BuildCommand() *cobra.Command {
 	// Define command-line flags for server configuration
 	cmd.Flags().StringVar(&addrFlag, "addr", defaultAddr, "addr to listen on")
 	cmd.Flags().StringVar(&baseURLFlag, "base-url", defaultBaseURL, "base URL of the service being served (for oauth2 redirect)")
-	cmd.Flags().StringVar(&authServerURLFlag, "auth-server-url", defaultAuthServerURL, "url to the GitHub OAuth server")
+	cmd.Flags().StringVar(&authServerURLFlag, "auth-server-url", "",
+		"url to the GitHub OAuth server (derived from --github-host if empty)")
 	cmd.Flags().DurationVar(&pingIntervalFlag, "ping-interval", defaultPingInterval, "interval for websocket ping messages")
+	cmd.Flags().StringVar(&githubHostFlag, "github-host", github.DefaultHost,
+		"web host of the GitHub instance the PRs are hosted on, e.g. the host of a GitHub Enterprise Server")
+	cmd.Flags().StringVar(&githubAPIURLFlag, "github-api-url", "",
+		"base URL of the GitHub REST API (derived from --github-host if empty, https://{host}/api/v3 for GitHub Enterprise Server)")
+	cmd.Flags().StringVar(&gitlabHostFlag, "gitlab-host", gitlab.DefaultHost,
+		"web host of the GitLab instance the merge requests are hosted on (enabled by LGTM_SERVER_GITLAB_TOKEN)")
+	cmd.Flags().StringVar(&gitlabAPIURLFlag, "gitlab-api-url", "",
+		"base URL of the GitLab REST API (derived from --gitlab-host if empty)")
+	cmd.Flags().StringVar(&giteaHostFlag, "gitea-host", "",
+		"web host of the Gitea or Forgejo instance the PRs are hosted on (enabled by LGTM_SERVER_GITEA_TOKEN)")
+	cmd.Flags().StringVar(&giteaAPIURLFlag, "gitea-api-url", "",
+		"base URL of the Gitea REST API (derived from --gitea-host if empty)")
+	cmd.Flags().StringVar(&routingStrategyFlag, "routing-strategy", string(RoutingStrategyRoundRobin),
+		"strategy used to select an approver (round-robin, least-recently-used, least-loaded, weighted)")
+	cmd.Flags().StringVar(&routingWeightsFlag, "routing-weights", "",
+		"comma-separated user=weight pairs used by the weighted routing strategy (e.g. alice=3,bob=1)")
+	cmd.Flags().DurationVar(&rpcTimeoutFlag, "rpc-timeout", defaultRPCTimeout, "maximum time to wait for one approver to answer")
+	cmd.Flags().DurationVar(&routingDeadlineFlag, "routing-deadline", defaultRoutingDeadline,
+		"maximum total time spent trying approvers for one request (0 for no deadline)")
+	cmd.Flags().IntVar(&maxAttemptsFlag, "max-routing-attempts", 0, "maximum number of approvers tried for one request (0 for no limit)")
+	cmd.Flags().DurationVar(&queueTTLFlag, "queue-ttl", defaultQueueTTL,
+		"how long a request is queued when no approver is online for its repository (0 disables queueing)")
+	cmd.Flags().StringVar(&queueFileFlag, "queue-file", "", "path to the file persisting the queued requests (in memory if empty)")
+	cmd.Flags().StringVar(&requiredApprovalsFlag, "required-approvals", "",
+		"comma-separated repo=count pairs setting the minimum number of distinct approvals per repository (e.g. foo/bar=2,gitlab:gitlab.com/group/project=3)")
+	cmd.Flags().StringVar(&policyFileFlag, "policy-file", "",
+		"path to a YAML or JSON file controlling who may approve and request approvals per repository (reloaded on SIGHUP)")
+	cmd.Flags().StringVar(&codeownersModeFlag, "codeowners", string(CodeownersModeOff),
+		"how CODEOWNERS files are used to select approvers (off, prefer, require)")
+	cmd.Flags().BoolVar(&requireGreenChecks, "require-green-checks", false,
+		"only route PRs whose head commit checks are green (requires LGTM_SERVER_GITHUB_TOKEN)")
+	cmd.Flags().StringSliceVar(&requiredChecksFlag, "required-checks", nil,
+		"names of the checks that must pass when --require-green-checks is set (all checks if empty)")
+	cmd.Flags().DurationVar(&checksWaitTimeoutFlag, "checks-wait-timeout", 0,
+		"how long to wait for pending checks to complete before failing the request")
+	cmd.Flags().StringVar(&submitterCheckFlag, "submitter-check", string(SubmitterCheckCollaborator),
+		"relationship the submitter must have with a PR to request its approval (off, author, collaborator)")
+	cmd.Flags().StringVar(&forgeAccountsFlag, "forge-accounts", "",
+		"comma-separated login=host/username pairs mapping GitHub logins to the accounts checked by --submitter-check on the other forges (e.g. alice=gitlab.com/alice-gl)")
+	cmd.Flags().StringSliceVar(&allowedOrgsFlag, "allowed-orgs", nil,
+		"GitHub organizations whose members can log in to the web UI (anyone if neither orgs nor teams are set)")
+	cmd.Flags().StringSliceVar(&allowedTeamsFlag, "allowed-teams", nil,
+		"GitHub teams, as org/team-slug, whose members can log in to the web UI")
+	cmd.Flags().StringVar(&webhookLabelFlag, "webhook-label", defaultWebhookLabel,
+		"label triggering an approval request when added to a PR (requires LGTM_WEBHOOK_SECRET, disabled if empty)")
+	cmd.Flags().StringVar(&webhookCommandFlag, "webhook-command", defaultWebhookCommand,
+		"comment triggering an approval request when posted on a PR (requires LGTM_WEBHOOK_SECRET, disabled if empty)")
+	cmd.Flags().Int64Var(&githubAppIDFlag, "github-app-id", 0,
+		"ID of the GitHub App the server authenticates as instead of LGTM_SERVER_GITHUB_TOKEN (requires LGTM_GITHUB_APP_PRIVATE_KEY)")
+	cmd.Flags().Int64Var(&githubAppInstallationIDFlag, "github-app-installation-id", 0,
+		"ID of the installation of the GitHub App whose tokens the server uses")
+	cmd.Flags().StringVar(&githubAppPermissionsFlag, "github-app-permissions", "",
+		"comma-separated permission=access pairs restricting the installation tokens (e.g. pull_requests=write,checks=read)")
+	cmd.Flags().StringVar(&apiTokensFileFlag, "api-tokens-file", "",
+		"path to the file persisting the personal API tokens (in memory if empty)")
+	cmd.Flags().DurationVar(&apiTokenTTLFlag, "api-token-ttl", defaultAPITokenTTL,
+		"how long the personal API tokens are valid after being issued (0 for no expiry)")
 	return cmd
 }
+
+// ParseRequiredApprovals parses a comma-separated list of repo=count pairs (e.g. "foo/bar=2"). Repositories are
+// given as "owner/repo" on the GitHub instance with the given host, or by their ID on any forge
+// (e.g. "gitlab:gitlab.com/group/project=2"). The counts are keyed by repository ID, see forge.RepoID.
+func ParseRequiredApprovals(s, githubHost string) (map[string]int, error) {
+	counts, err := parseIntPairs(s, 1)
+	if err != nil {
+		return nil, fmt.Errorf("invalid required approvals: %w", err)
+	}
+	byRepo := make(map[string]int, len(counts))
+	for repo, n := range counts {
+		if !strings.Contains(repo, ":") {
+			repo = forge.RepoID(forge.ProviderGitHub, githubHost, repo)
+		}
+		byRepo[strings.ToLower(repo)] = n
+	}
+	return byRepo, nil
+}
+
+// parseIntPairs parses a comma-separated list of key=value pairs where values are integers
+// greater than or equal to minValue.
+func parseIntPairs(s string, minValue int) (map[string]int, error) {
+	pairs := make(map[string]int)
+	if strings.TrimSpace(s) == "" {
+		return pairs, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid pair %q", pair)
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < minValue {
+			return nil, fmt.Errorf("invalid value for %s: %q", key, value)
+		}
+		pairs[key] = n
+	}
+	return pairs, nil
+}
+
+// newGithubAppClient creates a GitHub client authenticated with the installation tokens of a GitHub App.
+// privateKey is the PEM-encoded private key of the app and permissions a comma-separated list of
+// permission=access pairs restricting the tokens, the permissions of the installation apply if empty.
+// apiURL is the base URL of the REST API of the GitHub instance the app is registered on.
+func newGithubAppClient(appID, installationID int64, privateKey, permissions, apiURL string) (*github.Client, error) {
+	if installationID == 0 {
+		return nil, fmt.Errorf("--github-app-installation-id must be set with --github-app-id")
+	}
+	if privateKey == "" {
+		return nil, fmt.Errorf("LGTM_GITHUB_APP_PRIVATE_KEY must be set with --github-app-id")
+	}
+	key, err := github.ParsePrivateKey([]byte(privateKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub App private key: %w", err)
+	}
+
+	opts := github.InstallationTokenOptions{}
+	if strings.TrimSpace(permissions) != "" {
+		opts.Permissions = make(map[string]string)
+		for _, pair := range strings.Split(permissions, ",") {
+			name, access, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok || name == "" || access == "" {
+				return nil, fmt.Errorf("invalid GitHub App permission %q", pair)
+			}
+			opts.Permissions[name] = access
+		}
+	}
+
+	app := github.NewApp(appID, key, apiURL, nil)
+	return github.NewClientWithTokenSource(app.InstallationTokenSource(installationID, opts), apiURL, nil), nil
+}
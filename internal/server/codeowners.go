@@ -0,0 +1,177 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/clems4ever/lgtm/internal/forge"
+	"github.com/clems4ever/lgtm/internal/github"
+)
+
+// CodeownersMode controls how the CODEOWNERS file of a repository is used to select approvers.
+type CodeownersMode string
+
+const (
+	// CodeownersModeOff ignores CODEOWNERS files.
+	CodeownersModeOff CodeownersMode = "off"
+	// CodeownersModePrefer tries the approvers owning the changed paths first, then the others.
+	CodeownersModePrefer CodeownersMode = "prefer"
+	// CodeownersModeRequire only selects approvers owning the changed paths.
+	CodeownersModeRequire CodeownersMode = "require"
+)
+
+// ParseCodeownersMode validates the given CODEOWNERS mode.
+func ParseCodeownersMode(s string) (CodeownersMode, error) {
+	switch mode := CodeownersMode(s); mode {
+	case CodeownersModeOff, CodeownersModePrefer, CodeownersModeRequire:
+		return mode, nil
+	case "":
+		return CodeownersModeOff, nil
+	}
+	return "", fmt.Errorf("unknown codeowners mode %q", s)
+}
+
+// codeownersGroup is a set of changed paths sharing the same owners in the CODEOWNERS file.
+type codeownersGroup struct {
+	// Owners are the handles of the matching rule, as written in the file.
+	Owners []string
+	// users are the GitHub users (lowercased) among the owners, teams expanded into their members.
+	users map[string]struct{}
+}
+
+// resolveCodeowners returns the groups of the paths changed by the PR that have an owner, according to the
+// CODEOWNERS file of the base branch of the PR. Teams are expanded into their members and emails are ignored,
+// so the paths only owned by emails are not part of any group.
+// It returns nil if the repository has no CODEOWNERS file or if none of the changed paths has an owner,
+// in which case approvers must not be restricted.
+func (s *Server) resolveCodeowners(link github.PRLink) ([]codeownersGroup, error) {
+	pr, err := s.githubClient.GetPullRequest(link)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PR: %w", err)
+	}
+	codeowners, err := s.githubClient.GetCodeowners(link.Owner, link.Repo, pr.Base.Ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CODEOWNERS: %w", err)
+	}
+	if codeowners == nil {
+		return nil, nil
+	}
+
+	files, err := s.githubClient.GetPRFiles(link)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PR files: %w", err)
+	}
+
+	// Group the changed files by owners first so that each team is fetched once.
+	var groups []codeownersGroup
+	seen := make(map[string]struct{})
+	for _, f := range files {
+		owners := codeownersHandles(codeowners.OwnersFor(f))
+		key := strings.Join(owners, " ")
+		if _, ok := seen[key]; ok || len(owners) == 0 {
+			continue
+		}
+		seen[key] = struct{}{}
+		groups = append(groups, codeownersGroup{Owners: owners, users: make(map[string]struct{})})
+	}
+	if len(groups) == 0 {
+		return nil, nil
+	}
+
+	teams := make(map[string][]string)
+	for _, g := range groups {
+		for _, handle := range g.Owners {
+			org, team, isTeam := strings.Cut(strings.TrimPrefix(handle, "@"), "/")
+			if !isTeam {
+				g.users[org] = struct{}{}
+				continue
+			}
+			members, ok := teams[handle]
+			if !ok {
+				members, err = s.githubClient.GetTeamMembers(org, team)
+				if err != nil {
+					return nil, fmt.Errorf("failed to get members of team %s: %w", handle, err)
+				}
+				teams[handle] = members
+			}
+			for _, m := range members {
+				g.users[strings.ToLower(m)] = struct{}{}
+			}
+		}
+	}
+	return groups, nil
+}
+
+// codeownersHandles returns the lowercased GitHub handles among the owners of a path, sorted and without duplicates.
+// Owners can also be identified by email, which cannot be matched with connected approvers.
+func codeownersHandles(owners []string) []string {
+	handles := make([]string, 0, len(owners))
+	for _, o := range owners {
+		if strings.HasPrefix(o, "@") {
+			handles = append(handles, strings.ToLower(o))
+		}
+	}
+	slices.Sort(handles)
+	return slices.Compact(handles)
+}
+
+// codeownersFilter returns the groups of code owners the approvers of the PR should be selected from,
+// according to the server's CODEOWNERS mode. It returns nil if approvers must not be restricted, which is
+// always the case for the change requests of forges other than GitHub.
+func (s *Server) codeownersFilter(link github.PRLink) ([]codeownersGroup, error) {
+	if s.codeownersMode == CodeownersModeOff || s.codeownersMode == "" || s.githubClient == nil {
+		return nil, nil
+	}
+	if link.ProviderName() != forge.ProviderGitHub {
+		log.Printf("CODEOWNERS are not supported on %s, approvers of %s will not be restricted", link.ProviderName(), link)
+		return nil, nil
+	}
+	groups, err := s.resolveCodeowners(link)
+	if err != nil {
+		if s.codeownersMode == CodeownersModeRequire {
+			return nil, err
+		}
+		log.Printf("failed to resolve code owners of %s, approvers will not be restricted: %s", link, err)
+		return nil, nil
+	}
+	return groups, nil
+}
+
+// ownersOf returns the union of the users of the groups.
+func ownersOf(groups []codeownersGroup) map[string]struct{} {
+	owners := make(map[string]struct{})
+	for _, g := range groups {
+		for u := range g.users {
+			owners[u] = struct{}{}
+		}
+	}
+	return owners
+}
+
+// uncoveredGroups returns the groups none of the approvers belongs to.
+func uncoveredGroups(groups []codeownersGroup, approvers []string) []codeownersGroup {
+	var uncovered []codeownersGroup
+	for _, g := range groups {
+		covered := slices.ContainsFunc(approvers, func(a string) bool {
+			_, ok := g.users[strings.ToLower(a)]
+			return ok
+		})
+		if !covered {
+			uncovered = append(uncovered, g)
+		}
+	}
+	return uncovered
+}
+
+// filterOwners returns the clients whose GitHub user is one of the owners.
+func filterOwners(clients []*clientInfo, owners map[string]struct{}) []*clientInfo {
+	filtered := make([]*clientInfo, 0, len(clients))
+	for _, c := range clients {
+		if _, ok := owners[strings.ToLower(c.githubUser)]; ok {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/clems4ever/lgtm/internal/api"
+	"github.com/clems4ever/lgtm/internal/github"
+	"github.com/clems4ever/lgtm/internal/protocol"
+	"github.com/clems4ever/lgtm/internal/test"
+	"github.com/stretchr/testify/require"
+)
+
+func newCodeownersTestServer(t *testing.T, mode CodeownersMode) (*Server, string, *test.GithubMockServer) {
+	t.Helper()
+	githubSrv := test.NewGithubMockServer(t, "")
+	t.Cleanup(githubSrv.Close)
+	githubSrv.AddUser("lgtm-bot", "server-token", nil)
+	githubSrv.AddFile("foo/bar", ".github/CODEOWNERS", "* @alice\n/api/ @foo/api-team\n")
+	githubSrv.AddTeam("foo", "api-team", []string{"Carol"})
+
+	s, wsURL := newTestServer(t, nil, DefaultRetryPolicy())
+	s.codeownersMode = mode
+	s.githubClient = github.NewClient("server-token", githubSrv.URL(), nil)
+	return s, wsURL, githubSrv
+}
+
+func TestResolveCodeowners(t *testing.T) {
+	s, _, githubSrv := newCodeownersTestServer(t, CodeownersModeRequire)
+	githubSrv.SetPRFiles("foo/bar", 1, []string{"README.md", "api/handler.go"})
+
+	groups, err := s.resolveCodeowners(testLink)
+	require.NoError(t, err)
+	require.Equal(t, []codeownersGroup{
+		{Owners: []string{"@alice"}, users: map[string]struct{}{"alice": {}}},
+		{Owners: []string{"@foo/api-team"}, users: map[string]struct{}{"carol": {}}},
+	}, groups)
+
+	// Without CODEOWNERS file, approvers are not restricted.
+	groups, err = s.resolveCodeowners(github.PRLink{Owner: "foo", Repo: "other", PRNumber: 1})
+	require.NoError(t, err)
+	require.Nil(t, groups)
+}
+
+func TestResolveCodeowners_BaseBranch(t *testing.T) {
+	s, _, githubSrv := newCodeownersTestServer(t, CodeownersModeRequire)
+	githubSrv.SetPRFiles("foo/bar", 1, []string{"README.md"})
+	githubSrv.AddFileOnBranch("foo/bar", "release", ".github/CODEOWNERS", "* @bob\n")
+	githubSrv.SetPRBase("foo/bar", 1, "release")
+
+	// The CODEOWNERS file of the base branch applies, not the one of the default branch.
+	groups, err := s.resolveCodeowners(testLink)
+	require.NoError(t, err)
+	require.Equal(t, []codeownersGroup{{Owners: []string{"@bob"}, users: map[string]struct{}{"bob": {}}}}, groups)
+}
+
+func TestRequestApproval_RequiresCodeowners(t *testing.T) {
+	s, wsURL, githubSrv := newCodeownersTestServer(t, CodeownersModeRequire)
+	githubSrv.SetPRFiles("foo/bar", 1, []string{"api/handler.go"})
+
+	connectFakeApprover(t, s, wsURL, "alice", []string{"foo/bar"}, respondWith(protocol.ApproveResponseSuccess))
+	connectFakeApprover(t, s, wsURL, "bob", []string{"foo/bar"}, respondWith(protocol.ApproveResponseSuccess))
+	connectFakeApprover(t, s, wsURL, "carol", []string{"foo/bar"}, respondWith(protocol.ApproveResponseSuccess))
+
+	result, err := s.RequestApproval(api.ApprovalRequest{Link: testLink, RequiredApprovals: 1})
+	require.NoError(t, err)
+	require.Equal(t, []string{"carol"}, result.Approvers)
+
+	// Only one code owner is connected, so a second approval cannot be collected.
+	result, err = s.RequestApproval(api.ApprovalRequest{Link: testLink, RequiredApprovals: 2})
+	require.ErrorIs(t, err, ErrNotEnoughApprovals)
+	require.Equal(t, []string{"carol"}, result.Approvers)
+}
+
+func TestRequestApproval_PrefersCodeowners(t *testing.T) {
+	s, wsURL, githubSrv := newCodeownersTestServer(t, CodeownersModePrefer)
+	githubSrv.SetPRFiles("foo/bar", 1, []string{"api/handler.go"})
+
+	connectFakeApprover(t, s, wsURL, "alice", []string{"foo/bar"}, respondWith(protocol.ApproveResponseSuccess))
+	connectFakeApprover(t, s, wsURL, "carol", []string{"foo/bar"}, respondWith(protocol.ApproveResponseErrFailed))
+
+	// The code owner is tried first, then the request falls back to the other approvers.
+	result, err := s.RequestApproval(api.ApprovalRequest{Link: testLink, RequiredApprovals: 1})
+	require.NoError(t, err)
+	require.Len(t, result.Attempts, 2)
+	require.Equal(t, "carol", result.Attempts[0].Approver)
+	require.Equal(t, []string{"alice"}, result.Approvers)
+}
+
+func TestRequestApproval_RequiresOwnerOfEveryPathGroup(t *testing.T) {
+	s, wsURL, githubSrv := newCodeownersTestServer(t, CodeownersModeRequire)
+	githubSrv.SetPRFiles("foo/bar", 1, []string{"README.md", "api/handler.go"})
+
+	connectFakeApprover(t, s, wsURL, "carol", []string{"foo/bar"}, respondWith(protocol.ApproveResponseSuccess))
+
+	// The approval of an API team member is not enough, the other paths are owned by alice.
+	result, err := s.RequestApproval(api.ApprovalRequest{Link: testLink, RequiredApprovals: 1})
+	require.ErrorIs(t, err, ErrNoEligibleApprover)
+	require.ErrorIs(t, err, ErrNotEnoughApprovals)
+	require.Equal(t, []string{"carol"}, result.Approvers)
+
+	connectFakeApprover(t, s, wsURL, "alice", []string{"foo/bar"}, respondWith(protocol.ApproveResponseSuccess))
+
+	result, err = s.RequestApproval(api.ApprovalRequest{Link: testLink, RequiredApprovals: 1})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"alice", "carol"}, result.Approvers)
+}
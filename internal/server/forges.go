@@ -0,0 +1,25 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/clems4ever/lgtm/internal/forge"
+)
+
+// ForgeClient is a forge other than GitHub accessed with the token of the server, to read the change requests
+// submitted for approval and check their submitters.
+type ForgeClient interface {
+	forge.Provider
+	// HasWriteAccess tells whether the user can push to the repository ("owner/repo").
+	HasWriteAccess(owner, repo, username string) (bool, error)
+}
+
+// forgeOf returns the forge serving the change request, nil for GitHub.
+func (s *Server) forgeOf(link forge.ChangeLink) ForgeClient {
+	for _, f := range s.forges {
+		if f.Name() == link.ProviderName() && strings.EqualFold(f.Host(), link.HostName()) {
+			return f
+		}
+	}
+	return nil
+}
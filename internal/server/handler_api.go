@@ -0,0 +1,59 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/clems4ever/lgtm/internal/api"
+	"github.com/clems4ever/lgtm/internal/forge"
+	"github.com/clems4ever/lgtm/internal/github"
+	"github.com/gorilla/mux"
+)
+
+// handlerRepoApprovers handles GET requests listing the approvers available for a repository.
+// Only users who can read the repository on GitHub can list its approvers, other users get 404 Not Found.
+func (s *Server) handlerRepoApprovers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+
+	vars := mux.Vars(r)
+	owner, repo := vars["owner"], vars["repo"]
+
+	accessToken := r.Context().Value("access_token").(string)
+	gh := github.NewClient(accessToken, s.githubAPIURL, s.httpClient)
+	if _, err := gh.GetRepo(owner, repo); err != nil {
+		log.Printf("failed to get repository %s/%s: %s", owner, repo, err)
+		http.Error(w, "Repository not found", http.StatusNotFound)
+		return
+	}
+
+	fullName := owner + "/" + repo
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(api.RepoApproversResponseBody{
+		Repo:              fullName,
+		Approvers:         s.RepoApprovers(fullName),
+		RequiredApprovals: s.requiredApprovals(forge.RepoID(forge.ProviderGitHub, s.githubHost, fullName), 0),
+	})
+	if err != nil {
+		log.Println("failed to encode response", err)
+	}
+}
+
+// handlerRefreshRegistrations handles POST requests asking the connected clients of the authenticated user to
+// discover their repositories again, e.g. after being granted access to new repositories.
+func (s *Server) handlerRefreshRegistrations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	username := r.Context().Value("username").(string)
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(api.RefreshRegistrationsResponseBody{Refreshed: s.RefreshRegistrations(username)})
+	if err != nil {
+		log.Println("failed to encode response", err)
+	}
+}
@@ -35,8 +35,7 @@ func (s *Server) handlerCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	session.Values[GhAccessTokenSessionKey] = token.AccessToken
-	gh := github.NewClient(token.AccessToken, defaultGithubAPIURL, s.httpClient)
+	gh := github.NewClient(token.AccessToken, s.githubAPIURL, s.httpClient)
 
 	username, err := gh.GetAuthenticatedUserLogin()
 	if err != nil {
@@ -45,6 +44,20 @@ func (s *Server) handlerCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Refuse the session of users outside the allowed organizations and teams
+	allowed, err := s.loginRestriction.IsAllowed(gh)
+	if err != nil {
+		log.Printf("failed to check memberships of %s: %v", username, err)
+		http.Error(w, "Failed to retrieve user memberships", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		log.Printf("login refused for %s: not a member of an allowed organization or team", username)
+		renderForbidden(w, username)
+		return
+	}
+
+	session.Values[GhAccessTokenSessionKey] = token.AccessToken
 	session.Values[GhUsernameSessionKey] = username
 
 	err = session.Save(r, w)
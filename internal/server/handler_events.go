@@ -0,0 +1,104 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/clems4ever/lgtm/internal/github"
+)
+
+// eventsKeepAliveInterval is the interval between two comments sent to keep idle event streams open through proxies.
+const eventsKeepAliveInterval = 15 * time.Second
+
+// handlerEvents streams Server-Sent Events to the logged-in user:
+//   - "approvers" events carry an ApproverEvent every time an approver joins or leaves. The first one,
+//     sent when the stream opens, only carries the current number of approvers.
+//   - "request" events carry the api.RequestStatus of the user's requests every time it changes. The current status
+//     of every request is sent when the stream opens, so that a client reconnecting does not miss updates.
+//
+// The stream is closed if the user does not keep up with the events, the client reconnects to catch up.
+func (s *Server) handlerEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	username := r.Context().Value("username").(string)
+	gh := github.NewClient(r.Context().Value("access_token").(string), s.githubAPIURL, s.httpClient)
+
+	approverEvents, unsubscribeApprovers := s.approvalEngine.Subscribe()
+	defer unsubscribeApprovers()
+	requestUpdates, unsubscribeRequests := s.requests.Subscribe(username)
+	defer unsubscribeRequests()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if err := writeEvent(w, "approvers", ApproverEvent{Count: len(s.approvalEngine.GetApprovers())}); err != nil {
+		return
+	}
+	// Updates received in the meantime are sent again afterwards, the client ignores the older ones.
+	for _, status := range s.requests.List(username) {
+		if err := writeEvent(w, "request", status); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventsKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.ctx.Done():
+			return
+		case event, ok := <-approverEvents:
+			if !ok {
+				log.Printf("closing the event stream of %s, which does not keep up", username)
+				return
+			}
+			err = writeEvent(w, "approvers", event)
+		case status, ok := <-requestUpdates:
+			if !ok {
+				log.Printf("closing the event stream of %s, which does not keep up", username)
+				return
+			}
+			err = writeEvent(w, "request", status)
+		case <-keepAlive.C:
+			// The stream outlives the check of the login restriction made when it opened
+			if allowed, checkErr := s.isLoginAllowed(username, gh); checkErr != nil || !allowed {
+				log.Printf("closing the event stream of %s, who no longer passes the login restriction: %v", username, checkErr)
+				return
+			}
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		if err != nil {
+			log.Printf("failed to write event to %s: %s", username, err)
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes a Server-Sent Event with the given name and JSON data.
+func writeEvent(w http.ResponseWriter, name string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, payload)
+	return err
+}
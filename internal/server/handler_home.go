@@ -11,9 +11,13 @@ import (
 // HomeTemplateArgs represents the data passed to the home page template.
 // User: the authenticated user's GitHub username.
 // Approvers: the number of available approvers.
+// QueuedRequests: the requests of the user waiting for an approver to connect.
+// APITokens: the personal API tokens of the user.
 type HomeTemplateArgs struct {
-	User      string // Username of the authenticated user
-	Approvers int    // Number of available approvers
+	User           string          // Username of the authenticated user
+	Approvers      int             // Number of available approvers
+	QueuedRequests []QueuedRequest // Requests queued by the user
+	APITokens      []APIToken      // API tokens issued to the user
 }
 
 // Embed the home.html template file for rendering the home page.
@@ -38,10 +42,17 @@ func (s *Server) handlerHome(w http.ResponseWriter, r *http.Request) {
 	username := r.Context().Value("username").(string)
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 
-	// Render the home page template with the username and approver count.
+	var queued []QueuedRequest
+	if s.queue != nil {
+		queued = s.queue.ListByRequester(username)
+	}
+
+	// Render the home page template with the username, approver count, queued requests and API tokens.
 	err := homeTemplate.Execute(w, HomeTemplateArgs{
-		User:      username,
-		Approvers: len(s.approvalEngine.GetApprovers()),
+		User:           username,
+		Approvers:      len(s.approvalEngine.GetApprovers()),
+		QueuedRequests: queued,
+		APITokens:      s.tokens.ListByUser(username),
 	})
 	if err != nil {
 		log.Println("failed to execute template", err)
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// handlerCancelQueuedRequest handles DELETE requests cancelling a queued approval request.
+// Only the user who queued the request can cancel it.
+func (s *Server) handlerCancelQueuedRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.NotFound(w, r)
+		return
+	}
+	if s.queue == nil {
+		http.Error(w, "Queue is disabled", http.StatusNotFound)
+		return
+	}
+
+	username := r.Context().Value("username").(string)
+	id := mux.Vars(r)["id"]
+
+	err := s.queue.Cancel(id, username)
+	if err != nil {
+		log.Printf("failed to cancel queued request %s: %s", id, err)
+		switch {
+		case errors.Is(err, ErrQueuedRequestNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrNotRequester):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, "Failed to cancel request", http.StatusInternalServerError)
+		}
+		return
+	}
+	s.requests.Cancel(id)
+	w.WriteHeader(http.StatusNoContent)
+}
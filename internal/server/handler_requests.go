@@ -0,0 +1,32 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// handlerGetRequest handles GET requests returning the status of a submitted approval request as JSON.
+// Only the user who submitted the request can read its status, other users get 404 Not Found.
+func (s *Server) handlerGetRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+
+	username := r.Context().Value("username").(string)
+	id := mux.Vars(r)["id"]
+
+	status, err := s.requests.Get(id, username)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		log.Println("failed to encode response", err)
+	}
+}
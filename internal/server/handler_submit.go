@@ -6,25 +6,35 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/clems4ever/lgtm/internal/api"
+	"github.com/clems4ever/lgtm/internal/forge"
 	"github.com/clems4ever/lgtm/internal/github"
 )
 
-// SubmitBodyRequest represents the expected JSON body for a PR submission.
-type SubmitBodyRequest struct {
-	PRLink string `json:"pr_link"`
-}
+// maxJustificationLength is the maximum number of characters of a justification.
+const maxJustificationLength = 1000
+
+var (
+	// ErrJustificationTooLong is returned when the justification exceeds maxJustificationLength characters.
+	ErrJustificationTooLong = fmt.Errorf("justification too long")
+)
 
 // handlerSubmit handles POST requests to submit a PR for approval.
-// It parses the PR link, validates it, and attempts to forward it for approval.
-// Returns appropriate HTTP status codes and messages for error/success cases.
+// It parses the PR link, validates it, and forwards it for approval in the background.
+// It returns 202 Accepted right away with the status of the request, which can then be followed with GET /requests/{id}.
+// Submitters who are not allowed to request the approval of the PR get 403 Forbidden before any routing.
 func (s *Server) handlerSubmit(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.NotFound(w, r)
 		return
 	}
 
-	var resp SubmitBodyRequest
+	var resp api.SubmitBodyRequest
 	err := json.NewDecoder(r.Body).Decode(&resp)
 	if err != nil {
 		log.Println("failed to decode body", err)
@@ -32,25 +42,100 @@ func (s *Server) handlerSubmit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	prLink, err := github.ParsePullRequestURL(resp.PRLink)
-	if err != nil {
+	justification := strings.TrimSpace(resp.Justification)
+	if utf8.RuneCountInString(justification) > maxJustificationLength {
+		http.Error(w, fmt.Sprintf("Justification must not exceed %d characters", maxJustificationLength), http.StatusBadRequest)
+		return
+	}
+
+	providers := []forge.Provider{github.NewProvider(nil, s.githubHost)}
+	hosts := []string{s.githubHost}
+	for _, f := range s.forges {
+		providers = append(providers, f)
+		hosts = append(hosts, f.Host())
+	}
+	prLink, err := forge.ParseChangeURL(resp.PRLink, providers...)
+	if errors.Is(err, forge.ErrUnsupportedHost) {
+		http.Error(w, fmt.Sprintf("Pull request must be hosted on %s", strings.Join(hosts, " or ")), http.StatusBadRequest)
+		return
+	} else if err != nil {
 		log.Println("failed to parse pull request", err)
 		http.Error(w, "Invalid pull request URL", http.StatusBadRequest)
 		return
 	}
 
-	// Attempt to forward the PR for approval.
-	err = s.RequestApproval(prLink)
+	// Pin the approval to the current head of the PR so that commits pushed afterwards are not approved unseen.
+	// Only the author or a collaborator may request the approval of a PR, depending on the configured mode.
+	username := r.Context().Value("username").(string)
+	accessToken := r.Context().Value("access_token").(string)
+	var headSHA string
+	if f := s.forgeOf(prLink); f != nil {
+		headSHA, err = s.checkForgeSubmission(f, prLink, username)
+	} else {
+		headSHA, err = s.checkGithubSubmission(prLink, username, accessToken)
+	}
 	if err != nil {
-		fmt.Printf("failed to approve PR %s: %s\n", prLink, err)
-		if errors.Is(err, ErrNoEligibleApprover) {
-			// No eligible approver found: return 422 Unprocessable Entity.
-			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-			return
+		log.Printf("submission of %s by %s refused: %s", prLink, username, err)
+		if errors.Is(err, ErrSubmitterNotAllowed) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+		} else {
+			http.Error(w, "Failed to verify the pull request", http.StatusBadGateway)
 		}
-		// Internal error during approval process.
-		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	// If successful, you may want to write a success message here.
+
+	// Forward the PR for approval in the background, its progress is exposed under /requests/{id}.
+	status, err := s.SubmitApprovalAsync(api.ApprovalRequest{
+		Link:              prLink,
+		Requester:         username,
+		RequiredApprovals: resp.RequiredApprovals,
+		HeadSHA:           headSHA,
+		Justification:     justification,
+		RequestedAt:       time.Now(),
+	})
+	if err != nil {
+		fmt.Printf("failed to submit PR %s: %s\n", prLink, err)
+		if errors.Is(err, ErrRequesterNotAllowed) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+		} else {
+			http.Error(w, "Failed to submit the PR", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	// The status is served next to the submission endpoint: /submit and /requests/{id} for the web UI,
+	// /api/v1/requests and /api/v1/requests/{id} for the API.
+	w.Header().Set("Location", path.Join(path.Dir(r.URL.Path), "requests", status.ID))
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		log.Println("failed to encode response", err)
+	}
+}
+
+// checkGithubSubmission fetches the PR with the token of the submitter, verifies the submitter and returns the
+// head SHA of the PR.
+func (s *Server) checkGithubSubmission(link forge.ChangeLink, username, accessToken string) (string, error) {
+	gh := github.NewClient(accessToken, s.githubAPIURL, s.httpClient)
+	pr, err := gh.GetPullRequest(link)
+	if err != nil {
+		return "", fmt.Errorf("failed to get pull request: %w", err)
+	}
+	if err := s.verifySubmitter(gh, link, pr, username); err != nil {
+		return "", err
+	}
+	return pr.Head.SHA, nil
+}
+
+// checkForgeSubmission fetches the change request from a forge other than GitHub with the token of the server,
+// verifies the submitter and returns the head SHA of the change request.
+func (s *Server) checkForgeSubmission(f ForgeClient, link forge.ChangeLink, username string) (string, error) {
+	change, err := f.GetChange(link)
+	if err != nil {
+		return "", fmt.Errorf("failed to get change request: %w", err)
+	}
+	if err := s.verifyForgeSubmitter(f, link, change, username); err != nil {
+		return "", err
+	}
+	return change.HeadSHA, nil
 }
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// CreateTokenBodyRequest represents the expected JSON body for issuing an API token.
+type CreateTokenBodyRequest struct {
+	Name string `json:"name"`
+}
+
+// CreateTokenResponseBody is the token issued to the user along with its secret, which is only shown once.
+type CreateTokenResponseBody struct {
+	APIToken
+	Token string `json:"token"`
+}
+
+// handlerCreateToken handles POST requests issuing a personal API token to the logged-in user.
+// The token acts on behalf of the user with their current GitHub access token.
+func (s *Server) handlerCreateToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	var body CreateTokenBodyRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Println("failed to decode body", err)
+		http.Error(w, "Failed to decode request body", http.StatusBadRequest)
+		return
+	}
+
+	username := r.Context().Value("username").(string)
+	accessToken := r.Context().Value("access_token").(string)
+	token, secret, err := s.tokens.Issue(username, accessToken, body.Name)
+	if err != nil {
+		log.Printf("failed to issue token to %s: %s", username, err)
+		if errors.Is(err, ErrInvalidAPITokenName) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Failed to issue token", http.StatusInternalServerError)
+		}
+		return
+	}
+	log.Printf("API token %s issued to %s", token.ID, username)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(CreateTokenResponseBody{APIToken: token, Token: secret}); err != nil {
+		log.Println("failed to encode response", err)
+	}
+}
+
+// handlerRevokeToken handles DELETE requests revoking a personal API token.
+// Only the owner of the token can revoke it, other users get 404 Not Found.
+func (s *Server) handlerRevokeToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.NotFound(w, r)
+		return
+	}
+
+	username := r.Context().Value("username").(string)
+	id := mux.Vars(r)["id"]
+
+	if err := s.tokens.Revoke(id, username); err != nil {
+		log.Printf("failed to revoke token %s: %s", id, err)
+		if errors.Is(err, ErrAPITokenNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to revoke token", http.StatusInternalServerError)
+		}
+		return
+	}
+	log.Printf("API token %s revoked by %s", id, username)
+	w.WriteHeader(http.StatusNoContent)
+}
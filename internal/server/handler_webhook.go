@@ -0,0 +1,58 @@
+package server
+
+import (
+	"io"
+	"log"
+	"net/http"
+)
+
+// handlerWebhook handles the pull_request and issue_comment webhooks sent by GitHub.
+// Payloads whose signature does not match the configured secret are rejected with 401 Unauthorized.
+// Events triggering an approval request are answered with 202 Accepted right away, the request is then routed
+// in the background and its outcome is posted as a comment on the PR. Other events are acknowledged and ignored.
+func (s *Server) handlerWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookPayloadSize))
+	if err != nil {
+		log.Println("failed to read webhook payload", err)
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	if err := verifyWebhookSignature(s.webhook.Secret, r.Header.Get("X-Hub-Signature-256"), payload); err != nil {
+		log.Printf("webhook %s rejected: %s", r.Header.Get("X-GitHub-Delivery"), err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	trigger, err := s.webhook.parseTrigger(r.Header.Get("X-GitHub-Event"), payload)
+	if err != nil {
+		log.Printf("failed to parse webhook %s: %s", r.Header.Get("X-GitHub-Delivery"), err)
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if trigger == nil {
+		w.Write([]byte("Event ignored"))
+		return
+	}
+	if !s.markWebhookDelivery(r.Header.Get("X-GitHub-Delivery")) {
+		log.Printf("webhook %s ignored: already delivered", r.Header.Get("X-GitHub-Delivery"))
+		w.Write([]byte("Delivery already processed"))
+		return
+	}
+	// Webhooks are sent by the GitHub instance the server is configured for
+	trigger.Link.Host = s.githubHost
+	log.Printf("approval of %s requested by %s through a webhook", trigger.Link, trigger.Requester)
+
+	// GitHub expects an answer within 10 seconds, the routing can take much longer.
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.processWebhookTrigger(*trigger)
+	}()
+	w.WriteHeader(http.StatusAccepted)
+	w.Write([]byte("Approval requested"))
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	_ "embed"
+
+	"github.com/clems4ever/lgtm/internal/github"
+)
+
+// loginRecheckInterval is how long a successful membership check is trusted before the memberships of a user
+// authenticated by an existing session or API token are checked again.
+const loginRecheckInterval = 10 * time.Minute
+
+// LoginRestriction restricts the web login to the members of some GitHub organizations or teams.
+// If both lists are empty, any GitHub user can log in.
+type LoginRestriction struct {
+	// AllowedOrgs lists the organizations whose members can log in.
+	AllowedOrgs []string
+	// AllowedTeams lists the teams, as "org/team-slug", whose members can log in.
+	AllowedTeams []string
+}
+
+// ParseAllowedTeams validates a list of "org/team-slug" teams.
+func ParseAllowedTeams(teams []string) ([]string, error) {
+	for _, t := range teams {
+		org, slug, ok := strings.Cut(t, "/")
+		if !ok || org == "" || slug == "" || strings.Contains(slug, "/") {
+			return nil, fmt.Errorf("invalid team %q, expected org/team-slug", t)
+		}
+	}
+	return teams, nil
+}
+
+// Enabled tells whether the login is restricted.
+func (lr *LoginRestriction) Enabled() bool {
+	return lr != nil && (len(lr.AllowedOrgs) > 0 || len(lr.AllowedTeams) > 0)
+}
+
+// IsAllowed tells whether the user authenticated by gh is a member of one of the allowed organizations or teams.
+// The token of the user needs the 'read:org' permission.
+func (lr *LoginRestriction) IsAllowed(gh *github.Client) (bool, error) {
+	if !lr.Enabled() {
+		return true, nil
+	}
+	if len(lr.AllowedOrgs) > 0 {
+		orgs, err := gh.GetUserOrgs()
+		if err != nil {
+			return false, fmt.Errorf("failed to get organizations: %w", err)
+		}
+		for _, org := range orgs {
+			if containsUser(lr.AllowedOrgs, org) {
+				return true, nil
+			}
+		}
+	}
+	if len(lr.AllowedTeams) > 0 {
+		teams, err := gh.GetUserTeams()
+		if err != nil {
+			return false, fmt.Errorf("failed to get teams: %w", err)
+		}
+		for _, team := range teams {
+			if containsUser(lr.AllowedTeams, team) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// IsUserAllowed is like IsAllowed for a user the client is not authenticated as, such as the requester of
+// a webhook. The memberships are looked up with the client, whose token needs the 'read:org' permission.
+func (lr *LoginRestriction) IsUserAllowed(gh *github.Client, username string) (bool, error) {
+	if !lr.Enabled() {
+		return true, nil
+	}
+	for _, org := range lr.AllowedOrgs {
+		member, err := gh.IsOrgMember(org, username)
+		if err != nil {
+			return false, fmt.Errorf("failed to get membership of organization %s: %w", org, err)
+		}
+		if member {
+			return true, nil
+		}
+	}
+	for _, team := range lr.AllowedTeams {
+		org, slug, _ := strings.Cut(team, "/")
+		member, err := gh.IsTeamMember(org, slug, username)
+		if err != nil {
+			return false, fmt.Errorf("failed to get membership of team %s: %w", team, err)
+		}
+		if member {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isLoginAllowed tells whether the user authenticated by gh still passes the login restriction. Users who left
+// the allowed organizations and teams lose access within loginRecheckInterval, successful checks are cached
+// for that long.
+func (s *Server) isLoginAllowed(username string, gh *github.Client) (bool, error) {
+	if !s.loginRestriction.Enabled() {
+		return true, nil
+	}
+	s.allowedLoginsMu.Lock()
+	checkedAt, ok := s.allowedLogins[username]
+	s.allowedLoginsMu.Unlock()
+	if ok && time.Since(checkedAt) < loginRecheckInterval {
+		return true, nil
+	}
+
+	allowed, err := s.loginRestriction.IsAllowed(gh)
+	if err != nil {
+		return false, err
+	}
+	s.allowedLoginsMu.Lock()
+	defer s.allowedLoginsMu.Unlock()
+	if allowed {
+		s.allowedLogins[username] = time.Now()
+	} else {
+		delete(s.allowedLogins, username)
+	}
+	return allowed, nil
+}
+
+// ForbiddenTemplateArgs represents the data passed to the page shown to users who are not allowed to log in.
+type ForbiddenTemplateArgs struct {
+	User string // Username of the GitHub user who tried to log in
+}
+
+// Embed the forbidden.html template file for rendering the login refusal page.
+//
+//go:embed ui/forbidden.html
+var forbiddenHTML string
+
+// forbiddenTemplate is the parsed HTML template for the login refusal page.
+var forbiddenTemplate = template.Must(template.New("forbidden").Parse(forbiddenHTML))
+
+// renderForbidden answers 403 Forbidden with the page explaining to the user that they are not allowed to log in.
+func renderForbidden(w http.ResponseWriter, username string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusForbidden)
+	if err := forbiddenTemplate.Execute(w, ForbiddenTemplateArgs{User: username}); err != nil {
+		log.Println("failed to execute template", err)
+	}
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/clems4ever/lgtm/internal/github"
+	"github.com/clems4ever/lgtm/internal/test"
+	"github.com/gorilla/sessions"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestLoginRestriction_IsAllowed(t *testing.T) {
+	githubSrv := test.NewGithubMockServer(t, "")
+	t.Cleanup(githubSrv.Close)
+	githubSrv.AddUser("alice", "alice-token", nil)
+	githubSrv.AddUser("bob", "bob-token", nil)
+	githubSrv.AddUser("mallory", "mallory-token", nil)
+	githubSrv.AddOrgMember("acme", "alice")
+	githubSrv.AddTeam("partner", "reviewers", []string{"Bob"})
+	githubSrv.AddTeam("partner", "interns", []string{"mallory"})
+
+	tests := []struct {
+		name        string
+		restriction *LoginRestriction
+		token       string
+		allowed     bool
+	}{
+		{"no restriction", nil, "mallory-token", true},
+		{"org member", &LoginRestriction{AllowedOrgs: []string{"ACME"}}, "alice-token", true},
+		{"team member", &LoginRestriction{AllowedOrgs: []string{"acme"}, AllowedTeams: []string{"partner/reviewers"}}, "bob-token", true},
+		{"other team", &LoginRestriction{AllowedOrgs: []string{"acme"}, AllowedTeams: []string{"partner/reviewers"}}, "mallory-token", false},
+		{"team of the org only", &LoginRestriction{AllowedTeams: []string{"partner/reviewers"}}, "alice-token", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, err := tt.restriction.IsAllowed(github.NewClient(tt.token, githubSrv.URL(), nil))
+			require.NoError(t, err)
+			require.Equal(t, tt.allowed, allowed)
+		})
+	}
+}
+
+func TestParseAllowedTeams(t *testing.T) {
+	teams, err := ParseAllowedTeams([]string{"acme/core"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"acme/core"}, teams)
+
+	for _, in := range []string{"acme", "/core", "acme/", "acme/core/x"} {
+		_, err := ParseAllowedTeams([]string{in})
+		require.Error(t, err, in)
+	}
+}
+
+func TestMiddlewareWebAuth_LoginRestriction(t *testing.T) {
+	githubSrv := test.NewGithubMockServer(t, "")
+	t.Cleanup(githubSrv.Close)
+	githubSrv.AddUser("alice", "gh-alice", nil)
+	githubSrv.AddOrgMember("acme", "alice")
+
+	s := NewServer(&oauth2.Config{}, 0, nil, RetryPolicy{})
+	t.Cleanup(s.Close)
+	s.githubAPIURL = githubSrv.URL()
+	s.sessionStore = sessions.NewCookieStore([]byte("0123456789abcdef0123456789abcdef"))
+	s.loginRestriction = &LoginRestriction{AllowedOrgs: []string{"acme"}}
+
+	// Log alice in
+	rec := httptest.NewRecorder()
+	session, err := s.sessionStore.Get(httptest.NewRequest(http.MethodGet, "/", nil), SessionName)
+	require.NoError(t, err)
+	session.Values[GhAccessTokenSessionKey] = "gh-alice"
+	session.Values[GhUsernameSessionKey] = "alice"
+	require.NoError(t, session.Save(httptest.NewRequest(http.MethodGet, "/", nil), rec))
+	cookie := rec.Header().Get("Set-Cookie")
+
+	handler := s.middlewareWebAuthMiddleware(func(w http.ResponseWriter, r *http.Request) {})
+	call := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Cookie", cookie)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+	require.Equal(t, http.StatusOK, call().Code)
+
+	// The session is refused and forgotten once alice left the organization and the cached check expired.
+	s.loginRestriction.AllowedOrgs = []string{"other"}
+	require.Equal(t, http.StatusOK, call().Code)
+	s.allowedLoginsMu.Lock()
+	s.allowedLogins["alice"] = time.Now().Add(-loginRecheckInterval)
+	s.allowedLoginsMu.Unlock()
+	rec = call()
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	require.NotEmpty(t, rec.Header().Get("Set-Cookie"))
+}
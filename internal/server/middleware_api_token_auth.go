@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/clems4ever/lgtm/internal/github"
+)
+
+// middlewareAPITokenAuth is an HTTP middleware authenticating the requests to the API with a personal API token
+// passed in the Authorization header as a Bearer token. It injects the username and GitHub access token of the
+// owner of the token into the request context, like the web authentication middleware does.
+// If the token is missing, invalid or expired, the request is rejected with a 401 Unauthorized status. If its owner
+// no longer passes the login restriction, it is rejected with a 403 Forbidden status.
+func (s *Server) middlewareAPITokenAuth(fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		secret, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		token, accessToken, err := s.tokens.Authenticate(strings.TrimSpace(secret))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		// The owner of the token may have left the allowed organizations and teams since it was issued
+		allowed, err := s.isLoginAllowed(token.Username, github.NewClient(accessToken, s.githubAPIURL, s.httpClient))
+		if err != nil {
+			log.Printf("failed to check memberships of %s: %v", token.Username, err)
+			http.Error(w, "Failed to retrieve user memberships", http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			log.Printf("API token %s refused: %s is not a member of an allowed organization or team", token.ID, token.Username)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), "username", token.Username)
+		ctx = context.WithValue(ctx, "access_token", accessToken)
+		fn(w, r.WithContext(ctx))
+	}
+}
@@ -5,12 +5,14 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/clems4ever/lgtm/internal/github"
 	"golang.org/x/oauth2"
 )
 
 // middlewareWebAuthMiddleware is an HTTP middleware that ensures GitHub OAuth authentication for web requests.
 // It checks the session for a valid GitHub access token and username, and injects them into the request context.
-// If authentication fails, it redirects the user to the OAuth2 login flow.
+// If authentication fails, it redirects the user to the OAuth2 login flow. Users who no longer pass the login
+// restriction are logged out and refused.
 func (s *Server) middlewareWebAuthMiddleware(fn http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Retrieve the session from the request cookies.
@@ -35,6 +37,24 @@ func (s *Server) middlewareWebAuthMiddleware(fn http.HandlerFunc) http.HandlerFu
 			return
 		}
 
+		// The user may have left the allowed organizations and teams since they logged in
+		allowed, err := s.isLoginAllowed(username, github.NewClient(accessToken, s.githubAPIURL, s.httpClient))
+		if err != nil {
+			log.Printf("failed to check memberships of %s: %v", username, err)
+			http.Error(w, "Failed to retrieve user memberships", http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			log.Printf("session of %s refused: not a member of an allowed organization or team", username)
+			delete(session.Values, GhAccessTokenSessionKey)
+			delete(session.Values, GhUsernameSessionKey)
+			if err := session.Save(r, w); err != nil {
+				log.Printf("failed to save session: %v, username: %s", err, username)
+			}
+			renderForbidden(w, username)
+			return
+		}
+
 		// Add the username and access token to the request context for downstream handlers.
 		ctx := context.WithValue(r.Context(), "username", username)
 		ctx = context.WithValue(ctx, "access_token", accessToken)
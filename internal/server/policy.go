@@ -0,0 +1,193 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/clems4ever/lgtm/internal/api"
+	"github.com/clems4ever/lgtm/internal/common"
+	"github.com/clems4ever/lgtm/internal/forge"
+	"github.com/clems4ever/lgtm/internal/github"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrRequesterNotAllowed is returned when the policy does not allow the requester to submit PRs of a repository.
+	ErrRequesterNotAllowed = fmt.Errorf("requester not allowed")
+	// ErrApprovalNotVerified is returned when the approval reported by a client cannot be found on the forge.
+	ErrApprovalNotVerified = fmt.Errorf("approval not verified")
+)
+
+// Policy controls who may approve and who may request approvals for which repositories.
+// It is loaded from a YAML or JSON file.
+type Policy struct {
+	Rules []PolicyRule `yaml:"rules" json:"rules"`
+}
+
+// PolicyRule applies to the repositories matching one of its patterns.
+// Empty lists do not restrict anything.
+type PolicyRule struct {
+	// Repos lists "owner/repo" names or common.MatchGlob patterns (e.g. "acme/*") of the repositories of the GitHub instance
+	// of the server, or patterns of repository IDs of any forge (e.g. "gitlab:gitlab.com/acme/*"), see forge.RepoID.
+	Repos []string `yaml:"repos" json:"repos"`
+	// AllowedApprovers restricts the GitHub users who may approve PRs of the repositories.
+	AllowedApprovers []string `yaml:"allowed_approvers" json:"allowed_approvers"`
+	// DeniedApprovers lists GitHub users who may never approve PRs of the repositories.
+	DeniedApprovers []string `yaml:"denied_approvers" json:"denied_approvers"`
+	// RequiredApprovals is the minimum number of distinct approvals for the repositories.
+	RequiredApprovals int `yaml:"required_approvals" json:"required_approvals"`
+	// AllowedRequesters restricts the GitHub users who may request approvals for the repositories.
+	AllowedRequesters []string `yaml:"allowed_requesters" json:"allowed_requesters"`
+}
+
+// LoadPolicy reads and validates the policy file at the given path.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	// YAML being a superset of JSON, both formats are parsed by the YAML decoder.
+	var p Policy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file: %w", err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy file: %w", err)
+	}
+	return &p, nil
+}
+
+func (p *Policy) validate() error {
+	for i, rule := range p.Rules {
+		if len(rule.Repos) == 0 {
+			return fmt.Errorf("rule %d has no repos", i)
+		}
+		for _, pattern := range rule.Repos {
+			if pattern == "" {
+				return fmt.Errorf("rule %d has an empty repo pattern", i)
+			}
+		}
+		if rule.RequiredApprovals < 0 {
+			return fmt.Errorf("rule %d has a negative number of required approvals", i)
+		}
+	}
+	return nil
+}
+
+// RuleFor returns the first rule matching the repository, given by its ID (see forge.RepoID), or nil if none does.
+// Patterns without provider select the repositories of the GitHub instance with the given host. Repositories are
+// matched regardless of case, as forges do not distinguish "Acme/App" from "acme/app".
+func (p *Policy) RuleFor(githubHost, repoID string) *PolicyRule {
+	repoID = strings.ToLower(repoID)
+	for i := range p.Rules {
+		for _, pattern := range p.Rules[i].Repos {
+			if common.MatchGlob(strings.ToLower(repoIDPattern(githubHost, pattern)), repoID) {
+				return &p.Rules[i]
+			}
+		}
+	}
+	return nil
+}
+
+// repoIDPattern returns the pattern of the repository IDs selected by a repository pattern of the policy.
+// Patterns without provider select the repositories of the GitHub instance with the given host, the one served
+// by the server.
+func repoIDPattern(githubHost, pattern string) string {
+	if strings.Contains(pattern, ":") {
+		return pattern
+	}
+	return forge.RepoID(forge.ProviderGitHub, githubHost, pattern)
+}
+
+// IsApproverAllowed tells whether the GitHub user may approve PRs of the repositories covered by the rule.
+func (r *PolicyRule) IsApproverAllowed(user string) bool {
+	if containsUser(r.DeniedApprovers, user) {
+		return false
+	}
+	return len(r.AllowedApprovers) == 0 || containsUser(r.AllowedApprovers, user)
+}
+
+// IsRequesterAllowed tells whether the GitHub user may request approvals for the repositories covered by the rule.
+func (r *PolicyRule) IsRequesterAllowed(user string) bool {
+	return len(r.AllowedRequesters) == 0 || containsUser(r.AllowedRequesters, user)
+}
+
+// restrictsApprovers tells whether the rule allows or denies some approvers. A nil rule restricts nothing.
+func (r *PolicyRule) restrictsApprovers() bool {
+	return r != nil && (len(r.AllowedApprovers) > 0 || len(r.DeniedApprovers) > 0)
+}
+
+// containsUser tells whether the list contains the GitHub user. GitHub logins are case-insensitive.
+func containsUser(users []string, user string) bool {
+	return slices.ContainsFunc(users, func(u string) bool {
+		return strings.EqualFold(u, user)
+	})
+}
+
+// PolicyStore holds the policy loaded from a file and allows reloading it without restarting the server.
+type PolicyStore struct {
+	path    string
+	current atomic.Pointer[Policy]
+}
+
+// NewPolicyStore loads the policy file at the given path.
+func NewPolicyStore(path string) (*PolicyStore, error) {
+	ps := &PolicyStore{path: path}
+	if err := ps.Reload(); err != nil {
+		return nil, err
+	}
+	return ps, nil
+}
+
+// Reload reads the policy file again. If the new file is invalid, the current policy is kept.
+func (ps *PolicyStore) Reload() error {
+	p, err := LoadPolicy(ps.path)
+	if err != nil {
+		return err
+	}
+	ps.current.Store(p)
+	log.Printf("policy loaded from %s (%d rules)", ps.path, len(p.Rules))
+	return nil
+}
+
+// Policy returns the current policy.
+func (ps *PolicyStore) Policy() *Policy {
+	return ps.current.Load()
+}
+
+// verifyApproval checks on the forge that the approver approved the request. The server does not verify the
+// login a client declares when registering, so without this check a client could register as an allowed approver.
+// The declared login is trusted if the server has no token for the forge of the request.
+func (s *Server) verifyApproval(req api.ApprovalRequest, approver string) error {
+	var provider forge.Provider
+	if req.Link.ProviderName() == forge.ProviderGitHub {
+		if s.githubClient == nil {
+			return nil
+		}
+		provider = github.NewProvider(s.githubClient, s.githubHost)
+	} else if f := s.forgeOf(req.Link); f != nil {
+		provider = f
+	} else {
+		return nil
+	}
+	state, err := provider.GetApprovalState(req.Link, req.HeadSHA)
+	if err != nil {
+		return fmt.Errorf("failed to verify the approval of %s: %w", approver, err)
+	}
+	if !containsUser(state.Approvers, approver) {
+		return fmt.Errorf("%w: %s did not approve %s", ErrApprovalNotVerified, approver, req.Link)
+	}
+	return nil
+}
+
+// policyRule returns the policy rule applying to the repository, given by its ID, or nil if there is none.
+func (s *Server) policyRule(repoID string) *PolicyRule {
+	if s.policy == nil {
+		return nil
+	}
+	return s.policy.Policy().RuleFor(s.githubHost, repoID)
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/clems4ever/lgtm/internal/api"
+	"github.com/clems4ever/lgtm/internal/forge"
+	"github.com/clems4ever/lgtm/internal/github"
+	"github.com/clems4ever/lgtm/internal/protocol"
+	"github.com/clems4ever/lgtm/internal/test"
+	"github.com/stretchr/testify/require"
+)
+
+const testPolicy = `
+rules:
+  - repos: ["foo/bar"]
+    allowed_approvers: [alice, bob]
+    denied_approvers: [bob]
+    required_approvals: 2
+    allowed_requesters: [carol]
+  - repos: ["foo/*"]
+    denied_approvers: [mallory]
+`
+
+func writePolicyFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoadPolicy(t *testing.T) {
+	p, err := LoadPolicy(writePolicyFile(t, testPolicy))
+	require.NoError(t, err)
+
+	rule := p.RuleFor("github.com", forge.RepoID("", "", "foo/bar"))
+	require.NotNil(t, rule)
+	require.Equal(t, 2, rule.RequiredApprovals)
+	require.True(t, rule.IsApproverAllowed("Alice"))
+	require.False(t, rule.IsApproverAllowed("bob"))
+	require.False(t, rule.IsApproverAllowed("dave"))
+	require.True(t, rule.IsRequesterAllowed("carol"))
+	require.False(t, rule.IsRequesterAllowed("alice"))
+
+	rule = p.RuleFor("github.com", forge.RepoID("", "", "foo/baz"))
+	require.NotNil(t, rule)
+	require.False(t, rule.IsApproverAllowed("mallory"))
+	require.True(t, rule.IsApproverAllowed("dave"))
+	require.True(t, rule.IsRequesterAllowed("dave"))
+
+	require.Nil(t, p.RuleFor("github.com", forge.RepoID("", "", "other/repo")))
+
+	// Repositories are matched regardless of case.
+	rule = p.RuleFor("GitHub.com", forge.RepoID("", "", "Foo/Bar"))
+	require.NotNil(t, rule)
+	require.Equal(t, 2, rule.RequiredApprovals)
+}
+
+func TestPolicy_RuleForOtherForges(t *testing.T) {
+	p, err := LoadPolicy(writePolicyFile(t, `
+rules:
+  - repos: ["gitlab:gitlab.com/foo/*"]
+    required_approvals: 3
+  - repos: ["foo/*"]
+    required_approvals: 2
+  - repos: ["gitea:gitea.com/**"]
+    required_approvals: 4
+`))
+	require.NoError(t, err)
+
+	// The patterns without provider only apply to the repositories of the GitHub instance of the server.
+	host := "github.example.com"
+	require.Equal(t, 2, p.RuleFor(host, forge.RepoID(forge.ProviderGitHub, host, "foo/bar")).RequiredApprovals)
+	require.Nil(t, p.RuleFor(host, forge.RepoID(forge.ProviderGitHub, "github.com", "foo/bar")))
+	require.Equal(t, 3, p.RuleFor(host, forge.RepoID(forge.ProviderGitLab, "gitlab.com", "foo/bar")).RequiredApprovals)
+	require.Equal(t, 4, p.RuleFor(host, forge.RepoID(forge.ProviderGitea, "gitea.com", "foo/bar")).RequiredApprovals)
+	require.Nil(t, p.RuleFor(host, forge.RepoID(forge.ProviderGitea, "gitea.example.com", "foo/bar")))
+}
+
+func TestLoadPolicy_JSON(t *testing.T) {
+	p, err := LoadPolicy(writePolicyFile(t, `{"rules":[{"repos":["foo/*"],"required_approvals":3}]}`))
+	require.NoError(t, err)
+	require.Equal(t, 3, p.RuleFor("github.com", forge.RepoID("", "", "foo/bar")).RequiredApprovals)
+}
+
+func TestLoadPolicy_Invalid(t *testing.T) {
+	for _, content := range []string{
+		`rules: [{required_approvals: 1}]`,
+		`rules: [{repos: [""]}]`,
+		`rules: [{repos: ["foo/bar"], required_approvals: -1}]`,
+		`not: [valid`,
+	} {
+		_, err := LoadPolicy(writePolicyFile(t, content))
+		require.Error(t, err, content)
+	}
+}
+
+func TestPolicyStore_Reload(t *testing.T) {
+	path := writePolicyFile(t, `rules: [{repos: ["foo/bar"], required_approvals: 2}]`)
+	ps, err := NewPolicyStore(path)
+	require.NoError(t, err)
+	require.Equal(t, 2, ps.Policy().RuleFor("github.com", forge.RepoID("", "", "foo/bar")).RequiredApprovals)
+
+	require.NoError(t, os.WriteFile(path, []byte(`rules: [{repos: ["foo/bar"], required_approvals: 3}]`), 0600))
+	require.NoError(t, ps.Reload())
+	require.Equal(t, 3, ps.Policy().RuleFor("github.com", forge.RepoID("", "", "foo/bar")).RequiredApprovals)
+
+	// An invalid file keeps the previous policy.
+	require.NoError(t, os.WriteFile(path, []byte(`not: [valid`), 0600))
+	require.Error(t, ps.Reload())
+	require.Equal(t, 3, ps.Policy().RuleFor("github.com", forge.RepoID("", "", "foo/bar")).RequiredApprovals)
+}
+
+func TestSubmitApproval_EnforcesPolicy(t *testing.T) {
+	s, wsURL := newTestServer(t, nil, RetryPolicy{RPCTimeout: time.Second})
+	ps, err := NewPolicyStore(writePolicyFile(t, testPolicy))
+	require.NoError(t, err)
+	s.policy = ps
+
+	connectFakeApprover(t, s, wsURL, "alice", []string{"foo/bar"}, respondWith(protocol.ApproveResponseSuccess))
+	connectFakeApprover(t, s, wsURL, "bob", []string{"foo/bar"}, respondWith(protocol.ApproveResponseSuccess))
+	connectFakeApprover(t, s, wsURL, "dave", []string{"foo/bar"}, respondWith(protocol.ApproveResponseSuccess))
+
+	_, _, err = s.SubmitApproval(api.ApprovalRequest{Link: testLink, Requester: "alice"})
+	require.ErrorIs(t, err, ErrRequesterNotAllowed)
+
+	// Only alice is allowed to approve, so the 2 required approvals cannot be collected.
+	result, _, err := s.SubmitApproval(api.ApprovalRequest{Link: testLink, Requester: "carol"})
+	require.ErrorIs(t, err, ErrNotEnoughApprovals)
+	require.Equal(t, 2, result.RequiredApprovals)
+	require.Equal(t, []string{"alice"}, result.Approvers)
+	require.Len(t, result.Attempts, 1)
+}
+
+func TestSubmitApproval_VerifiesRestrictedApprovers(t *testing.T) {
+	githubSrv := test.NewGithubMockServer(t, "")
+	defer githubSrv.Close()
+	githubSrv.SetPRHead("foo/bar", 1, "abc123")
+
+	s, wsURL := newTestServer(t, nil, RetryPolicy{RPCTimeout: time.Second})
+	s.githubClient = github.NewClient("server-token", githubSrv.URL(), nil)
+	ps, err := NewPolicyStore(writePolicyFile(t, `rules: [{repos: ["foo/bar"], allowed_approvers: [alice]}]`))
+	require.NoError(t, err)
+	s.policy = ps
+
+	// The client claims to be alice but alice never approved the PR.
+	connectFakeApprover(t, s, wsURL, "alice", []string{"foo/bar"}, respondWith(protocol.ApproveResponseSuccess))
+	result, _, err := s.SubmitApproval(api.ApprovalRequest{Link: testLink, Requester: "carol", HeadSHA: "abc123"})
+	require.ErrorIs(t, err, ErrNoEligibleApprover)
+	require.Empty(t, result.Approvers)
+	require.Len(t, result.Attempts, 1)
+	require.Contains(t, result.Attempts[0].Error, ErrApprovalNotVerified.Error())
+
+	githubSrv.AddReview("foo/bar", 1, "Alice", "APPROVED", "abc123")
+	result, _, err = s.SubmitApproval(api.ApprovalRequest{Link: testLink, Requester: "carol", HeadSHA: "abc123"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"alice"}, result.Approvers)
+}
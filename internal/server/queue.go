@@ -0,0 +1,223 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/clems4ever/lgtm/internal/api"
+)
+
+var (
+	// ErrQueuedRequestNotFound is returned when a queued request does not exist or has expired.
+	ErrQueuedRequestNotFound = fmt.Errorf("queued request not found")
+	// ErrNotRequester is returned when a user tries to cancel a request queued by someone else.
+	ErrNotRequester = fmt.Errorf("request was queued by another user")
+)
+
+// QueuedRequest is an approval request waiting for an approver of its repository to connect.
+type QueuedRequest struct {
+	api.ApprovalRequest
+	ID        string    `json:"id"`
+	QueuedAt  time.Time `json:"queued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// PendingQueue holds the approval requests submitted while no approver was online for their repository.
+// Requests expire after a TTL. If a path is provided, the queue is persisted on disk after every change
+// so that it survives server restarts.
+type PendingQueue struct {
+	path  string
+	ttl   time.Duration
+	clock Clock
+
+	mu       sync.Mutex
+	requests map[string]QueuedRequest
+}
+
+// NewPendingQueue creates a PendingQueue with the given TTL. If path is not empty, the requests
+// previously persisted in that file are loaded. If clock is nil, time.Now is used.
+func NewPendingQueue(path string, ttl time.Duration, clock Clock) (*PendingQueue, error) {
+	if clock == nil {
+		clock = time.Now
+	}
+	q := &PendingQueue{
+		path:     path,
+		ttl:      ttl,
+		clock:    clock,
+		requests: make(map[string]QueuedRequest),
+	}
+	if path == "" {
+		return q, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return q, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read queue file: %w", err)
+	}
+	var requests []QueuedRequest
+	if err := json.Unmarshal(data, &requests); err != nil {
+		return nil, fmt.Errorf("failed to parse queue file: %w", err)
+	}
+	for _, r := range requests {
+		q.requests[r.ID] = r
+	}
+	q.expireLocked()
+	return q, nil
+}
+
+// Enqueue adds an approval request to the queue under a new ID.
+func (q *PendingQueue) Enqueue(req api.ApprovalRequest) (QueuedRequest, error) {
+	return q.EnqueueWithID(uuid.NewString(), req)
+}
+
+// EnqueueWithID adds an approval request to the queue under the given ID.
+func (q *PendingQueue) EnqueueWithID(id string, req api.ApprovalRequest) (QueuedRequest, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.expireLocked()
+
+	now := q.clock()
+	queued := QueuedRequest{
+		ApprovalRequest: req,
+		ID:              id,
+		QueuedAt:        now,
+		ExpiresAt:       now.Add(q.ttl),
+	}
+	q.requests[queued.ID] = queued
+	if err := q.saveLocked(); err != nil {
+		delete(q.requests, queued.ID)
+		return QueuedRequest{}, err
+	}
+	return queued, nil
+}
+
+// Requeue puts back a request taken from the queue, keeping its original expiration.
+func (q *PendingQueue) Requeue(req QueuedRequest) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.requests[req.ID] = req
+	q.expireLocked()
+	return q.saveLocked()
+}
+
+// Cancel removes the queued request with the given ID. Only the requester can cancel it.
+func (q *PendingQueue) Cancel(id string, requester string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.expireLocked()
+
+	req, ok := q.requests[id]
+	if !ok {
+		return ErrQueuedRequestNotFound
+	}
+	if req.Requester != requester {
+		return ErrNotRequester
+	}
+	delete(q.requests, id)
+	return q.saveLocked()
+}
+
+// ListByRequester returns the non-expired requests queued by the requester, oldest first.
+func (q *PendingQueue) ListByRequester(requester string) []QueuedRequest {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.expireLocked()
+
+	var l []QueuedRequest
+	for _, r := range q.requests {
+		if r.Requester == requester {
+			l = append(l, r)
+		}
+	}
+	sortQueuedRequests(l)
+	return l
+}
+
+// TakeForRepos removes and returns the non-expired requests targeting one of the given repositories, identified
+// as "provider:host/owner/repo", oldest first.
+func (q *PendingQueue) TakeForRepos(repos []string) ([]QueuedRequest, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.expireLocked()
+
+	set := make(map[string]struct{}, len(repos))
+	for _, r := range repos {
+		set[r] = struct{}{}
+	}
+
+	var taken []QueuedRequest
+	for id, r := range q.requests {
+		if _, ok := set[r.Link.RepoID()]; ok {
+			taken = append(taken, r)
+			delete(q.requests, id)
+		}
+	}
+	if len(taken) == 0 {
+		return nil, nil
+	}
+	sortQueuedRequests(taken)
+	return taken, q.saveLocked()
+}
+
+// expireLocked drops the expired requests. The caller must hold the lock.
+func (q *PendingQueue) expireLocked() {
+	now := q.clock()
+	for id, r := range q.requests {
+		if !now.Before(r.ExpiresAt) {
+			delete(q.requests, id)
+		}
+	}
+}
+
+// saveLocked persists the queue on disk if a path is configured. The caller must hold the lock.
+func (q *PendingQueue) saveLocked() error {
+	if q.path == "" {
+		return nil
+	}
+	requests := make([]QueuedRequest, 0, len(q.requests))
+	for _, r := range q.requests {
+		requests = append(requests, r)
+	}
+	sortQueuedRequests(requests)
+	data, err := json.Marshal(requests)
+	if err != nil {
+		return fmt.Errorf("failed to marshal queue: %w", err)
+	}
+
+	// Write to a temporary file first so that a crash never leaves a truncated queue file.
+	tmp, err := os.CreateTemp(filepath.Dir(q.path), filepath.Base(q.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create queue file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write queue file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write queue file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), q.path); err != nil {
+		return fmt.Errorf("failed to save queue file: %w", err)
+	}
+	return nil
+}
+
+func sortQueuedRequests(l []QueuedRequest) {
+	sort.Slice(l, func(i, j int) bool {
+		if l[i].QueuedAt.Equal(l[j].QueuedAt) {
+			return l[i].ID < l[j].ID
+		}
+		return l[i].QueuedAt.Before(l[j].QueuedAt)
+	})
+}
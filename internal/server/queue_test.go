@@ -0,0 +1,73 @@
+package server
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/clems4ever/lgtm/internal/api"
+	"github.com/clems4ever/lgtm/internal/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPendingQueue_EnqueueAndTake(t *testing.T) {
+	q, err := NewPendingQueue("", time.Hour, nil)
+	require.NoError(t, err)
+
+	r1, err := q.Enqueue(api.ApprovalRequest{Link: github.PRLink{Owner: "foo", Repo: "bar", PRNumber: 1}, Requester: "alice"})
+	require.NoError(t, err)
+	_, err = q.Enqueue(api.ApprovalRequest{Link: github.PRLink{Owner: "foo", Repo: "baz", PRNumber: 2}, Requester: "alice"})
+	require.NoError(t, err)
+
+	taken, err := q.TakeForRepos([]string{"github:github.com/foo/bar"})
+	require.NoError(t, err)
+	require.Len(t, taken, 1)
+	require.Equal(t, r1.ID, taken[0].ID)
+
+	// Taken requests are removed from the queue.
+	require.Len(t, q.ListByRequester("alice"), 1)
+}
+
+func TestPendingQueue_Expiration(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	q, err := NewPendingQueue("", time.Hour, func() time.Time { return now })
+	require.NoError(t, err)
+
+	_, err = q.Enqueue(api.ApprovalRequest{Link: github.PRLink{Owner: "foo", Repo: "bar", PRNumber: 1}, Requester: "alice"})
+	require.NoError(t, err)
+	require.Len(t, q.ListByRequester("alice"), 1)
+
+	now = now.Add(time.Hour)
+	require.Empty(t, q.ListByRequester("alice"))
+	taken, err := q.TakeForRepos([]string{"github:github.com/foo/bar"})
+	require.NoError(t, err)
+	require.Empty(t, taken)
+}
+
+func TestPendingQueue_Cancel(t *testing.T) {
+	q, err := NewPendingQueue("", time.Hour, nil)
+	require.NoError(t, err)
+
+	r, err := q.Enqueue(api.ApprovalRequest{Link: github.PRLink{Owner: "foo", Repo: "bar", PRNumber: 1}, Requester: "alice"})
+	require.NoError(t, err)
+
+	require.ErrorIs(t, q.Cancel(r.ID, "bob"), ErrNotRequester)
+	require.NoError(t, q.Cancel(r.ID, "alice"))
+	require.ErrorIs(t, q.Cancel(r.ID, "alice"), ErrQueuedRequestNotFound)
+}
+
+func TestPendingQueue_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.json")
+	q, err := NewPendingQueue(path, time.Hour, nil)
+	require.NoError(t, err)
+
+	r, err := q.Enqueue(api.ApprovalRequest{Link: github.PRLink{Owner: "foo", Repo: "bar", PRNumber: 1}, Requester: "alice"})
+	require.NoError(t, err)
+
+	reloaded, err := NewPendingQueue(path, time.Hour, nil)
+	require.NoError(t, err)
+	l := reloaded.ListByRequester("alice")
+	require.Len(t, l, 1)
+	require.Equal(t, r.ID, l[0].ID)
+	require.Equal(t, r.Link, l[0].Link)
+}
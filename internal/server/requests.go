@@ -0,0 +1,225 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/clems4ever/lgtm/internal/api"
+)
+
+var (
+	// ErrRequestNotFound is returned when a request does not exist, has been forgotten or belongs to another user.
+	ErrRequestNotFound = fmt.Errorf("request not found")
+	// ErrRequestCancelled is the error of the requests cancelled by their requester while queued.
+	ErrRequestCancelled = fmt.Errorf("request cancelled by the requester")
+	// ErrRequestExpired is the error of the requests that expired in the queue.
+	ErrRequestExpired = fmt.Errorf("request expired before an approver connected")
+)
+
+const (
+	// defaultRequestRetention is how long the status of a completed request remains available.
+	defaultRequestRetention = 24 * time.Hour
+	// requestUpdatesBufferSize is the number of status updates buffered per subscriber. The subscribers that do
+	// not keep up are dropped and their channel closed rather than missing updates.
+	requestUpdatesBufferSize = 64
+)
+
+// RequestTracker keeps the status of the submitted approval requests in memory.
+// Completed requests are forgotten after a retention period.
+type RequestTracker struct {
+	retention time.Duration
+	clock     Clock
+
+	mu       sync.Mutex
+	requests map[string]*api.RequestStatus
+	// subscribers maps the channels of the subscribers to the requester whose requests they follow.
+	subscribers map[chan api.RequestStatus]string
+}
+
+// NewRequestTracker creates a RequestTracker. If clock is nil, time.Now is used.
+func NewRequestTracker(retention time.Duration, clock Clock) *RequestTracker {
+	if clock == nil {
+		clock = time.Now
+	}
+	return &RequestTracker{
+		retention:   retention,
+		clock:       clock,
+		requests:    make(map[string]*api.RequestStatus),
+		subscribers: make(map[chan api.RequestStatus]string),
+	}
+}
+
+// Subscribe returns a channel receiving the status of the requests of the requester every time it changes,
+// and a function to unsubscribe. The channel is closed if the subscriber does not keep up with the updates,
+// it can then subscribe again and catch up with List.
+func (rt *RequestTracker) Subscribe(requester string) (<-chan api.RequestStatus, func()) {
+	ch := make(chan api.RequestStatus, requestUpdatesBufferSize)
+	rt.mu.Lock()
+	rt.subscribers[ch] = requester
+	rt.mu.Unlock()
+
+	return ch, func() {
+		rt.mu.Lock()
+		delete(rt.subscribers, ch)
+		rt.mu.Unlock()
+	}
+}
+
+// Get returns the status of the request if it was submitted by the given requester.
+func (rt *RequestTracker) Get(id, requester string) (api.RequestStatus, error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.expireLocked()
+
+	status, ok := rt.requests[id]
+	if !ok || status.Requester != requester {
+		return api.RequestStatus{}, ErrRequestNotFound
+	}
+	return cloneStatus(status), nil
+}
+
+// List returns the status of the requests of the given requester, oldest first.
+func (rt *RequestTracker) List(requester string) []api.RequestStatus {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.expireLocked()
+
+	statuses := []api.RequestStatus{}
+	for _, status := range rt.requests {
+		if status.Requester == requester {
+			statuses = append(statuses, cloneStatus(status))
+		}
+	}
+	slices.SortFunc(statuses, func(a, b api.RequestStatus) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return statuses
+}
+
+// Routing records that the request is being routed. The request is tracked if it was not already.
+func (rt *RequestTracker) Routing(id string, req api.ApprovalRequest) api.RequestStatus {
+	return rt.update(id, req, func(status *api.RequestStatus) {
+		status.State = api.RequestStateRouting
+		status.ExpiresAt = nil
+	})
+}
+
+// Queued records that the request waits in the queue until the given expiration.
+func (rt *RequestTracker) Queued(id string, req api.ApprovalRequest, expiresAt time.Time) api.RequestStatus {
+	return rt.update(id, req, func(status *api.RequestStatus) {
+		status.State = api.RequestStateQueued
+		status.CurrentApprover = ""
+		status.ExpiresAt = &expiresAt
+	})
+}
+
+// Progress records the routing progress of the request and the approver it is currently forwarded to.
+func (rt *RequestTracker) Progress(id string, req api.ApprovalRequest, result ApprovalResult, currentApprover string) api.RequestStatus {
+	return rt.update(id, req, func(status *api.RequestStatus) {
+		status.RequiredApprovals = result.RequiredApprovals
+		status.Approvers = append([]string{}, result.Approvers...)
+		status.Attempts = append([]api.RoutingAttempt{}, result.Attempts...)
+		status.CurrentApprover = currentApprover
+	})
+}
+
+// Complete records the outcome of the request. It is approved if err is nil, failed otherwise.
+func (rt *RequestTracker) Complete(id string, req api.ApprovalRequest, err error) api.RequestStatus {
+	return rt.update(id, req, func(status *api.RequestStatus) {
+		now := rt.clock()
+		if err != nil {
+			rt.failLocked(status, err, now)
+			return
+		}
+		status.State = api.RequestStateApproved
+		status.CurrentApprover = ""
+		status.ExpiresAt = nil
+		status.CompletedAt = &now
+	})
+}
+
+// Cancel records that the request was cancelled while queued. Untracked requests are ignored.
+func (rt *RequestTracker) Cancel(id string) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if status, ok := rt.requests[id]; ok {
+		rt.failLocked(status, ErrRequestCancelled, rt.clock())
+		rt.publishLocked(status)
+	}
+}
+
+// update applies the change to the status of the request, creating it if needed, and returns a copy of the result.
+func (rt *RequestTracker) update(id string, req api.ApprovalRequest, change func(status *api.RequestStatus)) api.RequestStatus {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.expireLocked()
+
+	now := rt.clock()
+	status, ok := rt.requests[id]
+	if !ok {
+		status = &api.RequestStatus{
+			ApprovalRequest: req,
+			ID:              id,
+			URL:             req.Link.String(),
+			Approvers:       []string{},
+			Attempts:        []api.RoutingAttempt{},
+			CreatedAt:       now,
+		}
+		rt.requests[id] = status
+	}
+	change(status)
+	status.UpdatedAt = now
+	rt.publishLocked(status)
+	return cloneStatus(status)
+}
+
+// expireLocked fails the requests that expired in the queue and forgets the requests completed for longer than
+// the retention period. The caller must hold the lock.
+func (rt *RequestTracker) expireLocked() {
+	now := rt.clock()
+	for id, status := range rt.requests {
+		if status.State == api.RequestStateQueued && status.ExpiresAt != nil && !now.Before(*status.ExpiresAt) {
+			rt.failLocked(status, ErrRequestExpired, *status.ExpiresAt)
+			rt.publishLocked(status)
+		}
+		if status.CompletedAt != nil && now.Sub(*status.CompletedAt) >= rt.retention {
+			delete(rt.requests, id)
+		}
+	}
+}
+
+// failLocked marks the request as failed at the given time. The caller must hold the lock.
+func (rt *RequestTracker) failLocked(status *api.RequestStatus, err error, at time.Time) {
+	status.State = api.RequestStateFailed
+	status.Error = err.Error()
+	status.CurrentApprover = ""
+	status.ExpiresAt = nil
+	status.CompletedAt = &at
+	status.UpdatedAt = at
+}
+
+// publishLocked sends a copy of the status to the subscribers following its requester without blocking.
+// The subscribers whose buffer is full are dropped. The caller must hold the lock.
+func (rt *RequestTracker) publishLocked(status *api.RequestStatus) {
+	for ch, requester := range rt.subscribers {
+		if requester != status.Requester {
+			continue
+		}
+		select {
+		case ch <- cloneStatus(status):
+		default:
+			delete(rt.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// cloneStatus returns a copy of the status that does not share its slices with the original.
+func cloneStatus(rs *api.RequestStatus) api.RequestStatus {
+	c := *rs
+	c.Approvers = append([]string{}, rs.Approvers...)
+	c.Attempts = append([]api.RoutingAttempt{}, rs.Attempts...)
+	return c
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/clems4ever/lgtm/internal/api"
+)
+
+func TestRequestTracker(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	rt := NewRequestTracker(time.Hour, func() time.Time { return now })
+	req := api.ApprovalRequest{Link: testLink, Requester: "alice", RequiredApprovals: 2}
+
+	status := rt.Routing("r1", req)
+	require.Equal(t, api.RequestStateRouting, status.State)
+	require.Equal(t, now, status.CreatedAt)
+
+	rt.Progress("r1", req, ApprovalResult{RequiredApprovals: 2, Approvers: []string{"bob"}}, "carol")
+	status, err := rt.Get("r1", "alice")
+	require.NoError(t, err)
+	require.Equal(t, []string{"bob"}, status.Approvers)
+	require.Equal(t, "carol", status.CurrentApprover)
+
+	now = now.Add(time.Minute)
+	status = rt.Complete("r1", req, errors.New("not enough approvals"))
+	require.Equal(t, api.RequestStateFailed, status.State)
+	require.Equal(t, "not enough approvals", status.Error)
+	require.Empty(t, status.CurrentApprover)
+	require.Equal(t, now, *status.CompletedAt)
+
+	// Completed requests are forgotten after the retention period.
+	now = now.Add(time.Hour)
+	_, err = rt.Get("r1", "alice")
+	require.ErrorIs(t, err, ErrRequestNotFound)
+}
+
+func TestRequestTracker_QueuedRequests(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	rt := NewRequestTracker(time.Hour, func() time.Time { return now })
+	req := api.ApprovalRequest{Link: testLink, Requester: "alice"}
+
+	rt.Queued("r1", req, now.Add(time.Minute))
+	rt.Queued("r2", req, now.Add(time.Minute))
+	rt.Cancel("r2")
+	status, err := rt.Get("r2", "alice")
+	require.NoError(t, err)
+	require.Equal(t, api.RequestStateFailed, status.State)
+	require.Equal(t, ErrRequestCancelled.Error(), status.Error)
+
+	// A request expiring in the queue fails.
+	now = now.Add(time.Minute)
+	status, err = rt.Get("r1", "alice")
+	require.NoError(t, err)
+	require.Equal(t, api.RequestStateFailed, status.State)
+	require.Equal(t, ErrRequestExpired.Error(), status.Error)
+}
+
+func TestRequestTracker_Subscribe(t *testing.T) {
+	rt := NewRequestTracker(time.Hour, nil)
+	updates, unsubscribe := rt.Subscribe("alice")
+
+	rt.Routing("r1", api.ApprovalRequest{Link: testLink, Requester: "bob"})
+	rt.Routing("r2", api.ApprovalRequest{Link: testLink, Requester: "alice"})
+
+	// Only the updates of the requests of the subscriber are received.
+	status := <-updates
+	require.Equal(t, "r2", status.ID)
+	require.Equal(t, api.RequestStateRouting, status.State)
+	require.Empty(t, updates)
+
+	unsubscribe()
+	rt.Complete("r2", api.ApprovalRequest{Link: testLink, Requester: "alice"}, nil)
+	require.Empty(t, updates)
+}
+
+func TestRequestTracker_SlowSubscriberIsDropped(t *testing.T) {
+	rt := NewRequestTracker(time.Hour, nil)
+	updates, unsubscribe := rt.Subscribe("alice")
+	defer unsubscribe()
+
+	req := api.ApprovalRequest{Link: testLink, Requester: "alice"}
+	for i := 0; i <= requestUpdatesBufferSize; i++ {
+		rt.Progress("r1", req, ApprovalResult{RequiredApprovals: 1}, "bob")
+	}
+
+	// The buffered updates are delivered, then the channel is closed instead of missing the last update.
+	for range requestUpdatesBufferSize {
+		_, ok := <-updates
+		require.True(t, ok)
+	}
+	_, ok := <-updates
+	require.False(t, ok)
+}
+
+func TestRequestTracker_List(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	rt := NewRequestTracker(time.Hour, func() time.Time { return now })
+	rt.Routing("r1", api.ApprovalRequest{Link: testLink, Requester: "alice"})
+	now = now.Add(time.Second)
+	rt.Routing("r2", api.ApprovalRequest{Link: testLink, Requester: "bob"})
+	now = now.Add(time.Second)
+	rt.Complete("r3", api.ApprovalRequest{Link: testLink, Requester: "alice"}, nil)
+
+	statuses := rt.List("alice")
+	require.Len(t, statuses, 2)
+	require.Equal(t, "r1", statuses[0].ID)
+	require.Equal(t, "r3", statuses[1].ID)
+	require.Equal(t, testLink.String(), statuses[0].URL)
+}
@@ -0,0 +1,199 @@
+package server
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RoutingStrategy identifies one of the built-in Router implementations.
+type RoutingStrategy string
+
+const (
+	// RoutingStrategyRoundRobin cycles through the eligible approvers of a repository.
+	RoutingStrategyRoundRobin RoutingStrategy = "round-robin"
+	// RoutingStrategyLeastRecentlyUsed picks the approver who was selected the longest time ago.
+	RoutingStrategyLeastRecentlyUsed RoutingStrategy = "least-recently-used"
+	// RoutingStrategyLeastLoaded picks the approver with the fewest in-flight requests.
+	RoutingStrategyLeastLoaded RoutingStrategy = "least-loaded"
+	// RoutingStrategyWeighted picks an approver randomly, proportionally to its weight.
+	RoutingStrategyWeighted RoutingStrategy = "weighted"
+)
+
+// Clock returns the current time. It is injectable so that routing stays deterministic in tests.
+type Clock func() time.Time
+
+// Router selects which client should receive an approval request among the eligible ones.
+type Router interface {
+	// Select returns the client that should handle the request for the given repository.
+	// candidates is never empty.
+	Select(repo string, candidates []*clientInfo) *clientInfo
+}
+
+// NewRouter builds the Router implementing the given strategy.
+// weights is only used by the weighted strategy and maps a GitHub user to its weight.
+// clock and rnd may be nil, in which case time.Now and a time-seeded source are used.
+func NewRouter(strategy RoutingStrategy, weights map[string]int, clock Clock, rnd *rand.Rand) (Router, error) {
+	if clock == nil {
+		clock = time.Now
+	}
+	if rnd == nil {
+		rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	switch strategy {
+	case RoutingStrategyRoundRobin, "":
+		return NewRoundRobinRouter(), nil
+	case RoutingStrategyLeastRecentlyUsed:
+		return NewLeastRecentlyUsedRouter(clock), nil
+	case RoutingStrategyLeastLoaded:
+		return NewLeastLoadedRouter(), nil
+	case RoutingStrategyWeighted:
+		return NewWeightedRouter(weights, rnd), nil
+	}
+	return nil, fmt.Errorf("unknown routing strategy %q", strategy)
+}
+
+// ParseRoutingWeights parses a comma-separated list of user=weight pairs (e.g. "alice=3,bob=1").
+func ParseRoutingWeights(s string) (map[string]int, error) {
+	weights, err := parseIntPairs(s, 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid routing weights: %w", err)
+	}
+	return weights, nil
+}
+
+// sortedCandidates returns a copy of the candidates sorted by GitHub user so that the
+// selection does not depend on the order clients registered in.
+func sortedCandidates(candidates []*clientInfo) []*clientInfo {
+	sorted := make([]*clientInfo, len(candidates))
+	copy(sorted, candidates)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].githubUser < sorted[j].githubUser
+	})
+	return sorted
+}
+
+// RoundRobinRouter cycles through the eligible approvers of each repository in turn.
+type RoundRobinRouter struct {
+	mu   sync.Mutex
+	next map[string]int // repo -> index of the next approver to pick
+}
+
+// NewRoundRobinRouter creates a new RoundRobinRouter.
+func NewRoundRobinRouter() *RoundRobinRouter {
+	return &RoundRobinRouter{next: make(map[string]int)}
+}
+
+// Select implements Router.
+func (r *RoundRobinRouter) Select(repo string, candidates []*clientInfo) *clientInfo {
+	sorted := sortedCandidates(candidates)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	idx := r.next[repo] % len(sorted)
+	r.next[repo] = idx + 1
+	return sorted[idx]
+}
+
+// LeastRecentlyUsedRouter picks the approver who was selected the longest time ago.
+// Approvers who have never been selected are preferred.
+type LeastRecentlyUsedRouter struct {
+	clock Clock
+
+	mu       sync.Mutex
+	lastUsed map[string]time.Time // github user -> last time it was selected
+}
+
+// NewLeastRecentlyUsedRouter creates a new LeastRecentlyUsedRouter using the given clock.
+func NewLeastRecentlyUsedRouter(clock Clock) *LeastRecentlyUsedRouter {
+	return &LeastRecentlyUsedRouter{
+		clock:    clock,
+		lastUsed: make(map[string]time.Time),
+	}
+}
+
+// Select implements Router.
+func (r *LeastRecentlyUsedRouter) Select(repo string, candidates []*clientInfo) *clientInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var selected *clientInfo
+	var selectedTime time.Time
+	for _, c := range sortedCandidates(candidates) {
+		t := r.lastUsed[c.githubUser]
+		if selected == nil || t.Before(selectedTime) {
+			selected = c
+			selectedTime = t
+		}
+	}
+	r.lastUsed[selected.githubUser] = r.clock()
+	return selected
+}
+
+// LeastLoadedRouter picks the approver with the fewest in-flight approval requests.
+type LeastLoadedRouter struct{}
+
+// NewLeastLoadedRouter creates a new LeastLoadedRouter.
+func NewLeastLoadedRouter() *LeastLoadedRouter {
+	return &LeastLoadedRouter{}
+}
+
+// Select implements Router.
+func (r *LeastLoadedRouter) Select(repo string, candidates []*clientInfo) *clientInfo {
+	var selected *clientInfo
+	var selectedLoad int64
+	for _, c := range sortedCandidates(candidates) {
+		load := c.inFlight.Load()
+		if selected == nil || load < selectedLoad {
+			selected = c
+			selectedLoad = load
+		}
+	}
+	return selected
+}
+
+// WeightedRouter picks an approver randomly with a probability proportional to its weight.
+// Users without an explicit weight have a weight of 1.
+type WeightedRouter struct {
+	weights map[string]int
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// NewWeightedRouter creates a new WeightedRouter with the given weights and random source.
+func NewWeightedRouter(weights map[string]int, rnd *rand.Rand) *WeightedRouter {
+	return &WeightedRouter{weights: weights, rnd: rnd}
+}
+
+func (r *WeightedRouter) weight(user string) int {
+	w, ok := r.weights[user]
+	if !ok {
+		return 1
+	}
+	return w
+}
+
+// Select implements Router.
+func (r *WeightedRouter) Select(repo string, candidates []*clientInfo) *clientInfo {
+	sorted := sortedCandidates(candidates)
+	total := 0
+	for _, c := range sorted {
+		total += r.weight(c.githubUser)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// If every candidate has a weight of zero, fall back to a uniform selection.
+	if total == 0 {
+		return sorted[r.rnd.Intn(len(sorted))]
+	}
+	n := r.rnd.Intn(total)
+	for _, c := range sorted {
+		n -= r.weight(c.githubUser)
+		if n < 0 {
+			return c
+		}
+	}
+	return sorted[len(sorted)-1]
+}
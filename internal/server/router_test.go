@@ -0,0 +1,126 @@
+package server
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestClients(users ...string) []*clientInfo {
+	clients := make([]*clientInfo, 0, len(users))
+	for _, u := range users {
+		clients = append(clients, &clientInfo{githubUser: u, repos: make(map[string]struct{})})
+	}
+	return clients
+}
+
+func TestRoundRobinRouter(t *testing.T) {
+	r := NewRoundRobinRouter()
+	clients := newTestClients("carol", "alice", "bob")
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, r.Select("foo/bar", clients).githubUser)
+	}
+	want := []string{"alice", "bob", "carol", "alice"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("round robin selection = %v, want %v", got, want)
+		}
+	}
+
+	// Each repository has its own cursor.
+	if u := r.Select("foo/baz", clients).githubUser; u != "alice" {
+		t.Errorf("expected alice for a new repo, got %s", u)
+	}
+}
+
+func TestLeastRecentlyUsedRouter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+	r := NewLeastRecentlyUsedRouter(clock)
+	clients := newTestClients("alice", "bob")
+
+	if u := r.Select("foo/bar", clients).githubUser; u != "alice" {
+		t.Fatalf("expected alice, got %s", u)
+	}
+	if u := r.Select("foo/bar", clients).githubUser; u != "bob" {
+		t.Fatalf("expected bob, got %s", u)
+	}
+	// A newly connected approver has never been used and must be preferred.
+	clients = append(clients, newTestClients("carol")...)
+	if u := r.Select("foo/bar", clients).githubUser; u != "carol" {
+		t.Fatalf("expected carol, got %s", u)
+	}
+	if u := r.Select("foo/bar", clients).githubUser; u != "alice" {
+		t.Fatalf("expected alice, got %s", u)
+	}
+}
+
+func TestLeastLoadedRouter(t *testing.T) {
+	r := NewLeastLoadedRouter()
+	clients := newTestClients("alice", "bob", "carol")
+	clients[0].inFlight.Store(2)
+	clients[1].inFlight.Store(1)
+	clients[2].inFlight.Store(3)
+
+	if u := r.Select("foo/bar", clients).githubUser; u != "bob" {
+		t.Fatalf("expected bob, got %s", u)
+	}
+}
+
+func TestWeightedRouter(t *testing.T) {
+	r := NewWeightedRouter(map[string]int{"alice": 0, "bob": 3}, rand.New(rand.NewSource(1)))
+	clients := newTestClients("alice", "bob", "carol")
+
+	counts := make(map[string]int)
+	for i := 0; i < 400; i++ {
+		counts[r.Select("foo/bar", clients).githubUser]++
+	}
+	if counts["alice"] != 0 {
+		t.Errorf("alice has a weight of zero but was selected %d times", counts["alice"])
+	}
+	if counts["bob"] <= counts["carol"] {
+		t.Errorf("expected bob to be selected more often than carol, got %v", counts)
+	}
+}
+
+func TestNewRouterUnknownStrategy(t *testing.T) {
+	if _, err := NewRouter("unknown", nil, nil, nil); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestParseRoutingWeights(t *testing.T) {
+	weights, err := ParseRoutingWeights("alice=3, bob=1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if weights["alice"] != 3 || weights["bob"] != 1 {
+		t.Errorf("unexpected weights %v", weights)
+	}
+
+	for _, in := range []string{"alice", "=3", "alice=x", "alice=-1"} {
+		if _, err := ParseRoutingWeights(in); err == nil {
+			t.Errorf("ParseRoutingWeights(%q) expected error, got nil", in)
+		}
+	}
+}
+
+func TestParseRequiredApprovals(t *testing.T) {
+	counts, err := ParseRequiredApprovals("Foo/Bar=2,gitlab:gitlab.com/foo/bar=3", "github.example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]int{"github:github.example.com/foo/bar": 2, "gitlab:gitlab.com/foo/bar": 3}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("unexpected counts %v", counts)
+	}
+	if _, err := ParseRequiredApprovals("foo/bar=0", "github.com"); err == nil {
+		t.Error("expected error for a count of zero, got nil")
+	}
+}
@@ -0,0 +1,436 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/clems4ever/lgtm/internal/api"
+	"github.com/clems4ever/lgtm/internal/forge"
+	"github.com/clems4ever/lgtm/internal/protocol"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNoEligibleApprover is returned when no eligible approver is found for a PR.
+	ErrNoEligibleApprover = fmt.Errorf("no eligible approver")
+	// ErrRoutingDeadlineExceeded is returned when the total routing deadline expired before the PR got approved.
+	ErrRoutingDeadlineExceeded = fmt.Errorf("routing deadline exceeded")
+	// ErrMaxAttemptsReached is returned when the maximum number of approvers have been tried without success.
+	ErrMaxAttemptsReached = fmt.Errorf("maximum number of attempts reached")
+	// ErrNotEnoughApprovals is returned when some but not all of the required approvals could be collected.
+	ErrNotEnoughApprovals = fmt.Errorf("not enough approvals")
+	// ErrHeadMoved is returned when new commits were pushed to the PR after the approval was requested.
+	ErrHeadMoved = fmt.Errorf("pull request head moved")
+)
+
+const (
+	defaultRPCTimeout      = 10 * time.Second
+	defaultRoutingDeadline = 30 * time.Second
+)
+
+// RetryPolicy controls how an approval request falls through to the next approver when an attempt fails.
+type RetryPolicy struct {
+	// RPCTimeout is the maximum time to wait for a single approver to answer.
+	RPCTimeout time.Duration
+	// Deadline is the maximum total time spent routing a request across all attempts. Zero means no deadline.
+	Deadline time.Duration
+	// MaxAttempts is the maximum number of approvers to try. Zero means every eligible approver can be tried.
+	MaxAttempts int
+}
+
+// DefaultRetryPolicy returns the retry policy used when none is configured.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		RPCTimeout: defaultRPCTimeout,
+		Deadline:   defaultRoutingDeadline,
+	}
+}
+
+// ApprovalResult summarizes the routing of an approval request.
+type ApprovalResult struct {
+	// RequiredApprovals is the number of distinct approvals the request needed.
+	RequiredApprovals int `json:"required_approvals"`
+	// Approvers lists the GitHub users who approved the PR, in order.
+	Approvers []string `json:"approvers"`
+	// Attempts lists every approver the request was forwarded to, in order.
+	Attempts []api.RoutingAttempt `json:"attempts"`
+}
+
+// RequestApproval forwards a pull request approval request to eligible approvers until the required number
+// of distinct GitHub users approved it.
+// Approvers are selected by the server's router among the connected clients registered for the target repository
+// and allowed by the policy. Depending on the CODEOWNERS mode, the owners of the changed paths are preferred or required.
+// If an attempt fails (same author, approver-side error, timeout or disconnection), the approver is excluded
+// and the next one is tried, as long as the retry policy allows it. Approvers who approved are excluded as well
+// so that every approval comes from a distinct user.
+// The result is returned even on error so that the caller can report partial progress. If no eligible approver
+// is left, the error wraps ErrNoEligibleApprover. If some but not all approvals were collected, the error also
+// wraps ErrNotEnoughApprovals. If the request is pinned to a head commit and an approver reports that the head
+// moved, the routing stops with an error wrapping ErrHeadMoved. If the checks policy is enabled and the checks
+// of the PR are not green, no approver is tried and the error wraps ErrChecksNotGreen.
+func (s *Server) RequestApproval(req api.ApprovalRequest) (ApprovalResult, error) {
+	return s.routeApproval(req, nil)
+}
+
+// routingProgress is notified before and after every attempt of the routing of a request.
+// current is the approver being tried, empty once the attempt is over.
+type routingProgress func(result ApprovalResult, current string)
+
+// routeApproval implements RequestApproval, notifying progress if it is not nil.
+func (s *Server) routeApproval(req api.ApprovalRequest, progress routingProgress) (ApprovalResult, error) {
+	if progress == nil {
+		progress = func(ApprovalResult, string) {}
+	}
+	link := req.Link
+	fmt.Println("need to forward approval link:", link)
+	targetRepo := link.RepoID()
+	result := ApprovalResult{RequiredApprovals: max(req.RequiredApprovals, 1)}
+
+	if err := s.ensureChecksGreen(req); err != nil {
+		return result, err
+	}
+
+	s.mu.Lock()
+	eligible := []*clientInfo{}
+	eligible = append(eligible, s.clientsByRepo[targetRepo]...)
+	s.mu.Unlock()
+
+	// Only keep the approvers allowed by the policy
+	rule := s.policyRule(targetRepo)
+	if rule != nil {
+		eligible = slices.DeleteFunc(eligible, func(c *clientInfo) bool {
+			return !rule.IsApproverAllowed(c.githubUser)
+		})
+	}
+
+	// Restrict or order the approvers according to the code owners of the changed paths
+	groups, err := s.codeownersFilter(link)
+	if err != nil {
+		return result, fmt.Errorf("failed to resolve code owners: %w", err)
+	}
+	requireOwners := groups != nil && s.codeownersMode == CodeownersModeRequire
+	if requireOwners {
+		eligible = filterOwners(eligible, ownersOf(groups))
+	}
+
+	var deadline time.Time
+	if s.retryPolicy.Deadline > 0 {
+		deadline = time.Now().Add(s.retryPolicy.Deadline)
+	}
+
+	// When code owners are required, every group of changed paths needs the approval of one of its owners
+	// in addition to the required number of approvals.
+	for len(result.Approvers) < result.RequiredApprovals || (requireOwners && len(uncoveredGroups(groups, result.Approvers)) > 0) {
+		if len(eligible) == 0 {
+			fmt.Printf("no eligible approver for %s\n", link)
+			if len(result.Attempts) == 0 {
+				return result, ErrNoEligibleApprover
+			}
+			return result, result.progressError(fmt.Errorf("%w left after %d attempt(s)", ErrNoEligibleApprover, len(result.Attempts)))
+		}
+		if s.retryPolicy.MaxAttempts > 0 && len(result.Attempts) >= s.retryPolicy.MaxAttempts {
+			return result, result.progressError(fmt.Errorf("%w (%d)", ErrMaxAttemptsReached, len(result.Attempts)))
+		}
+
+		timeout := s.retryPolicy.RPCTimeout
+		if !deadline.IsZero() {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				return result, result.progressError(fmt.Errorf("%w after %d attempt(s)", ErrRoutingDeadlineExceeded, len(result.Attempts)))
+			}
+			timeout = min(timeout, remaining)
+		}
+
+		// The owners of the paths no approver owns yet are tried first, then the other code owners
+		candidates := eligible
+		if groups != nil {
+			uncovered := uncoveredGroups(groups, result.Approvers)
+			preferred := filterOwners(eligible, ownersOf(uncovered))
+			switch {
+			case len(preferred) > 0:
+				candidates = preferred
+			case requireOwners && len(uncovered) > 0:
+				err := fmt.Errorf("%w among the owners of the paths owned by %s", ErrNoEligibleApprover, strings.Join(uncovered[0].Owners, " "))
+				return result, result.progressError(err)
+			default:
+				if preferred := filterOwners(eligible, ownersOf(groups)); len(preferred) > 0 {
+					candidates = preferred
+				}
+			}
+		}
+
+		selected := s.router.Select(targetRepo, candidates)
+		fmt.Printf("%s will tentatively be approved by %s\n", link, selected.githubUser)
+		progress(result, selected.githubUser)
+
+		attempt := s.attemptApproval(req, selected, timeout)
+		if attempt.Response == protocol.ApproveResponseSuccess && rule.restrictsApprovers() {
+			// The policy must not trust the login the client declared when registering.
+			if err := s.verifyApproval(req, selected.githubUser); err != nil {
+				attempt.Response = protocol.ApproveResponseErrFailed
+				attempt.Error = err.Error()
+			}
+		}
+		result.Attempts = append(result.Attempts, attempt)
+		if attempt.Response == protocol.ApproveResponseSuccess {
+			fmt.Printf("%s approved by %s (%d/%d)\n", link, selected.githubUser, len(result.Approvers)+1, result.RequiredApprovals)
+			result.Approvers = append(result.Approvers, selected.githubUser)
+		}
+		progress(result, "")
+		if attempt.Response != protocol.ApproveResponseSuccess {
+			fmt.Printf("%s not approved by %s: %s\n", link, selected.githubUser, attempt.Error)
+			// No other approver will approve the requested commit either
+			if attempt.Rejection != nil && attempt.Rejection.Code == protocol.RejectionHeadMoved {
+				return result, result.progressError(fmt.Errorf("%w: %s", ErrHeadMoved, attempt.Rejection.Message))
+			}
+		}
+
+		// Exclude the approver, whether it failed or already approved, and try the next one
+		eligible = excludeApprover(eligible, selected.githubUser)
+	}
+	return result, nil
+}
+
+// progressError wraps the error that stopped the routing with the approvals collected so far, if any.
+func (r ApprovalResult) progressError(err error) error {
+	if len(r.Approvers) == 0 {
+		return err
+	}
+	return fmt.Errorf("%w: %d of %d approvals collected: %w", ErrNotEnoughApprovals, len(r.Approvers), r.RequiredApprovals, err)
+}
+
+// SubmitApproval forwards the approval request like RequestApproval. If no approver is online for the
+// repository and the pending queue is enabled, the request is queued on behalf of the requester instead
+// and delivered once an approver of the repository registers.
+// The number of required approvals is raised to the one configured for the repository if it is lower.
+// If the policy does not allow the requester to submit PRs of the repository, ErrRequesterNotAllowed is returned.
+// The progress of the request is tracked and can be read with the request tracker.
+func (s *Server) SubmitApproval(req api.ApprovalRequest) (ApprovalResult, *QueuedRequest, error) {
+	req, err := s.prepareSubmission(req)
+	if err != nil {
+		return ApprovalResult{}, nil, err
+	}
+	return s.routeOrQueue(s.requests.Routing(uuid.NewString(), req))
+}
+
+// SubmitApprovalAsync checks the approval request like SubmitApproval and routes it in the background.
+// It returns the initial status of the request right away, its progress can be read with the request tracker.
+func (s *Server) SubmitApprovalAsync(req api.ApprovalRequest) (api.RequestStatus, error) {
+	req, err := s.prepareSubmission(req)
+	if err != nil {
+		return api.RequestStatus{}, err
+	}
+	status := s.requests.Routing(uuid.NewString(), req)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		_, _, err := s.routeOrQueue(status)
+		if err != nil {
+			log.Printf("failed to approve request %s (%s): %s", status.ID, req.Link, err)
+		}
+	}()
+	return status, nil
+}
+
+// prepareSubmission checks that the requester may submit the request and raises its number of required approvals
+// to the one configured for the repository.
+func (s *Server) prepareSubmission(req api.ApprovalRequest) (api.ApprovalRequest, error) {
+	repo := req.Link.RepoID()
+	if rule := s.policyRule(repo); rule != nil && !rule.IsRequesterAllowed(req.Requester) {
+		return req, fmt.Errorf("%w to request approvals for %s", ErrRequesterNotAllowed, req.Link.RepoFullName())
+	}
+	req.RequiredApprovals = s.requiredApprovals(repo, req.RequiredApprovals)
+	return req, nil
+}
+
+// routeOrQueue routes the request whose routing was recorded with the given status and tracks its progress
+// under the same ID. If no approver is online and the pending queue is enabled, the request is queued under
+// the same ID.
+func (s *Server) routeOrQueue(status api.RequestStatus) (ApprovalResult, *QueuedRequest, error) {
+	id, req := status.ID, status.ApprovalRequest
+	result, err := s.routeApproval(req, func(result ApprovalResult, current string) {
+		s.requests.Progress(id, req, result, current)
+	})
+	if s.queue == nil || len(result.Attempts) > 0 || !errors.Is(err, ErrNoEligibleApprover) {
+		s.requests.Complete(id, req, err)
+		return result, nil, err
+	}
+
+	queued, err := s.queue.EnqueueWithID(id, req)
+	if err != nil {
+		err = fmt.Errorf("failed to queue request: %w", err)
+		s.requests.Complete(id, req, err)
+		return result, nil, err
+	}
+	s.requests.Queued(id, req, queued.ExpiresAt)
+	fmt.Printf("%s queued until %s\n", req.Link, queued.ExpiresAt.Format(time.RFC3339))
+	return result, &queued, nil
+}
+
+// requiredApprovals returns the number of approvals needed for a request on the given repository, given by its ID.
+// The repository settings, from the command line and the policy, act as a floor that a request can raise but not lower.
+func (s *Server) requiredApprovals(repoID string, requested int) int {
+	var fromPolicy int
+	if rule := s.policyRule(repoID); rule != nil {
+		fromPolicy = rule.RequiredApprovals
+	}
+	return max(requested, s.requiredApprovalsByRepo[strings.ToLower(repoID)], fromPolicy, 1)
+}
+
+// RepoApprovers returns the online approvers of the repository, given as "owner/repo" on the GitHub instance of the
+// server, allowed by the policy, sorted and without duplicates.
+func (s *Server) RepoApprovers(repo string) []string {
+	repoID := forge.RepoID(forge.ProviderGitHub, s.githubHost, repo)
+	s.mu.Lock()
+	clients := append([]*clientInfo{}, s.clientsByRepo[repoID]...)
+	s.mu.Unlock()
+
+	rule := s.policyRule(repoID)
+	approvers := []string{}
+	for _, c := range clients {
+		if rule != nil && !rule.IsApproverAllowed(c.githubUser) {
+			continue
+		}
+		approvers = append(approvers, c.githubUser)
+	}
+	slices.Sort(approvers)
+	return slices.Compact(approvers)
+}
+
+// deliverQueuedRequests routes the queued requests targeting one of the given "provider:host/owner/repo" repositories.
+// It is called when a client registers as an approver for those repositories.
+func (s *Server) deliverQueuedRequests(repos []string) {
+	if s.queue == nil {
+		return
+	}
+	requests, err := s.queue.TakeForRepos(repos)
+	if err != nil {
+		log.Printf("failed to update queue: %s", err)
+	}
+
+	for _, req := range requests {
+		s.wg.Add(1)
+		go func(req QueuedRequest) {
+			defer s.wg.Done()
+			// Keep the attempts of the previous deliveries of a requeued request in its status.
+			previous := slices.Clip(s.requests.Routing(req.ID, req.ApprovalRequest).Attempts)
+			result, err := s.routeApproval(req.ApprovalRequest, func(result ApprovalResult, current string) {
+				result.Attempts = append(previous, result.Attempts...)
+				s.requests.Progress(req.ID, req.ApprovalRequest, result, current)
+			})
+			if isTransientFailure(result, err) {
+				// No approver could be reached, keep waiting for another one until the request expires.
+				log.Printf("queued request %s (%s) not delivered, requeuing it: %s", req.ID, req.Link, err)
+				if err := s.queue.Requeue(req); err != nil {
+					log.Printf("failed to requeue request %s: %s", req.ID, err)
+					err = fmt.Errorf("failed to requeue request: %w", err)
+					s.requests.Complete(req.ID, req.ApprovalRequest, err)
+					s.notifyQueuedOutcome(req, result, err)
+					return
+				}
+				s.requests.Queued(req.ID, req.ApprovalRequest, req.ExpiresAt)
+				return
+			}
+			s.requests.Complete(req.ID, req.ApprovalRequest, err)
+			s.notifyQueuedOutcome(req, result, err)
+			if err != nil {
+				log.Printf("failed to approve queued request %s (%s): %s", req.ID, req.Link, err)
+				return
+			}
+			log.Printf("queued request %s (%s) approved", req.ID, req.Link)
+		}(req)
+	}
+}
+
+// isTransientFailure tells whether the routing failed only because no approver could be reached: none was online,
+// or none of the approvers the request was forwarded to answered before timing out or disconnecting.
+func isTransientFailure(result ApprovalResult, err error) bool {
+	if err == nil || len(result.Approvers) > 0 {
+		return false
+	}
+	if len(result.Attempts) == 0 {
+		return errors.Is(err, ErrNoEligibleApprover)
+	}
+	for _, attempt := range result.Attempts {
+		if attempt.Response != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// notifyQueuedOutcome reports the outcome of a request delivered from the queue where it was submitted.
+// The requesters of the web UI and the API follow their requests, the outcome of the requests triggered by
+// a webhook is commented on the PR.
+func (s *Server) notifyQueuedOutcome(req QueuedRequest, result ApprovalResult, err error) {
+	if req.Source == api.RequestSourceWebhook {
+		s.commentWebhookOutcome(req.Link, req.Requester, result, nil, err)
+	}
+}
+
+// attemptApproval forwards the approval request to the selected client and waits for its response.
+func (s *Server) attemptApproval(req api.ApprovalRequest, selected *clientInfo, timeout time.Duration) (attempt api.RoutingAttempt) {
+	attempt.Approver = selected.githubUser
+	attempt.StartedAt = time.Now()
+	defer func() {
+		attempt.Duration = time.Since(attempt.StartedAt)
+	}()
+
+	selected.inFlight.Add(1)
+	defer selected.inFlight.Add(-1)
+
+	res, _, err := s.sendRPC(selected, protocol.ApproveRequestMessage{
+		Link:          req.Link,
+		Requester:     req.Requester,
+		HeadSHA:       req.HeadSHA,
+		Justification: req.Justification,
+		RequestedAt:   req.RequestedAt,
+		Deadline:      time.Now().Add(timeout),
+	}, timeout)
+	if err != nil {
+		attempt.Error = fmt.Sprintf("failed to send rpc call: %s", err)
+		return attempt
+	}
+
+	// Wait for the approval response
+	var resp protocol.ApproveResponseMessage
+	err = res.WaitResponse(&resp)
+	if errors.Is(err, protocol.ErrFutureClosed) {
+		attempt.Error = "no response from approver (timeout or disconnection)"
+		return attempt
+	} else if err != nil {
+		attempt.Error = fmt.Sprintf("failed to receive async response: %s", err)
+		return attempt
+	}
+
+	attempt.Response = resp.Response
+	attempt.Rejection = resp.Rejection
+	switch resp.Response {
+	case protocol.ApproveResponseSuccess:
+	case protocol.ApproveResponseErrSameAuthor:
+		attempt.Error = "approver is the author of the PR"
+	default:
+		attempt.Error = string(resp.Response)
+		if resp.Reason != "" {
+			attempt.Error = fmt.Sprintf("%s: %s", resp.Response, resp.Reason)
+		}
+	}
+	return attempt
+}
+
+// excludeApprover returns a new list of clients without the clients of the given GitHub user.
+func excludeApprover(clients []*clientInfo, githubUser string) []*clientInfo {
+	reducedList := make([]*clientInfo, 0, len(clients))
+	for _, c := range clients {
+		if c.githubUser == githubUser {
+			continue
+		}
+		reducedList = append(reducedList, c)
+	}
+	return reducedList
+}
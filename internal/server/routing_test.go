@@ -0,0 +1,329 @@
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/clems4ever/lgtm/internal/api"
+	"github.com/clems4ever/lgtm/internal/forge"
+	"github.com/clems4ever/lgtm/internal/github"
+	"github.com/clems4ever/lgtm/internal/protocol"
+	"github.com/clems4ever/lgtm/internal/test"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+var testLink = github.PRLink{Owner: "foo", Repo: "bar", PRNumber: 1}
+
+func TestRequestApproval_NoApprover(t *testing.T) {
+	s, _ := newTestServer(t, nil, DefaultRetryPolicy())
+
+	result, err := s.RequestApproval(api.ApprovalRequest{Link: testLink, RequiredApprovals: 1})
+	attempts := result.Attempts
+	require.ErrorIs(t, err, ErrNoEligibleApprover)
+	require.Empty(t, attempts)
+}
+
+func TestRequestApproval_NamespacesReposByForge(t *testing.T) {
+	s, wsURL := newTestServer(t, nil, DefaultRetryPolicy())
+	connectFakeApprover(t, s, wsURL, "alice", []string{"foo/bar"}, respondWith(protocol.ApproveResponseSuccess))
+	connectFakeApproverOnForge(t, s, wsURL, "github", "ghe.example.com", "bob", []string{"foo/bar"},
+		respondWith(protocol.ApproveResponseSuccess))
+	connectFakeApproverOnForge(t, s, wsURL, "gitlab", "ghe.example.com", "carol", []string{"foo/bar"},
+		respondWith(protocol.ApproveResponseSuccess))
+
+	result, err := s.RequestApproval(api.ApprovalRequest{Link: testLink, RequiredApprovals: 1})
+	require.NoError(t, err)
+	require.Equal(t, []string{"alice"}, result.Approvers)
+
+	enterpriseLink := github.PRLink{Host: "ghe.example.com", Owner: "foo", Repo: "bar", PRNumber: 1}
+	result, err = s.RequestApproval(api.ApprovalRequest{Link: enterpriseLink, RequiredApprovals: 1})
+	require.NoError(t, err)
+	require.Equal(t, []string{"bob"}, result.Approvers)
+
+	mergeRequestLink := forge.ChangeLink{Provider: "gitlab", Host: "ghe.example.com", Owner: "foo", Repo: "bar", PRNumber: 1}
+	result, err = s.RequestApproval(api.ApprovalRequest{Link: mergeRequestLink, RequiredApprovals: 1})
+	require.NoError(t, err)
+	require.Equal(t, []string{"carol"}, result.Approvers)
+
+	otherLink := github.PRLink{Host: "other.example.com", Owner: "foo", Repo: "bar", PRNumber: 1}
+	_, err = s.RequestApproval(api.ApprovalRequest{Link: otherLink, RequiredApprovals: 1})
+	require.ErrorIs(t, err, ErrNoEligibleApprover)
+}
+
+func TestRequestApproval_SkipsGitHubGatesOnOtherForges(t *testing.T) {
+	githubSrv := test.NewGithubMockServer(t, "")
+	t.Cleanup(githubSrv.Close)
+
+	s, wsURL := newTestServer(t, nil, DefaultRetryPolicy())
+	s.githubClient = github.NewClient("server-token", githubSrv.URL(), nil)
+	s.checks = &ChecksPolicy{}
+	s.codeownersMode = CodeownersModeRequire
+	connectFakeApproverOnForge(t, s, wsURL, "gitlab", "gitlab.com", "carol", []string{"foo/bar"},
+		respondWith(protocol.ApproveResponseSuccess))
+
+	mergeRequestLink := forge.ChangeLink{Provider: "gitlab", Host: "gitlab.com", Owner: "foo", Repo: "bar", PRNumber: 1}
+	result, err := s.RequestApproval(api.ApprovalRequest{Link: mergeRequestLink, RequiredApprovals: 1})
+	require.NoError(t, err)
+	require.Equal(t, []string{"carol"}, result.Approvers)
+}
+
+func TestRequestApproval_FallsThroughOnTimeout(t *testing.T) {
+	s, wsURL := newTestServer(t, nil, RetryPolicy{RPCTimeout: 200 * time.Millisecond})
+	connectFakeApprover(t, s, wsURL, "alice", []string{"foo/bar"}, neverRespond)
+	connectFakeApprover(t, s, wsURL, "bob", []string{"foo/bar"}, respondWith(protocol.ApproveResponseSuccess))
+
+	result, err := s.RequestApproval(api.ApprovalRequest{Link: testLink, RequiredApprovals: 1})
+	attempts := result.Attempts
+	require.NoError(t, err)
+	require.Len(t, attempts, 2)
+	require.Equal(t, "alice", attempts[0].Approver)
+	require.NotEmpty(t, attempts[0].Error)
+	require.Equal(t, "bob", attempts[1].Approver)
+	require.Equal(t, protocol.ApproveResponseSuccess, attempts[1].Response)
+}
+
+func TestRequestApproval_FallsThroughOnFailure(t *testing.T) {
+	s, wsURL := newTestServer(t, nil, DefaultRetryPolicy())
+	connectFakeApprover(t, s, wsURL, "alice", []string{"foo/bar"},
+		func(*websocket.Conn, protocol.ApproveRequestMessage) *protocol.ApproveResponseMessage {
+			return &protocol.ApproveResponseMessage{Response: protocol.ApproveResponseErrFailed, Reason: "GitHub API error"}
+		})
+	connectFakeApprover(t, s, wsURL, "bob", []string{"foo/bar"}, respondWith(protocol.ApproveResponseSuccess))
+
+	result, err := s.RequestApproval(api.ApprovalRequest{Link: testLink, RequiredApprovals: 1})
+	attempts := result.Attempts
+	require.NoError(t, err)
+	require.Len(t, attempts, 2)
+	require.Equal(t, protocol.ApproveResponseErrFailed, attempts[0].Response)
+	require.Contains(t, attempts[0].Error, "GitHub API error")
+}
+
+func TestRequestApproval_FallsThroughOnDisconnection(t *testing.T) {
+	s, wsURL := newTestServer(t, nil, RetryPolicy{RPCTimeout: 10 * time.Second})
+	connectFakeApprover(t, s, wsURL, "alice", []string{"foo/bar"},
+		func(conn *websocket.Conn, _ protocol.ApproveRequestMessage) *protocol.ApproveResponseMessage {
+			conn.Close()
+			return nil
+		})
+	connectFakeApprover(t, s, wsURL, "bob", []string{"foo/bar"}, respondWith(protocol.ApproveResponseSuccess))
+
+	start := time.Now()
+	result, err := s.RequestApproval(api.ApprovalRequest{Link: testLink, RequiredApprovals: 1})
+	attempts := result.Attempts
+	require.NoError(t, err)
+	require.Len(t, attempts, 2)
+	require.Equal(t, "bob", attempts[1].Approver)
+	// The disconnection must be detected without waiting for the RPC timeout.
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestRequestApproval_SameAuthorExhaustsApprovers(t *testing.T) {
+	s, wsURL := newTestServer(t, nil, DefaultRetryPolicy())
+	connectFakeApprover(t, s, wsURL, "alice", []string{"foo/bar"}, respondWith(protocol.ApproveResponseErrSameAuthor))
+
+	result, err := s.RequestApproval(api.ApprovalRequest{Link: testLink, RequiredApprovals: 1})
+	attempts := result.Attempts
+	require.ErrorIs(t, err, ErrNoEligibleApprover)
+	require.Len(t, attempts, 1)
+}
+
+func TestRequestApproval_Deadline(t *testing.T) {
+	s, wsURL := newTestServer(t, nil, RetryPolicy{RPCTimeout: 10 * time.Second, Deadline: 300 * time.Millisecond})
+	connectFakeApprover(t, s, wsURL, "alice", []string{"foo/bar"}, neverRespond)
+	connectFakeApprover(t, s, wsURL, "bob", []string{"foo/bar"}, respondWith(protocol.ApproveResponseSuccess))
+
+	result, err := s.RequestApproval(api.ApprovalRequest{Link: testLink, RequiredApprovals: 1})
+	attempts := result.Attempts
+	require.True(t, errors.Is(err, ErrRoutingDeadlineExceeded), "unexpected error: %v", err)
+	require.Len(t, attempts, 1)
+}
+
+func TestRequestApproval_MaxAttempts(t *testing.T) {
+	s, wsURL := newTestServer(t, nil, RetryPolicy{MaxAttempts: 1})
+	connectFakeApprover(t, s, wsURL, "alice", []string{"foo/bar"}, respondWith(protocol.ApproveResponseErrFailed))
+	connectFakeApprover(t, s, wsURL, "bob", []string{"foo/bar"}, respondWith(protocol.ApproveResponseSuccess))
+
+	result, err := s.RequestApproval(api.ApprovalRequest{Link: testLink, RequiredApprovals: 1})
+	attempts := result.Attempts
+	require.ErrorIs(t, err, ErrMaxAttemptsReached)
+	require.Len(t, attempts, 1)
+}
+
+func TestSubmitApproval_QueuesAndDeliversOnRegistration(t *testing.T) {
+	s, wsURL := newTestServer(t, nil, DefaultRetryPolicy())
+	queue, err := NewPendingQueue("", time.Hour, nil)
+	require.NoError(t, err)
+	s.queue = queue
+
+	result, queued, err := s.SubmitApproval(api.ApprovalRequest{Link: testLink, Requester: "alice"})
+	attempts := result.Attempts
+	require.NoError(t, err)
+	require.Empty(t, attempts)
+	require.NotNil(t, queued)
+	require.Len(t, queue.ListByRequester("alice"), 1)
+	status, err := s.requests.Get(queued.ID, "alice")
+	require.NoError(t, err)
+	require.Equal(t, api.RequestStateQueued, status.State)
+
+	approvedC := make(chan github.PRLink, 1)
+	connectFakeApprover(t, s, wsURL, "bob", []string{"foo/bar"},
+		func(_ *websocket.Conn, msg protocol.ApproveRequestMessage) *protocol.ApproveResponseMessage {
+			approvedC <- msg.Link
+			return &protocol.ApproveResponseMessage{Response: protocol.ApproveResponseSuccess}
+		})
+
+	select {
+	case link := <-approvedC:
+		require.Equal(t, testLink, link)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "queued request was not delivered")
+	}
+	require.Empty(t, queue.ListByRequester("alice"))
+	require.Eventually(t, func() bool {
+		status, err := s.requests.Get(queued.ID, "alice")
+		return err == nil && status.State == api.RequestStateApproved
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestSubmitApproval_RequeuesWhenNoApproverAnswers(t *testing.T) {
+	s, wsURL := newTestServer(t, nil, RetryPolicy{RPCTimeout: 200 * time.Millisecond})
+	queue, err := NewPendingQueue("", time.Hour, nil)
+	require.NoError(t, err)
+	s.queue = queue
+
+	_, queued, err := s.SubmitApproval(api.ApprovalRequest{Link: testLink, Requester: "alice"})
+	require.NoError(t, err)
+	require.NotNil(t, queued)
+
+	// The approver times out, the request waits in the queue for another one.
+	connectFakeApprover(t, s, wsURL, "bob", []string{"foo/bar"}, neverRespond)
+	require.Eventually(t, func() bool {
+		status, err := s.requests.Get(queued.ID, "alice")
+		return err == nil && status.State == api.RequestStateQueued && len(status.Attempts) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	require.Len(t, queue.ListByRequester("alice"), 1)
+
+	connectFakeApprover(t, s, wsURL, "carol", []string{"foo/bar"}, respondWith(protocol.ApproveResponseSuccess))
+	require.Eventually(t, func() bool {
+		status, err := s.requests.Get(queued.ID, "alice")
+		return err == nil && status.State == api.RequestStateApproved
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Empty(t, queue.ListByRequester("alice"))
+
+	// The attempts of the first delivery are kept.
+	status, err := s.requests.Get(queued.ID, "alice")
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(status.Attempts), 2)
+	require.Equal(t, "bob", status.Attempts[0].Approver)
+}
+
+func TestSubmitApproval_RecordsQueuedFailure(t *testing.T) {
+	s, wsURL := newTestServer(t, nil, DefaultRetryPolicy())
+	queue, err := NewPendingQueue("", time.Hour, nil)
+	require.NoError(t, err)
+	s.queue = queue
+
+	_, queued, err := s.SubmitApproval(api.ApprovalRequest{Link: testLink, Requester: "alice"})
+	require.NoError(t, err)
+	require.NotNil(t, queued)
+
+	// The approver refuses the PR, the requester sees the request failed.
+	connectFakeApprover(t, s, wsURL, "bob", []string{"foo/bar"}, respondWith(protocol.ApproveResponseRefused))
+	require.Eventually(t, func() bool {
+		status, err := s.requests.Get(queued.ID, "alice")
+		return err == nil && status.State == api.RequestStateFailed && status.Error != ""
+	}, 2*time.Second, 10*time.Millisecond)
+	require.Empty(t, queue.ListByRequester("alice"))
+}
+
+func TestSubmitApprovalAsync(t *testing.T) {
+	s, wsURL := newTestServer(t, nil, DefaultRetryPolicy())
+	release := make(chan struct{})
+	connectFakeApprover(t, s, wsURL, "bob", []string{"foo/bar"},
+		func(*websocket.Conn, protocol.ApproveRequestMessage) *protocol.ApproveResponseMessage {
+			<-release
+			return &protocol.ApproveResponseMessage{Response: protocol.ApproveResponseSuccess}
+		})
+
+	status, err := s.SubmitApprovalAsync(api.ApprovalRequest{Link: testLink, Requester: "alice"})
+	require.NoError(t, err)
+	require.Equal(t, api.RequestStateRouting, status.State)
+
+	// The status shows the approver the request is waiting for.
+	require.Eventually(t, func() bool {
+		status, err := s.requests.Get(status.ID, "alice")
+		return err == nil && status.CurrentApprover == "bob"
+	}, 2*time.Second, 10*time.Millisecond)
+	_, err = s.requests.Get(status.ID, "mallory")
+	require.ErrorIs(t, err, ErrRequestNotFound)
+
+	close(release)
+	require.Eventually(t, func() bool {
+		status, err := s.requests.Get(status.ID, "alice")
+		return err == nil && status.State == api.RequestStateApproved
+	}, 2*time.Second, 10*time.Millisecond)
+	status, err = s.requests.Get(status.ID, "alice")
+	require.NoError(t, err)
+	require.Equal(t, []string{"bob"}, status.Approvers)
+	require.Len(t, status.Attempts, 1)
+	require.NotNil(t, status.CompletedAt)
+}
+
+func TestRequestApproval_RequiresDistinctApprovals(t *testing.T) {
+	s, wsURL := newTestServer(t, nil, DefaultRetryPolicy())
+	connectFakeApprover(t, s, wsURL, "alice", []string{"foo/bar"}, respondWith(protocol.ApproveResponseSuccess))
+	// A second client of the same user must not count as a distinct approval.
+	connectFakeApprover(t, s, wsURL, "alice", []string{"foo/bar"}, respondWith(protocol.ApproveResponseSuccess))
+	connectFakeApprover(t, s, wsURL, "bob", []string{"foo/bar"}, respondWith(protocol.ApproveResponseErrSameAuthor))
+	connectFakeApprover(t, s, wsURL, "carol", []string{"foo/bar"}, respondWith(protocol.ApproveResponseSuccess))
+
+	result, err := s.RequestApproval(api.ApprovalRequest{Link: testLink, RequiredApprovals: 2})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"alice", "carol"}, result.Approvers)
+}
+
+func TestRequestApproval_ReportsPartialProgress(t *testing.T) {
+	s, wsURL := newTestServer(t, nil, DefaultRetryPolicy())
+	connectFakeApprover(t, s, wsURL, "alice", []string{"foo/bar"}, respondWith(protocol.ApproveResponseSuccess))
+	connectFakeApprover(t, s, wsURL, "bob", []string{"foo/bar"}, respondWith(protocol.ApproveResponseErrFailed))
+
+	result, err := s.RequestApproval(api.ApprovalRequest{Link: testLink, RequiredApprovals: 2})
+	require.ErrorIs(t, err, ErrNotEnoughApprovals)
+	require.ErrorIs(t, err, ErrNoEligibleApprover)
+	require.Equal(t, []string{"alice"}, result.Approvers)
+	require.Equal(t, 2, result.RequiredApprovals)
+}
+
+func TestRequiredApprovals(t *testing.T) {
+	s, _ := newTestServer(t, nil, DefaultRetryPolicy())
+	s.requiredApprovalsByRepo = map[string]int{testLink.RepoID(): 2}
+
+	require.Equal(t, 2, s.requiredApprovals(testLink.RepoID(), 0))
+	require.Equal(t, 2, s.requiredApprovals(testLink.RepoID(), 1))
+	require.Equal(t, 3, s.requiredApprovals(testLink.RepoID(), 3))
+	require.Equal(t, 1, s.requiredApprovals(forge.RepoID(forge.ProviderGitHub, "", "foo/baz"), 0))
+	// The same repository name on another forge is another repository.
+	require.Equal(t, 1, s.requiredApprovals(forge.RepoID(forge.ProviderGitLab, "", "foo/bar"), 0))
+}
+
+func TestRequestApproval_StopsWhenHeadMoved(t *testing.T) {
+	s, wsURL := newTestServer(t, nil, DefaultRetryPolicy())
+	var receivedSHA string
+	connectFakeApprover(t, s, wsURL, "alice", []string{"foo/bar"},
+		func(_ *websocket.Conn, msg protocol.ApproveRequestMessage) *protocol.ApproveResponseMessage {
+			receivedSHA = msg.HeadSHA
+			return &protocol.ApproveResponseMessage{
+				Response:  protocol.ApproveResponseRejected,
+				Rejection: &protocol.Rejection{Code: protocol.RejectionHeadMoved, Message: "head moved from abc to def"},
+			}
+		})
+	connectFakeApprover(t, s, wsURL, "bob", []string{"foo/bar"}, respondWith(protocol.ApproveResponseSuccess))
+
+	result, err := s.RequestApproval(api.ApprovalRequest{Link: testLink, RequiredApprovals: 1, HeadSHA: "abc"})
+	require.ErrorIs(t, err, ErrHeadMoved)
+	require.Equal(t, "abc", receivedSHA)
+	require.Len(t, result.Attempts, 1)
+	require.Empty(t, result.Approvers)
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/clems4ever/lgtm/internal/github"
 	"github.com/clems4ever/lgtm/internal/protocol"
 	"github.com/gorilla/sessions"
 	"github.com/gorilla/websocket"
@@ -17,7 +19,21 @@ type clientInfo struct {
 	// if the githubUser variable is not set, it means the connection is established but
 	// the client have not registered yet.
 	githubUser string
-	repos      map[string]struct{} // set of "owner/repo"
+	// provider and host of the forge the client registered for, the repositories of registration updates are on it.
+	provider string
+	host     string
+	repos    map[string]struct{} // set of "provider:host/owner/repo"
+	// number of approval requests sent to this client and not answered yet.
+	inFlight atomic.Int64
+	// serializes the writes on conn, websocket connections support one concurrent writer only.
+	writeMu sync.Mutex
+}
+
+// pendingRequest is an RPC sent to a client and waiting for its response.
+type pendingRequest struct {
+	future *protocol.ResponseFuture
+	// the connection the request was sent on.
+	conn *websocket.Conn
 }
 
 var (
@@ -34,28 +50,85 @@ type Server struct {
 	httpClient   *http.Client
 	pingInterval time.Duration
 
+	// githubHost is the web host of the GitHub instance the PRs are hosted on, e.g. github.com or a GitHub Enterprise Server.
+	githubHost string
+	// githubAPIURL is the base URL of the REST API of the GitHub instance, used with the tokens of the users.
+	githubAPIURL string
+	// forges are the forges other than GitHub whose change requests are accepted, e.g. GitLab or Gitea.
+	forges []ForgeClient
+
 	approvalEngine *ApprovalEngine
+	router         Router
+	retryPolicy    RetryPolicy
+	// requiredApprovalsByRepo is the minimum number of approvals per "provider:host/owner/repo", see forge.RepoID.
+	requiredApprovalsByRepo map[string]int
+	// codeownersMode controls how CODEOWNERS files are used to select approvers.
+	codeownersMode CodeownersMode
+	// loginRestriction restricts the web login to some organizations or teams, nil to allow everyone.
+	loginRestriction *LoginRestriction
+	// allowedLogins records when the users last passed the login restriction, see isLoginAllowed.
+	allowedLogins   map[string]time.Time
+	allowedLoginsMu sync.Mutex
+	// submitterCheck controls which relationship the submitter must have with a PR to request its approval.
+	submitterCheck SubmitterCheckMode
+	// forgeAccounts are the accounts of the users on the forges other than GitHub, used by the submitter check.
+	forgeAccounts ForgeAccounts
+	// webhook configures the GitHub webhooks triggering approval requests, nil if webhooks are disabled.
+	webhook *WebhookConfig
+	// webhookDeliveries records when the recent webhook deliveries were received, to ignore redeliveries.
+	webhookDeliveries   map[string]time.Time
+	webhookDeliveriesMu sync.Mutex
+	// checks requires the checks of a PR to be green before routing it, nil to route every PR.
+	checks *ChecksPolicy
+	// githubClient is authenticated with the server's own token, used to read CODEOWNERS files and PR files.
+	githubClient *github.Client
+	// policy controls who may approve and request approvals, nil if no policy file is configured.
+	policy *PolicyStore
+	// queue holds the requests submitted while no approver was online, nil if queueing is disabled.
+	queue *PendingQueue
+	// requests tracks the status of the submitted requests.
+	requests *RequestTracker
+	// tokens holds the personal tokens authenticating the requests to the API.
+	tokens *TokenStore
 
 	mu               sync.Mutex
 	clientInfoByConn map[*websocket.Conn]*clientInfo
-	clientsByRepo    map[string][]*clientInfo
+	// clientsByRepo are the registered clients by "provider:host/owner/repo", see forge.RepoID.
+	clientsByRepo map[string][]*clientInfo
 
 	asyncRequestsMu sync.Mutex
-	asyncRequests   map[string]*protocol.ResponseFuture
+	asyncRequests   map[string]*pendingRequest
 	wg              sync.WaitGroup
 }
 
-func NewServer(oauth2Config *oauth2.Config, pingInterval time.Duration) *Server {
+// NewServer creates a new Server. If router is nil, approvers are selected in a round-robin fashion.
+// If the RPC timeout of the retry policy is not set, the default one is used.
+func NewServer(oauth2Config *oauth2.Config, pingInterval time.Duration, router Router, retryPolicy RetryPolicy) *Server {
+	if router == nil {
+		router = NewRoundRobinRouter()
+	}
+	if retryPolicy.RPCTimeout <= 0 {
+		retryPolicy.RPCTimeout = defaultRPCTimeout
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Server{
-		oauth2Config:     oauth2Config,
-		approvalEngine:   NewApprovalEngine(),
-		clientInfoByConn: make(map[*websocket.Conn]*clientInfo),
-		clientsByRepo:    make(map[string][]*clientInfo),
-		asyncRequests:    make(map[string]*protocol.ResponseFuture),
-		ctx:              ctx,
-		done:             cancel,
-		pingInterval:     pingInterval,
+		oauth2Config:      oauth2Config,
+		approvalEngine:    NewApprovalEngine(),
+		router:            router,
+		retryPolicy:       retryPolicy,
+		clientInfoByConn:  make(map[*websocket.Conn]*clientInfo),
+		clientsByRepo:     make(map[string][]*clientInfo),
+		asyncRequests:     make(map[string]*pendingRequest),
+		webhookDeliveries: make(map[string]time.Time),
+		allowedLogins:     make(map[string]time.Time),
+		requests:          NewRequestTracker(defaultRequestRetention, nil),
+		tokens:            newTokenStore("", defaultAPITokenTTL, nil),
+		ctx:               ctx,
+		done:              cancel,
+		pingInterval:      pingInterval,
+		submitterCheck:    SubmitterCheckCollaborator,
+		githubHost:        github.DefaultHost,
+		githubAPIURL:      github.APIURLForHost(github.DefaultHost),
 	}
 }
 
@@ -64,7 +137,7 @@ func (s *Server) Close() {
 
 	s.asyncRequestsMu.Lock()
 	for _, r := range s.asyncRequests {
-		r.Close()
+		r.future.Close()
 	}
 	s.asyncRequestsMu.Unlock()
 	s.wg.Wait()
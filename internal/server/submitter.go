@@ -0,0 +1,128 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/clems4ever/lgtm/internal/forge"
+	"github.com/clems4ever/lgtm/internal/github"
+)
+
+var (
+	// ErrSubmitterNotAllowed is returned when the submitter is neither the author nor a collaborator of the PR,
+	// as required by the submitter check mode.
+	ErrSubmitterNotAllowed = fmt.Errorf("submitter not allowed")
+)
+
+// SubmitterCheckMode controls which relationship the submitter must have with the PR to request its approval.
+type SubmitterCheckMode string
+
+const (
+	// SubmitterCheckOff lets any logged-in user submit any PR.
+	SubmitterCheckOff SubmitterCheckMode = "off"
+	// SubmitterCheckAuthor only lets the author of the PR submit it.
+	SubmitterCheckAuthor SubmitterCheckMode = "author"
+	// SubmitterCheckCollaborator lets the author of the PR or a user with push access to the repository submit it.
+	SubmitterCheckCollaborator SubmitterCheckMode = "collaborator"
+)
+
+// ParseSubmitterCheckMode validates the given submitter check mode.
+func ParseSubmitterCheckMode(s string) (SubmitterCheckMode, error) {
+	switch mode := SubmitterCheckMode(s); mode {
+	case SubmitterCheckOff, SubmitterCheckAuthor, SubmitterCheckCollaborator:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown submitter check mode %q", s)
+}
+
+// verifySubmitter checks the relationship of the submitter with the PR according to the submitter check mode.
+// gh must be authenticated as the submitter. The returned error wraps ErrSubmitterNotAllowed if the check fails.
+func (s *Server) verifySubmitter(gh *github.Client, link github.PRLink, pr *github.PullRequest, username string) error {
+	return s.checkSubmitter(link, pr.Author(), username, func() (bool, error) {
+		repo, err := gh.GetRepo(link.Owner, link.Repo)
+		if err != nil {
+			return false, err
+		}
+		return repo.Permissions.Push, nil
+	})
+}
+
+// verifySubmitterAsServer is like verifySubmitter for submissions that are not authenticated as the submitter,
+// such as webhooks. The permission of the submitter is read with the server's GitHub client.
+func (s *Server) verifySubmitterAsServer(link github.PRLink, pr *github.PullRequest, username string) error {
+	return s.checkSubmitter(link, pr.Author(), username, func() (bool, error) {
+		permission, err := s.githubClient.GetCollaboratorPermission(link.Owner, link.Repo, username)
+		if err != nil {
+			return false, err
+		}
+		return permission == "admin" || permission == "write", nil
+	})
+}
+
+// verifyForgeSubmitter is like verifySubmitterAsServer for the change requests of forges other than GitHub.
+// Users log in with GitHub and the same username can belong to someone else on another forge, so the submitter
+// is checked as the forge account mapped to their GitHub login. Submitters without a mapped account are refused.
+func (s *Server) verifyForgeSubmitter(f ForgeClient, link forge.ChangeLink, change *forge.Change, username string) error {
+	if s.submitterCheck == SubmitterCheckOff {
+		return nil
+	}
+	forgeUser, ok := s.forgeAccounts.Username(username, link.HostName())
+	if !ok {
+		return fmt.Errorf("%w: %s has no account mapped on %s", ErrSubmitterNotAllowed, username, link.HostName())
+	}
+	return s.checkSubmitter(link, change.Author, forgeUser, func() (bool, error) {
+		return f.HasWriteAccess(link.Owner, link.Repo, forgeUser)
+	})
+}
+
+// ForgeAccounts maps the GitHub logins (lowercased) to the usernames of the same users on the hosts of the other
+// forges, e.g. accounts["alice"]["gitlab.com"] = "alice-gl".
+type ForgeAccounts map[string]map[string]string
+
+// ParseForgeAccounts parses a comma-separated list of login=host/username pairs (e.g. "alice=gitlab.com/alice-gl").
+func ParseForgeAccounts(s string) (ForgeAccounts, error) {
+	accounts := make(ForgeAccounts)
+	if strings.TrimSpace(s) == "" {
+		return accounts, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		login, account, _ := strings.Cut(strings.TrimSpace(pair), "=")
+		host, username, _ := strings.Cut(account, "/")
+		if login == "" || host == "" || username == "" {
+			return nil, fmt.Errorf("invalid forge account %q, expected login=host/username", pair)
+		}
+		login = strings.ToLower(login)
+		if accounts[login] == nil {
+			accounts[login] = make(map[string]string)
+		}
+		accounts[login][strings.ToLower(host)] = username
+	}
+	return accounts, nil
+}
+
+// Username returns the username of the GitHub user on the forge with the given host.
+func (a ForgeAccounts) Username(login, host string) (string, bool) {
+	username, ok := a[strings.ToLower(login)][strings.ToLower(host)]
+	return username, ok
+}
+
+// checkSubmitter implements the submitter check. hasPushAccess tells whether the submitter can push to the
+// repository of the PR, it is only called if the submitter is not the author.
+func (s *Server) checkSubmitter(link forge.ChangeLink, author, username string, hasPushAccess func() (bool, error)) error {
+	if s.submitterCheck == SubmitterCheckOff || strings.EqualFold(author, username) {
+		return nil
+	}
+	if s.submitterCheck == SubmitterCheckAuthor {
+		return fmt.Errorf("%w: %s is not the author of %s", ErrSubmitterNotAllowed, username, link)
+	}
+
+	push, err := hasPushAccess()
+	if err != nil {
+		return fmt.Errorf("failed to get repository permissions: %w", err)
+	}
+	if !push {
+		return fmt.Errorf("%w: %s is neither the author of %s nor a collaborator of %s",
+			ErrSubmitterNotAllowed, username, link, link.RepoFullName())
+	}
+	return nil
+}
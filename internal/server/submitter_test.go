@@ -0,0 +1,115 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/clems4ever/lgtm/internal/forge"
+	"github.com/clems4ever/lgtm/internal/gitea"
+	"github.com/clems4ever/lgtm/internal/github"
+	"github.com/clems4ever/lgtm/internal/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifySubmitter(t *testing.T) {
+	githubSrv := test.NewGithubMockServer(t, "")
+	t.Cleanup(githubSrv.Close)
+	githubSrv.AddUser("prauthor", "author-token", nil)
+	githubSrv.AddUser("alice", "alice-token", []test.Repo{{FullName: "foo/bar", Permissions: test.RepoPermissions{Push: true}}})
+	githubSrv.AddUser("mallory", "mallory-token", nil)
+
+	s, _ := newTestServer(t, nil, DefaultRetryPolicy())
+	pr := &github.PullRequest{}
+	pr.User.Login = "prauthor"
+
+	tests := []struct {
+		mode    SubmitterCheckMode
+		user    string
+		token   string
+		allowed bool
+	}{
+		{SubmitterCheckAuthor, "PRAuthor", "author-token", true},
+		{SubmitterCheckAuthor, "alice", "alice-token", false},
+		{SubmitterCheckCollaborator, "prauthor", "author-token", true},
+		{SubmitterCheckCollaborator, "alice", "alice-token", true},
+		{SubmitterCheckCollaborator, "mallory", "mallory-token", false},
+		{SubmitterCheckOff, "mallory", "mallory-token", true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode)+"/"+tt.user, func(t *testing.T) {
+			s.submitterCheck = tt.mode
+			gh := github.NewClient(tt.token, githubSrv.URL(), nil)
+			err := s.verifySubmitter(gh, testLink, pr, tt.user)
+			if tt.allowed {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, ErrSubmitterNotAllowed)
+			}
+		})
+	}
+}
+
+func TestCheckForgeSubmission(t *testing.T) {
+	giteaSrv := test.NewGiteaMockServer(t)
+	t.Cleanup(giteaSrv.Close)
+	giteaSrv.AddUser("lgtm-bot", "server-token", nil)
+	giteaSrv.AddUser("prauthor", "author-token", nil)
+	giteaSrv.AddUser("alice", "alice-token", []test.Repo{{FullName: "foo/bar", Permissions: test.RepoPermissions{Push: true}}})
+	giteaSrv.AddUser("mallory", "mallory-token", nil)
+	giteaSrv.AddPullRequest("foo/bar", 1, test.GiteaPullRequest{Author: "prauthor", HeadSHA: "abc"})
+
+	s, _ := newTestServer(t, nil, DefaultRetryPolicy())
+	s.forges = []ForgeClient{gitea.NewClient("server-token", giteaSrv.Host(), giteaSrv.APIURL(), nil)}
+	link, err := forge.ParseChangeURL(giteaSrv.URL()+"/foo/bar/pulls/1", s.forges[0])
+	require.NoError(t, err)
+	f := s.forgeOf(link)
+	require.NotNil(t, f)
+	require.Nil(t, s.forgeOf(testLink))
+
+	host := link.HostName()
+	s.forgeAccounts = ForgeAccounts{
+		"prauthor": {host: "prauthor"},
+		"alice-gh": {host: "alice"},
+		"mallory":  {host: "mallory"},
+		"other-gh": {"gitlab.com": "alice"},
+	}
+
+	// The GitHub login of the submitter is not their username on the forge, only the mapped account counts.
+	for user, allowed := range map[string]bool{"prauthor": true, "alice-gh": true, "mallory": false, "alice": false, "other-gh": false} {
+		t.Run(user, func(t *testing.T) {
+			headSHA, err := s.checkForgeSubmission(f, link, user)
+			if allowed {
+				require.NoError(t, err)
+				require.Equal(t, "abc", headSHA)
+			} else {
+				require.ErrorIs(t, err, ErrSubmitterNotAllowed)
+			}
+		})
+	}
+}
+
+func TestParseForgeAccounts(t *testing.T) {
+	accounts, err := ParseForgeAccounts("Alice=GitLab.com/alice-gl, alice=git.example.com/alice2")
+	require.NoError(t, err)
+	username, ok := accounts.Username("alice", "gitlab.com")
+	require.True(t, ok)
+	require.Equal(t, "alice-gl", username)
+	username, ok = accounts.Username("ALICE", "git.example.com")
+	require.True(t, ok)
+	require.Equal(t, "alice2", username)
+	_, ok = accounts.Username("bob", "gitlab.com")
+	require.False(t, ok)
+
+	for _, in := range []string{"alice", "alice=gitlab.com", "=gitlab.com/alice", "alice=/alice"} {
+		_, err := ParseForgeAccounts(in)
+		require.Error(t, err, in)
+	}
+}
+
+func TestParseSubmitterCheckMode(t *testing.T) {
+	mode, err := ParseSubmitterCheckMode("author")
+	require.NoError(t, err)
+	require.Equal(t, SubmitterCheckAuthor, mode)
+
+	_, err = ParseSubmitterCheckMode("anyone")
+	require.Error(t, err)
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clems4ever/lgtm/internal/forge"
+	"github.com/clems4ever/lgtm/internal/protocol"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+// approveHandler decides how a fake approver answers an approval request.
+// Returning nil means the approver does not answer.
+type approveHandler func(conn *websocket.Conn, msg protocol.ApproveRequestMessage) *protocol.ApproveResponseMessage
+
+// newTestServer starts a Server exposing its websocket endpoint on an httptest server.
+func newTestServer(t *testing.T, router Router, retryPolicy RetryPolicy) (*Server, string) {
+	t.Helper()
+	s := NewServer(nil, 0, router, retryPolicy)
+	ts := httptest.NewServer(http.HandlerFunc(s.wsHandler))
+	t.Cleanup(func() {
+		ts.Close()
+		s.Close()
+	})
+	return s, "ws" + strings.TrimPrefix(ts.URL, "http")
+}
+
+// connectFakeApprover connects a fake client registered as an approver for the given repos.
+// It waits until the server has registered the client.
+func connectFakeApprover(t *testing.T, s *Server, wsURL, user string, repos []string, handler approveHandler) *websocket.Conn {
+	t.Helper()
+	return connectFakeApproverOnForge(t, s, wsURL, "", "", user, repos, handler)
+}
+
+// connectFakeApproverOnForge connects a fake client registered as an approver for the given repos of a forge instance.
+// It waits until the server has registered the client.
+func connectFakeApproverOnForge(t *testing.T, s *Server, wsURL, provider, host, user string, repos []string,
+	handler approveHandler) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	_, err = protocol.Write(conn, protocol.RegisterRequestMessage{Provider: provider, Host: host, Repos: repos, GithubUser: user})
+	require.NoError(t, err)
+
+	go func() {
+		for {
+			var msg protocol.Message
+			if err := protocol.Read(conn, &msg); err != nil {
+				return
+			}
+			req, ok := msg.Message.(protocol.ApproveRequestMessage)
+			if !ok {
+				continue
+			}
+			resp := handler(conn, req)
+			if resp == nil {
+				continue
+			}
+			if err := protocol.WriteWithRequestID(conn, *resp, msg.RequestID); err != nil {
+				return
+			}
+		}
+	}()
+
+	require.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, c := range s.clientsByRepo[forge.RepoID(provider, host, repos[0])] {
+			if c.githubUser == user {
+				return true
+			}
+		}
+		return false
+	}, 2*time.Second, 10*time.Millisecond)
+	return conn
+}
+
+// respondWith returns an approveHandler always answering with the given response type.
+func respondWith(response protocol.ApproveResponseType) approveHandler {
+	return func(*websocket.Conn, protocol.ApproveRequestMessage) *protocol.ApproveResponseMessage {
+		return &protocol.ApproveResponseMessage{Response: response}
+	}
+}
+
+// neverRespond is an approveHandler that never answers.
+func neverRespond(*websocket.Conn, protocol.ApproveRequestMessage) *protocol.ApproveResponseMessage {
+	return nil
+}
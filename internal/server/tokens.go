@@ -0,0 +1,321 @@
+package server
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrAPITokenNotFound is returned when a token does not exist or belongs to another user.
+	ErrAPITokenNotFound = fmt.Errorf("API token not found")
+	// ErrInvalidAPIToken is returned when a secret does not match any token.
+	ErrInvalidAPIToken = fmt.Errorf("invalid API token")
+	// ErrInvalidAPITokenName is returned when the name of a new token is empty or too long.
+	ErrInvalidAPITokenName = fmt.Errorf("invalid token name")
+)
+
+const (
+	// apiTokenPrefix prefixes the secrets of the API tokens so that they are easy to recognize, e.g. by secret scanners.
+	apiTokenPrefix = "lgtm_"
+	// maxAPITokenNameLength is the maximum number of characters of the name of a token.
+	maxAPITokenNameLength = 100
+	// defaultAPITokenTTL is how long the API tokens are valid after being issued.
+	defaultAPITokenTTL = 90 * 24 * time.Hour
+	// accessTokenKeyContext separates the key encrypting the GitHub access token from the hash of the secret,
+	// both are derived from the secret of the API token.
+	accessTokenKeyContext = "lgtm API token access token encryption\x00"
+)
+
+// APIToken is a personal token authenticating the requests to the API on behalf of a GitHub user.
+type APIToken struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Username string `json:"username"`
+	// CreatedAt is the time the token was issued.
+	CreatedAt time.Time `json:"created_at"`
+	// LastUsedAt is the last time the token authenticated a request, nil if it was never used.
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	// ExpiresAt is the time the token stops being accepted, nil if it never expires.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// storedAPIToken is an APIToken along with the data that must never leave the server.
+type storedAPIToken struct {
+	APIToken
+	// Hash is the SHA-256 of the secret of the token, the secret itself is only shown once when issued.
+	Hash string `json:"hash"`
+	// EncryptedAccessToken is the GitHub access token of the user when the token was issued, used to call GitHub
+	// on their behalf. It is encrypted with a key derived from the secret, so that it can only be read when the
+	// token authenticates a request, not from the tokens file.
+	EncryptedAccessToken string `json:"encrypted_access_token"`
+}
+
+// TokenStore holds the personal API tokens. If a path is provided, the tokens are persisted on disk after
+// every change so that they survive server restarts. Tokens expire after a TTL.
+type TokenStore struct {
+	path  string
+	ttl   time.Duration
+	clock Clock
+
+	mu     sync.Mutex
+	tokens map[string]*storedAPIToken
+}
+
+// NewTokenStore creates a TokenStore issuing tokens valid for ttl, or forever if ttl is zero. If path is not empty,
+// the tokens previously persisted in that file are loaded. If clock is nil, time.Now is used.
+func NewTokenStore(path string, ttl time.Duration, clock Clock) (*TokenStore, error) {
+	ts := newTokenStore(path, ttl, clock)
+	if path == "" {
+		return ts, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ts, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read tokens file: %w", err)
+	}
+	var tokens []storedAPIToken
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to parse tokens file: %w", err)
+	}
+	for i := range tokens {
+		ts.tokens[tokens[i].ID] = &tokens[i]
+	}
+	return ts, nil
+}
+
+// newTokenStore creates an empty TokenStore persisted in path, if not empty.
+func newTokenStore(path string, ttl time.Duration, clock Clock) *TokenStore {
+	if clock == nil {
+		clock = time.Now
+	}
+	return &TokenStore{
+		path:   path,
+		ttl:    ttl,
+		clock:  clock,
+		tokens: make(map[string]*storedAPIToken),
+	}
+}
+
+// Issue creates a token for the user and returns it along with its secret. The secret cannot be retrieved later.
+// accessToken is the GitHub access token used to call GitHub when the token authenticates a request.
+func (ts *TokenStore) Issue(username, accessToken, name string) (APIToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return APIToken{}, "", fmt.Errorf("%w: it must not be empty", ErrInvalidAPITokenName)
+	}
+	if utf8.RuneCountInString(name) > maxAPITokenNameLength {
+		return APIToken{}, "", fmt.Errorf("%w: it must not exceed %d characters", ErrInvalidAPITokenName, maxAPITokenNameLength)
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return APIToken{}, "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	secret := apiTokenPrefix + hex.EncodeToString(b)
+	encrypted, err := encryptAccessToken(secret, accessToken)
+	if err != nil {
+		return APIToken{}, "", err
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.expireLocked()
+	now := ts.clock()
+	token := &storedAPIToken{
+		APIToken: APIToken{
+			ID:        uuid.NewString(),
+			Name:      name,
+			Username:  username,
+			CreatedAt: now,
+		},
+		Hash:                 hashAPIToken(secret),
+		EncryptedAccessToken: encrypted,
+	}
+	if ts.ttl > 0 {
+		expiresAt := now.Add(ts.ttl)
+		token.ExpiresAt = &expiresAt
+	}
+	ts.tokens[token.ID] = token
+	if err := ts.saveLocked(); err != nil {
+		delete(ts.tokens, token.ID)
+		return APIToken{}, "", err
+	}
+	return token.APIToken, secret, nil
+}
+
+// ListByUser returns the tokens of the user, oldest first.
+func (ts *TokenStore) ListByUser(username string) []APIToken {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.expireLocked()
+
+	var l []APIToken
+	for _, t := range ts.tokens {
+		if t.Username == username {
+			l = append(l, t.APIToken)
+		}
+	}
+	sort.Slice(l, func(i, j int) bool {
+		if l[i].CreatedAt.Equal(l[j].CreatedAt) {
+			return l[i].ID < l[j].ID
+		}
+		return l[i].CreatedAt.Before(l[j].CreatedAt)
+	})
+	return l
+}
+
+// Revoke deletes the token with the given ID. Only the owner of the token can revoke it.
+func (ts *TokenStore) Revoke(id, username string) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	token, ok := ts.tokens[id]
+	if !ok || token.Username != username {
+		return ErrAPITokenNotFound
+	}
+	delete(ts.tokens, id)
+	if err := ts.saveLocked(); err != nil {
+		ts.tokens[id] = token
+		return err
+	}
+	return nil
+}
+
+// Authenticate returns the token matching the secret and the GitHub access token of its owner.
+// ErrInvalidAPIToken is returned if no token matches or if the token expired.
+func (ts *TokenStore) Authenticate(secret string) (APIToken, string, error) {
+	if !strings.HasPrefix(secret, apiTokenPrefix) {
+		return APIToken{}, "", ErrInvalidAPIToken
+	}
+	hash := []byte(hashAPIToken(secret))
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.expireLocked()
+	for _, t := range ts.tokens {
+		if subtle.ConstantTimeCompare(hash, []byte(t.Hash)) == 1 {
+			accessToken, err := decryptAccessToken(secret, t.EncryptedAccessToken)
+			if err != nil {
+				return APIToken{}, "", fmt.Errorf("%w: %w", ErrInvalidAPIToken, err)
+			}
+			// The last use is only persisted with the next change, it is not worth a write per request.
+			now := ts.clock()
+			t.LastUsedAt = &now
+			return t.APIToken, accessToken, nil
+		}
+	}
+	return APIToken{}, "", ErrInvalidAPIToken
+}
+
+// expireLocked forgets the expired tokens, they are removed from the file with the next change.
+// The caller must hold the lock.
+func (ts *TokenStore) expireLocked() {
+	now := ts.clock()
+	for id, t := range ts.tokens {
+		if t.ExpiresAt != nil && !now.Before(*t.ExpiresAt) {
+			delete(ts.tokens, id)
+		}
+	}
+}
+
+// saveLocked persists the tokens on disk if a path is configured. The caller must hold the lock.
+func (ts *TokenStore) saveLocked() error {
+	if ts.path == "" {
+		return nil
+	}
+	tokens := make([]storedAPIToken, 0, len(ts.tokens))
+	for _, t := range ts.tokens {
+		tokens = append(tokens, *t)
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].ID < tokens[j].ID })
+	data, err := json.Marshal(tokens)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tokens: %w", err)
+	}
+
+	// Write to a temporary file first so that a crash never leaves a truncated tokens file.
+	// The temporary file is only readable by the owner, which the tokens file inherits.
+	tmp, err := os.CreateTemp(filepath.Dir(ts.path), filepath.Base(ts.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create tokens file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write tokens file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write tokens file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), ts.path); err != nil {
+		return fmt.Errorf("failed to save tokens file: %w", err)
+	}
+	return nil
+}
+
+// accessTokenCipher returns the AES-256-GCM cipher encrypting the GitHub access token of the token with
+// the given secret.
+func accessTokenCipher(secret string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(accessTokenKeyContext + secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptAccessToken encrypts the GitHub access token with a key derived from the secret of the API token.
+// The result is the base64 encoding of the nonce followed by the ciphertext.
+func encryptAccessToken(secret, accessToken string) (string, error) {
+	aead, err := accessTokenCipher(secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt access token: %w", err)
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to encrypt access token: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(accessToken), nil)), nil
+}
+
+// decryptAccessToken decrypts a GitHub access token encrypted by encryptAccessToken.
+func decryptAccessToken(secret, encrypted string) (string, error) {
+	aead, err := accessTokenCipher(secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt access token: %w", err)
+	}
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil || len(data) < aead.NonceSize() {
+		return "", fmt.Errorf("failed to decrypt access token: malformed ciphertext")
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt access token: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// hashAPIToken returns the hex-encoded SHA-256 of the secret of a token.
+func hashAPIToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
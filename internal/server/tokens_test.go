@@ -0,0 +1,151 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clems4ever/lgtm/internal/protocol"
+	"github.com/clems4ever/lgtm/internal/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenStore(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	ts, err := NewTokenStore("", time.Hour, func() time.Time { return now })
+	require.NoError(t, err)
+
+	_, _, err = ts.Issue("alice", "gh-alice", "  ")
+	require.ErrorIs(t, err, ErrInvalidAPITokenName)
+
+	token, secret, err := ts.Issue("alice", "gh-alice", "ci-bot")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(secret, apiTokenPrefix))
+	require.Equal(t, "alice", token.Username)
+	require.Nil(t, token.LastUsedAt)
+	require.Equal(t, now.Add(time.Hour), *token.ExpiresAt)
+
+	authenticated, accessToken, err := ts.Authenticate(secret)
+	require.NoError(t, err)
+	require.Equal(t, token.ID, authenticated.ID)
+	require.Equal(t, "gh-alice", accessToken)
+	require.Equal(t, now, *ts.ListByUser("alice")[0].LastUsedAt)
+
+	_, _, err = ts.Authenticate(secret + "x")
+	require.ErrorIs(t, err, ErrInvalidAPIToken)
+
+	// Only the owner of a token can revoke it.
+	require.ErrorIs(t, ts.Revoke(token.ID, "bob"), ErrAPITokenNotFound)
+	require.NoError(t, ts.Revoke(token.ID, "alice"))
+	require.Empty(t, ts.ListByUser("alice"))
+	_, _, err = ts.Authenticate(secret)
+	require.ErrorIs(t, err, ErrInvalidAPIToken)
+
+	// Tokens are refused and forgotten once expired.
+	_, secret, err = ts.Issue("alice", "gh-alice", "ci-bot")
+	require.NoError(t, err)
+	now = now.Add(time.Hour)
+	_, _, err = ts.Authenticate(secret)
+	require.ErrorIs(t, err, ErrInvalidAPIToken)
+	require.Empty(t, ts.ListByUser("alice"))
+}
+
+func TestTokenStore_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	ts, err := NewTokenStore(path, 0, nil)
+	require.NoError(t, err)
+
+	token, secret, err := ts.Issue("alice", "gh-alice", "ci-bot")
+	require.NoError(t, err)
+	require.Nil(t, token.ExpiresAt)
+
+	// Neither the secret nor the GitHub access token can be read from the file.
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), secret)
+	require.NotContains(t, string(data), "gh-alice")
+
+	reloaded, err := NewTokenStore(path, 0, nil)
+	require.NoError(t, err)
+	authenticated, accessToken, err := reloaded.Authenticate(secret)
+	require.NoError(t, err)
+	require.Equal(t, token.ID, authenticated.ID)
+	require.Equal(t, "gh-alice", accessToken)
+}
+
+func TestMiddlewareAPITokenAuth(t *testing.T) {
+	s := NewServer(nil, 0, nil, RetryPolicy{})
+	t.Cleanup(s.Close)
+	_, secret, err := s.tokens.Issue("alice", "gh-alice", "ci-bot")
+	require.NoError(t, err)
+
+	handler := s.middlewareAPITokenAuth(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Context().Value("username").(string) + ":" + r.Context().Value("access_token").(string)))
+	})
+
+	for _, authorization := range []string{"", "Bearer lgtm_invalid", secret} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/requests/1", nil)
+		req.Header.Set("Authorization", authorization)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		require.Equal(t, http.StatusUnauthorized, rec.Code, authorization)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/requests/1", nil)
+	req.Header.Set("Authorization", "Bearer "+secret)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "alice:gh-alice", rec.Body.String())
+}
+
+func TestMiddlewareAPITokenAuth_LoginRestriction(t *testing.T) {
+	githubSrv := test.NewGithubMockServer(t, "")
+	t.Cleanup(githubSrv.Close)
+	githubSrv.AddUser("alice", "gh-alice", nil)
+	githubSrv.AddOrgMember("acme", "alice")
+
+	s := NewServer(nil, 0, nil, RetryPolicy{})
+	t.Cleanup(s.Close)
+	s.githubAPIURL = githubSrv.URL()
+	s.loginRestriction = &LoginRestriction{AllowedOrgs: []string{"acme"}}
+	_, secret, err := s.tokens.Issue("alice", "gh-alice", "ci-bot")
+	require.NoError(t, err)
+
+	handler := s.middlewareAPITokenAuth(func(w http.ResponseWriter, r *http.Request) {})
+	call := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/requests/1", nil)
+		req.Header.Set("Authorization", "Bearer "+secret)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec.Code
+	}
+	require.Equal(t, http.StatusOK, call())
+
+	// The token is refused once its owner left the organization and the cached check expired.
+	s.loginRestriction.AllowedOrgs = []string{"other"}
+	require.Equal(t, http.StatusOK, call())
+	s.allowedLoginsMu.Lock()
+	s.allowedLogins["alice"] = time.Now().Add(-loginRecheckInterval)
+	s.allowedLoginsMu.Unlock()
+	require.Equal(t, http.StatusForbidden, call())
+}
+
+func TestRepoApprovers(t *testing.T) {
+	s, wsURL := newTestServer(t, nil, RetryPolicy{RPCTimeout: time.Second})
+	ps, err := NewPolicyStore(writePolicyFile(t, testPolicy))
+	require.NoError(t, err)
+	s.policy = ps
+
+	connectFakeApprover(t, s, wsURL, "bob", []string{"foo/bar"}, respondWith(protocol.ApproveResponseSuccess))
+	connectFakeApprover(t, s, wsURL, "alice", []string{"foo/bar"}, respondWith(protocol.ApproveResponseSuccess))
+	connectFakeApprover(t, s, wsURL, "alice", []string{"foo/bar"}, respondWith(protocol.ApproveResponseSuccess))
+
+	// bob is denied by the policy and alice is only listed once.
+	require.Equal(t, []string{"alice"}, s.RepoApprovers("foo/bar"))
+	require.Empty(t, s.RepoApprovers("foo/baz"))
+}
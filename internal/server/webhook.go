@@ -0,0 +1,270 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/clems4ever/lgtm/internal/api"
+	"github.com/clems4ever/lgtm/internal/github"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidWebhookSignature is returned when the signature of a webhook does not match its payload.
+	ErrInvalidWebhookSignature = fmt.Errorf("invalid webhook signature")
+)
+
+const (
+	// defaultWebhookLabel is the label triggering an approval request when added to a PR.
+	defaultWebhookLabel = "needs-lgtm"
+	// defaultWebhookCommand is the comment triggering an approval request when posted on a PR.
+	defaultWebhookCommand = "/lgtm please"
+	// maxWebhookPayloadSize is the maximum size of the webhook payloads sent by GitHub.
+	maxWebhookPayloadSize = 25 << 20
+	// webhookDeliveryRetention is how long the IDs of the webhook deliveries are remembered to ignore redeliveries.
+	// GitHub lets deliveries of the last 3 days be redelivered.
+	webhookDeliveryRetention = 72 * time.Hour
+)
+
+// publicWebhookErrors are the errors whose message can be posted on the PR. The other errors may leak details of
+// the server and are only logged.
+var publicWebhookErrors = []error{
+	ErrSubmitterNotAllowed,
+	ErrRequesterNotAllowed,
+	ErrJustificationTooLong,
+	ErrChecksNotGreen,
+	ErrHeadMoved,
+	ErrNoEligibleApprover,
+	ErrMaxAttemptsReached,
+	ErrRoutingDeadlineExceeded,
+	ErrNotEnoughApprovals,
+	ErrRequestCancelled,
+	ErrRequestExpired,
+}
+
+// WebhookConfig configures the GitHub webhooks triggering approval requests.
+type WebhookConfig struct {
+	// Secret is the secret shared with GitHub to sign the payloads.
+	Secret string
+	// Label triggers an approval request when added to a PR, disabled if empty.
+	Label string
+	// Command triggers an approval request when posted as the first line of a comment on a PR, disabled if empty.
+	// The following lines of the comment are forwarded to the approvers as the justification.
+	Command string
+}
+
+// webhookTrigger is an approval request triggered by a webhook.
+type webhookTrigger struct {
+	Link          github.PRLink
+	Requester     string
+	Justification string
+}
+
+// webhookPayload holds the fields of the pull_request and issue_comment payloads used to trigger approval requests.
+type webhookPayload struct {
+	Action string `json:"action"`
+	Label  *struct {
+		Name string `json:"name"`
+	} `json:"label"`
+	PullRequest *struct {
+		Number int    `json:"number"`
+		State  string `json:"state"`
+	} `json:"pull_request"`
+	Issue *struct {
+		Number int    `json:"number"`
+		State  string `json:"state"`
+		// PullRequest is only set if the issue is a pull request.
+		PullRequest *struct {
+			URL string `json:"url"`
+		} `json:"pull_request"`
+	} `json:"issue"`
+	Comment *struct {
+		Body string `json:"body"`
+	} `json:"comment"`
+	Repository struct {
+		Name  string `json:"name"`
+		Owner struct {
+			Login string `json:"login"`
+		} `json:"owner"`
+	} `json:"repository"`
+	Sender struct {
+		Login string `json:"login"`
+		Type  string `json:"type"`
+	} `json:"sender"`
+}
+
+// verifyWebhookSignature checks the X-Hub-Signature-256 header sent by GitHub, the hex-encoded HMAC-SHA256
+// of the payload keyed with the secret and prefixed by "sha256=".
+func verifyWebhookSignature(secret, signature string, payload []byte) error {
+	sig, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return ErrInvalidWebhookSignature
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return ErrInvalidWebhookSignature
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return ErrInvalidWebhookSignature
+	}
+	return nil
+}
+
+// parseTrigger returns the approval request triggered by the webhook event, nil if the event does not trigger any.
+// Approval requests are triggered by adding the configured label to an open PR or by commenting the configured
+// command on an open PR. Events sent by bots are ignored.
+func (c *WebhookConfig) parseTrigger(event string, payload []byte) (*webhookTrigger, error) {
+	if event != "pull_request" && event != "issue_comment" {
+		return nil, nil
+	}
+	var p webhookPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse %s payload: %w", event, err)
+	}
+	if p.Sender.Type == "Bot" {
+		return nil, nil
+	}
+
+	trigger := &webhookTrigger{Requester: p.Sender.Login}
+	trigger.Link.Owner = p.Repository.Owner.Login
+	trigger.Link.Repo = p.Repository.Name
+
+	switch event {
+	case "pull_request":
+		if c.Label == "" || p.Action != "labeled" || p.Label == nil || p.PullRequest == nil {
+			return nil, nil
+		}
+		if !strings.EqualFold(p.Label.Name, c.Label) || p.PullRequest.State != "open" {
+			return nil, nil
+		}
+		trigger.Link.PRNumber = p.PullRequest.Number
+	case "issue_comment":
+		if c.Command == "" || p.Action != "created" || p.Comment == nil || p.Issue == nil || p.Issue.PullRequest == nil {
+			return nil, nil
+		}
+		command, justification, _ := strings.Cut(strings.TrimSpace(p.Comment.Body), "\n")
+		if !strings.EqualFold(strings.TrimSpace(command), c.Command) || p.Issue.State != "open" {
+			return nil, nil
+		}
+		trigger.Link.PRNumber = p.Issue.Number
+		trigger.Justification = strings.TrimSpace(justification)
+	}
+	return trigger, nil
+}
+
+// markWebhookDelivery records the delivery and tells whether it is the first time it is received.
+// Deliveries without ID are never considered redelivered.
+func (s *Server) markWebhookDelivery(id string) bool {
+	if id == "" {
+		return true
+	}
+	s.webhookDeliveriesMu.Lock()
+	defer s.webhookDeliveriesMu.Unlock()
+
+	now := time.Now()
+	for d, receivedAt := range s.webhookDeliveries {
+		if now.Sub(receivedAt) >= webhookDeliveryRetention {
+			delete(s.webhookDeliveries, d)
+		}
+	}
+	if _, ok := s.webhookDeliveries[id]; ok {
+		return false
+	}
+	s.webhookDeliveries[id] = now
+	return true
+}
+
+// processWebhookTrigger submits the approval request triggered by a webhook on behalf of its requester,
+// waits for the outcome and posts it as a comment on the PR.
+// The requester is checked like the submitters of the web UI, with the server's GitHub client.
+func (s *Server) processWebhookTrigger(trigger webhookTrigger) {
+	result, queued, err := s.submitWebhookTrigger(trigger)
+	if err != nil {
+		log.Printf("failed to approve %s requested by %s through a webhook: %s", trigger.Link, trigger.Requester, err)
+	}
+	s.commentWebhookOutcome(trigger.Link, trigger.Requester, result, queued, err)
+}
+
+// commentWebhookOutcome posts the outcome of an approval request triggered by a webhook as a comment on the PR.
+func (s *Server) commentWebhookOutcome(link github.PRLink, requester string, result ApprovalResult, queued *QueuedRequest, err error) {
+	comment := formatWebhookOutcome(requester, result, queued, err)
+	if err := s.githubClient.CreateComment(link, comment); err != nil {
+		log.Printf("failed to comment the outcome on %s: %s", link, err)
+	}
+}
+
+// submitWebhookTrigger checks and routes the approval request triggered by a webhook.
+func (s *Server) submitWebhookTrigger(trigger webhookTrigger) (ApprovalResult, *QueuedRequest, error) {
+	if utf8.RuneCountInString(trigger.Justification) > maxJustificationLength {
+		return ApprovalResult{}, nil, fmt.Errorf("%w: at most %d characters", ErrJustificationTooLong, maxJustificationLength)
+	}
+
+	// Anyone can comment on the PRs of public repositories, the requester must be allowed to log in
+	allowed, err := s.loginRestriction.IsUserAllowed(s.githubClient, trigger.Requester)
+	if err != nil {
+		return ApprovalResult{}, nil, fmt.Errorf("failed to check memberships: %w", err)
+	}
+	if !allowed {
+		return ApprovalResult{}, nil, fmt.Errorf("%w: %s is not a member of an allowed organization or team",
+			ErrSubmitterNotAllowed, trigger.Requester)
+	}
+
+	pr, err := s.githubClient.GetPullRequest(trigger.Link)
+	if err != nil {
+		return ApprovalResult{}, nil, fmt.Errorf("failed to get pull request: %w", err)
+	}
+	if err := s.verifySubmitterAsServer(trigger.Link, pr, trigger.Requester); err != nil {
+		return ApprovalResult{}, nil, err
+	}
+
+	req, err := s.prepareSubmission(api.ApprovalRequest{
+		Link:          trigger.Link,
+		Requester:     trigger.Requester,
+		HeadSHA:       pr.Head.SHA,
+		Justification: trigger.Justification,
+		RequestedAt:   time.Now(),
+		Source:        api.RequestSourceWebhook,
+	})
+	if err != nil {
+		return ApprovalResult{}, nil, err
+	}
+	return s.routeOrQueue(s.requests.Routing(uuid.NewString(), req))
+}
+
+// formatWebhookOutcome renders the comment reporting the outcome of an approval request triggered by a webhook.
+func formatWebhookOutcome(requester string, result ApprovalResult, queued *QueuedRequest, err error) string {
+	switch {
+	case err != nil:
+		return fmt.Sprintf("@%s ❌ lgtm could not approve this PR: %s", requester, publicWebhookError(err))
+	case queued != nil:
+		return fmt.Sprintf("@%s ⏳ No approver is online, the approval request is queued until %s.",
+			requester, queued.ExpiresAt.UTC().Format(time.RFC3339))
+	default:
+		approvers := make([]string, 0, len(result.Approvers))
+		for _, a := range result.Approvers {
+			approvers = append(approvers, "@"+a)
+		}
+		return fmt.Sprintf("@%s ✔ This PR has been approved by %s.", requester, strings.Join(approvers, ", "))
+	}
+}
+
+// publicWebhookError returns the message of the error that can be posted on the PR: the message of the first
+// public error it wraps, without the details added by the wrapping errors.
+func publicWebhookError(err error) string {
+	for _, public := range publicWebhookErrors {
+		if errors.Is(err, public) {
+			return public.Error()
+		}
+	}
+	return "internal error, see the logs of the server"
+}
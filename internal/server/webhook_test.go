@@ -0,0 +1,207 @@
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/clems4ever/lgtm/internal/github"
+	"github.com/clems4ever/lgtm/internal/protocol"
+	"github.com/clems4ever/lgtm/internal/test"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+const testWebhookSecret = "webhook-secret"
+
+// replayWebhook sends a recorded webhook payload to the handler as a new delivery, signed with the given secret.
+func replayWebhook(t *testing.T, s *Server, event, file, secret string) *httptest.ResponseRecorder {
+	t.Helper()
+	return replayWebhookDelivery(t, s, event, file, secret, uuid.NewString())
+}
+
+// replayWebhookDelivery is like replayWebhook with the given delivery ID.
+func replayWebhookDelivery(t *testing.T, s *Server, event, file, secret, delivery string) *httptest.ResponseRecorder {
+	t.Helper()
+	payload, err := os.ReadFile(filepath.Join("testdata", "webhooks", file))
+	require.NoError(t, err)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(payload))
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-GitHub-Delivery", delivery)
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	rec := httptest.NewRecorder()
+	s.handlerWebhook(rec, req)
+	return rec
+}
+
+// newWebhookTestServer starts a Server accepting webhooks, backed by a mock of GitHub where octocat
+// is a collaborator of foo/bar.
+func newWebhookTestServer(t *testing.T) (*Server, string, *test.GithubMockServer) {
+	t.Helper()
+	githubSrv := test.NewGithubMockServer(t, "")
+	t.Cleanup(githubSrv.Close)
+	githubSrv.AddUser("lgtm-bot", "server-token", nil)
+	githubSrv.AddUser("octocat", "octocat-token", []test.Repo{{FullName: "foo/bar", Permissions: test.RepoPermissions{Push: true}}})
+	githubSrv.SetPRHead("foo/bar", 1, "abc")
+
+	s, wsURL := newTestServer(t, nil, RetryPolicy{RPCTimeout: time.Second})
+	s.githubClient = github.NewClient("server-token", githubSrv.URL(), nil)
+	s.webhook = &WebhookConfig{Secret: testWebhookSecret, Label: defaultWebhookLabel, Command: defaultWebhookCommand}
+	return s, wsURL, githubSrv
+}
+
+func TestWebhook_LabelTriggersApproval(t *testing.T) {
+	s, wsURL, githubSrv := newWebhookTestServer(t)
+	requests := make(chan protocol.ApproveRequestMessage, 1)
+	connectFakeApprover(t, s, wsURL, "alice", []string{"foo/bar"},
+		func(_ *websocket.Conn, msg protocol.ApproveRequestMessage) *protocol.ApproveResponseMessage {
+			requests <- msg
+			return &protocol.ApproveResponseMessage{Response: protocol.ApproveResponseSuccess}
+		})
+
+	rec := replayWebhook(t, s, "pull_request", "pull_request_labeled.json", testWebhookSecret)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+
+	msg := <-requests
+	require.Equal(t, "octocat", msg.Requester)
+	require.Equal(t, "abc", msg.HeadSHA)
+	require.Eventually(t, func() bool {
+		return len(githubSrv.Comments("foo/bar", 1)) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	require.Equal(t, "@octocat ✔ This PR has been approved by @alice.", githubSrv.Comments("foo/bar", 1)[0])
+}
+
+func TestWebhook_CommentTriggersApproval(t *testing.T) {
+	s, wsURL, githubSrv := newWebhookTestServer(t)
+	requests := make(chan protocol.ApproveRequestMessage, 1)
+	connectFakeApprover(t, s, wsURL, "alice", []string{"foo/bar"},
+		func(_ *websocket.Conn, msg protocol.ApproveRequestMessage) *protocol.ApproveResponseMessage {
+			requests <- msg
+			return &protocol.ApproveResponseMessage{Response: protocol.ApproveResponseErrFailed, Reason: "not today"}
+		})
+
+	rec := replayWebhook(t, s, "issue_comment", "issue_comment_created.json", testWebhookSecret)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+
+	// The lines following the command are the justification.
+	msg := <-requests
+	require.Equal(t, "Hotfix for the outage of the exporter", msg.Justification)
+	require.Eventually(t, func() bool {
+		return len(githubSrv.Comments("foo/bar", 1)) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	require.Contains(t, githubSrv.Comments("foo/bar", 1)[0], "@octocat ❌ lgtm could not approve this PR")
+}
+
+func TestWebhook_IgnoredEvents(t *testing.T) {
+	s, _, _ := newWebhookTestServer(t)
+
+	// Comments on issues are not PR comments.
+	rec := replayWebhook(t, s, "issue_comment", "issue_comment_on_issue.json", testWebhookSecret)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	// Other labels do not trigger approvals.
+	s.webhook.Label = "other"
+	rec = replayWebhook(t, s, "pull_request", "pull_request_labeled.json", testWebhookSecret)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = replayWebhook(t, s, "push", "pull_request_labeled.json", testWebhookSecret)
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestWebhook_RejectsInvalidSignature(t *testing.T) {
+	s, _, githubSrv := newWebhookTestServer(t)
+
+	rec := replayWebhook(t, s, "pull_request", "pull_request_labeled.json", "wrong-secret")
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.Empty(t, githubSrv.Comments("foo/bar", 1))
+}
+
+func TestWebhook_RequesterMustBeCollaborator(t *testing.T) {
+	s, _, githubSrv := newWebhookTestServer(t)
+	githubSrv.AddUser("octocat", "octocat-token", nil)
+
+	rec := replayWebhook(t, s, "pull_request", "pull_request_labeled.json", testWebhookSecret)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	require.Eventually(t, func() bool {
+		return len(githubSrv.Comments("foo/bar", 1)) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	require.Equal(t, "@octocat ❌ lgtm could not approve this PR: submitter not allowed", githubSrv.Comments("foo/bar", 1)[0])
+}
+
+func TestWebhook_RequesterMustPassLoginRestriction(t *testing.T) {
+	s, _, githubSrv := newWebhookTestServer(t)
+	s.loginRestriction = &LoginRestriction{AllowedOrgs: []string{"foo"}}
+
+	rec := replayWebhook(t, s, "pull_request", "pull_request_labeled.json", testWebhookSecret)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	require.Eventually(t, func() bool {
+		return len(githubSrv.Comments("foo/bar", 1)) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	require.Equal(t, "@octocat ❌ lgtm could not approve this PR: submitter not allowed", githubSrv.Comments("foo/bar", 1)[0])
+
+	// Members of the allowed organizations can trigger approvals
+	githubSrv.AddOrgMember("foo", "octocat")
+	rec = replayWebhook(t, s, "pull_request", "pull_request_labeled.json", testWebhookSecret)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	require.Eventually(t, func() bool {
+		return len(githubSrv.Comments("foo/bar", 1)) == 2
+	}, 2*time.Second, 10*time.Millisecond)
+	require.Equal(t, "@octocat ❌ lgtm could not approve this PR: no eligible approver", githubSrv.Comments("foo/bar", 1)[1])
+}
+
+func TestWebhook_IgnoresRedeliveries(t *testing.T) {
+	s, _, githubSrv := newWebhookTestServer(t)
+
+	rec := replayWebhookDelivery(t, s, "pull_request", "pull_request_labeled.json", testWebhookSecret, "delivery-1")
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	rec = replayWebhookDelivery(t, s, "pull_request", "pull_request_labeled.json", testWebhookSecret, "delivery-1")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	require.Eventually(t, func() bool {
+		return len(githubSrv.Comments("foo/bar", 1)) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	require.Never(t, func() bool {
+		return len(githubSrv.Comments("foo/bar", 1)) > 1
+	}, 200*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestWebhook_CommentsQueuedOutcome(t *testing.T) {
+	s, wsURL, githubSrv := newWebhookTestServer(t)
+	queue, err := NewPendingQueue("", time.Hour, nil)
+	require.NoError(t, err)
+	s.queue = queue
+
+	rec := replayWebhook(t, s, "pull_request", "pull_request_labeled.json", testWebhookSecret)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	require.Eventually(t, func() bool {
+		return len(githubSrv.Comments("foo/bar", 1)) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	require.Contains(t, githubSrv.Comments("foo/bar", 1)[0], "the approval request is queued")
+
+	// The outcome is commented once an approver connects
+	connectFakeApprover(t, s, wsURL, "alice", []string{"foo/bar"}, respondWith(protocol.ApproveResponseSuccess))
+	require.Eventually(t, func() bool {
+		return len(githubSrv.Comments("foo/bar", 1)) == 2
+	}, 2*time.Second, 10*time.Millisecond)
+	require.Equal(t, "@octocat ✔ This PR has been approved by @alice.", githubSrv.Comments("foo/bar", 1)[1])
+}
+
+func TestFormatWebhookOutcome_HidesInternalErrors(t *testing.T) {
+	comment := formatWebhookOutcome("octocat", ApprovalResult{}, nil, fmt.Errorf("failed to get pull request: GitHub API error: secret"))
+	require.Equal(t, "@octocat ❌ lgtm could not approve this PR: internal error, see the logs of the server", comment)
+
+	comment = formatWebhookOutcome("octocat", ApprovalResult{}, nil, fmt.Errorf("%w: checks failed on the runner", ErrChecksNotGreen))
+	require.Equal(t, "@octocat ❌ lgtm could not approve this PR: checks are not green", comment)
+}
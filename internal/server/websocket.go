@@ -4,18 +4,13 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/clems4ever/lgtm/internal/github"
+	"github.com/clems4ever/lgtm/internal/forge"
 	"github.com/clems4ever/lgtm/internal/protocol"
 	"github.com/google/uuid"
-	"github.com/gorilla/websocket"
-)
-
-var (
-	// ErrNoEligibleApprover is returned when no eligible approver is found for a PR.
-	ErrNoEligibleApprover = fmt.Errorf("no eligible approver")
 )
 
 // wsHandler handles WebSocket connections for client registration and PR approval requests.
@@ -61,7 +56,7 @@ func (s *Server) wsHandler(w http.ResponseWriter, r *http.Request) {
 			s.asyncRequestsMu.Lock()
 			req, ok := s.asyncRequests[message.RequestID]
 			if ok {
-				s.handleAsyncResponse(message.RequestID, req, message.Message)
+				s.handleAsyncResponse(message.RequestID, req.future, message.Message)
 				s.asyncRequestsMu.Unlock()
 				continue
 			}
@@ -74,11 +69,13 @@ func (s *Server) wsHandler(w http.ResponseWriter, r *http.Request) {
 	if s.pingInterval > 0 {
 		wg.Add(1)
 		go func() {
+			defer wg.Done()
 			ticker := time.NewTicker(s.pingInterval)
+			defer ticker.Stop()
 			for {
 				select {
 				case <-ticker.C:
-					_, err := protocol.Write(conn, protocol.PingMessage{})
+					err := info.write(protocol.PingMessage{}, uuid.NewString())
 					if err != nil {
 						log.Println("failed to ping")
 					}
@@ -97,21 +94,21 @@ func (s *Server) wsHandler(w http.ResponseWriter, r *http.Request) {
 	delete(s.clientInfoByConn, conn)
 	// Remove the client from all repositories it was registered for
 	for repo := range info.repos {
-		list := s.clientsByRepo[repo]
-		newList := make([]*clientInfo, 0, len(list))
-		for _, c := range list {
-			if c != &info {
-				newList = append(newList, c)
-			}
-		}
-		if len(newList) == 0 {
-			delete(s.clientsByRepo, repo)
-		} else {
-			s.clientsByRepo[repo] = newList
-		}
+		s.unregisterRepo(&info, repo)
 	}
 	s.mu.Unlock()
 
+	// Fail the requests still waiting for a response from this client so that they can be
+	// routed to another approver without waiting for the timeout.
+	s.asyncRequestsMu.Lock()
+	for requestID, req := range s.asyncRequests {
+		if req.conn == conn {
+			req.future.Close()
+			s.cleanupAsyncRequest(requestID)
+		}
+	}
+	s.asyncRequestsMu.Unlock()
+
 	s.approvalEngine.RemoveApprover(info.githubUser)
 	if info.githubUser != "" {
 		log.Printf("client disconnected (user %s)\n", info.githubUser)
@@ -127,7 +124,16 @@ func (s *Server) handleAsyncMessage(msg any, info *clientInfo) {
 		err := s.handleRegisterRequestMessage(v, info)
 		if err != nil {
 			log.Printf("failed to handle message: %s", err)
+			return
 		}
+		s.deliverQueuedRequests(registeredRepoIDs(v))
+	case protocol.UpdateRegistrationMessage:
+		added, err := s.handleUpdateRegistrationMessage(v, info)
+		if err != nil {
+			log.Printf("failed to handle message: %s", err)
+			return
+		}
+		s.deliverQueuedRequests(added)
 	case protocol.PingMessage:
 		// do nothing here, we just make sure the message is supported.
 	default:
@@ -141,83 +147,31 @@ func (s *Server) handleAsyncResponse(requestID string, future *protocol.Response
 	s.cleanupAsyncRequest(requestID)
 }
 
-// RequestApproval forwards a pull request approval request to an eligible approver.
-// It selects a random approver from the list of connected clients who are registered for the target repository.
-// If no eligible approver is found, returns ErrNoEligibleApprover.
-func (s *Server) RequestApproval(link github.PRLink) error {
-	fmt.Println("need to forward approval link:", link)
-	targetRepo := link.RepoFullName()
-
-	s.mu.Lock()
-	eligible := []*clientInfo{}
-	eligible = append(eligible, s.clientsByRepo[targetRepo]...)
-	s.mu.Unlock()
-
-	// TODO: rewrite this without recursion.
-	return s.routePRApprovalRequestRecursive(link, eligible)
-}
-
-// routePRApprovalRequestRecursive tries to forward the approval request to eligible clients, recursively excluding authors.
-func (s *Server) routePRApprovalRequestRecursive(link github.PRLink, eligible []*clientInfo) error {
-	if len(eligible) == 0 {
-		fmt.Printf("no eligible approver for %s\n", link)
-		return ErrNoEligibleApprover
-	}
-
-	// Select a random eligible client
-	idx := 0
-	if len(eligible) > 1 {
-		idx = int(time.Now().UnixNano()) % len(eligible)
-	}
-	selected := eligible[idx]
-
-	fmt.Printf("%s will tentatively be approved by %s\n", link, selected.githubUser)
-
-	res, _, err := s.sendRPC(selected.conn, protocol.ApproveRequestMessage{
-		Link: link,
-	}, 10*time.Second)
-	if err != nil {
-		return fmt.Errorf("failed to send rpc call: %w", err)
-	}
-
-	// Wait for the approval response
-	var resp protocol.ApproveResponseMessage
-	err = res.WaitResponse(&resp)
-	if err != nil {
-		return fmt.Errorf("failed to receive async response: %w", err)
-	}
-
-	switch resp.Response {
-	case protocol.ApproveResponseSuccess:
-		fmt.Printf("%s approved by %s\n", link, selected.githubUser)
-		return nil
-	case protocol.ApproveResponseErrSameAuthor:
-		fmt.Printf("%s not approved by author %s\n", link, selected.githubUser)
-		// Create a reduced list excluding the author
-		reducedList := make([]*clientInfo, 0, len(eligible))
-		for _, c := range eligible {
-			if c.githubUser == selected.githubUser {
-				continue
-			}
-			reducedList = append(reducedList, c)
-		}
-		return s.routePRApprovalRequestRecursive(link, reducedList)
-	}
-	return fmt.Errorf("%s", resp.Response)
+// write sends a message to the client. It is safe to call from several goroutines.
+func (c *clientInfo) write(msg any, requestID string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return protocol.WriteWithRequestID(c.conn, msg, requestID)
 }
 
 // sendRPC sends an approval request message to a client and sets up a ResponseFuture for the response.
-func (s *Server) sendRPC(conn *websocket.Conn, msg protocol.ApproveRequestMessage, timeout time.Duration) (*protocol.ResponseFuture, string, error) {
+// The future is closed if no response is received before the timeout or if the client disconnects.
+func (s *Server) sendRPC(client *clientInfo, msg protocol.ApproveRequestMessage, timeout time.Duration) (*protocol.ResponseFuture, string, error) {
 	requestID := uuid.NewString()
-	err := protocol.WriteWithRequestID(conn, msg, requestID)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to write request with id: %w", err)
-	}
 
+	// Register the future before writing the request so that a fast response cannot be missed.
 	res := protocol.NewResponseFuture()
 	s.asyncRequestsMu.Lock()
-	s.asyncRequests[requestID] = res
+	s.asyncRequests[requestID] = &pendingRequest{future: res, conn: client.conn}
 	s.asyncRequestsMu.Unlock()
+
+	err := client.write(msg, requestID)
+	if err != nil {
+		s.asyncRequestsMu.Lock()
+		s.cleanupAsyncRequest(requestID)
+		s.asyncRequestsMu.Unlock()
+		return nil, "", fmt.Errorf("failed to write request with id: %w", err)
+	}
 	s.wg.Add(1)
 
 	go func() {
@@ -225,8 +179,8 @@ func (s *Server) sendRPC(conn *websocket.Conn, msg protocol.ApproveRequestMessag
 		select {
 		case <-s.ctx.Done():
 		case <-time.After(timeout):
-			res.Close()
 		}
+		res.Close()
 		s.asyncRequestsMu.Lock()
 		s.cleanupAsyncRequest(requestID)
 		s.asyncRequestsMu.Unlock()
@@ -240,22 +194,116 @@ func (s *Server) cleanupAsyncRequest(reqID string) {
 }
 
 // handleRegisterRequestMessage processes a registration message from a client and updates the server state.
+// A new registration on the same connection replaces the previous one.
 func (s *Server) handleRegisterRequestMessage(msg protocol.RegisterRequestMessage, info *clientInfo) error {
 	log.Printf("client registered with handle %s\n", msg.GithubUser)
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Store the client's repositories and GitHub user
-	for _, repo := range msg.Repos {
+	// Drop the previous registration so that the client is never listed twice for a repository
+	if info.githubUser != "" {
+		for repo := range info.repos {
+			s.unregisterRepo(info, repo)
+		}
+		clear(info.repos)
+		s.approvalEngine.RemoveApprover(info.githubUser)
+	}
+
+	// Store the client's repositories, namespaced by GitHub host, and GitHub user
+	repos := registeredRepoIDs(msg)
+	for _, repo := range repos {
 		info.repos[repo] = struct{}{}
 	}
 	info.githubUser = msg.GithubUser
+	info.provider, info.host = msg.Provider, msg.Host
 
 	// Update global state with the new client
-	for _, repo := range msg.Repos {
+	for _, repo := range repos {
 		s.clientsByRepo[repo] = append(s.clientsByRepo[repo], info)
 	}
 
 	s.approvalEngine.AddApprover(msg.GithubUser)
 	return nil
 }
+
+// handleUpdateRegistrationMessage adds and removes repositories of the registration of a client at once, so that
+// routing never sees a partial update. It returns the repositories the client was not registered for yet.
+func (s *Server) handleUpdateRegistrationMessage(msg protocol.UpdateRegistrationMessage, info *clientInfo) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if info.githubUser == "" {
+		return nil, fmt.Errorf("registration update received before registration")
+	}
+	removed := 0
+	for _, repo := range msg.Removed {
+		id := forge.RepoID(info.provider, info.host, repo)
+		if _, ok := info.repos[id]; ok {
+			delete(info.repos, id)
+			s.unregisterRepo(info, id)
+			removed++
+		}
+	}
+	var added []string
+	for _, repo := range msg.Added {
+		id := forge.RepoID(info.provider, info.host, repo)
+		if _, ok := info.repos[id]; !ok {
+			info.repos[id] = struct{}{}
+			s.clientsByRepo[id] = append(s.clientsByRepo[id], info)
+			added = append(added, id)
+		}
+	}
+	log.Printf("client %s updated its registration: %d repositories added, %d removed\n",
+		info.githubUser, len(added), removed)
+	return added, nil
+}
+
+// unregisterRepo removes the client from the approvers of the repository. s.mu must be held.
+func (s *Server) unregisterRepo(info *clientInfo, repo string) {
+	list := s.clientsByRepo[repo]
+	newList := make([]*clientInfo, 0, len(list))
+	for _, c := range list {
+		if c != info {
+			newList = append(newList, c)
+		}
+	}
+	if len(newList) == 0 {
+		delete(s.clientsByRepo, repo)
+	} else {
+		s.clientsByRepo[repo] = newList
+	}
+}
+
+// RefreshRegistrations asks the connected clients of the user to discover their repositories again, e.g. after
+// the user was granted access to new repositories. The clients update their registration if they changed.
+// It returns the number of clients asked.
+func (s *Server) RefreshRegistrations(user string) int {
+	s.mu.Lock()
+	var clients []*clientInfo
+	for _, info := range s.clientInfoByConn {
+		if info.githubUser != "" && strings.EqualFold(info.githubUser, user) {
+			clients = append(clients, info)
+		}
+	}
+	s.mu.Unlock()
+
+	refreshed := 0
+	for _, client := range clients {
+		if err := client.write(protocol.RefreshRegistrationMessage{}, uuid.NewString()); err != nil {
+			log.Printf("failed to ask a client of %s to refresh its registration: %s", user, err)
+			continue
+		}
+		refreshed++
+	}
+	return refreshed
+}
+
+// registeredRepoIDs returns the provider-qualified identifiers of the repositories of a registration, see forge.RepoID.
+// Clients which do not send their provider and host approve the PRs of github.com.
+func registeredRepoIDs(msg protocol.RegisterRequestMessage) []string {
+	ids := make([]string, 0, len(msg.Repos))
+	for _, repo := range msg.Repos {
+		ids = append(ids, forge.RepoID(msg.Provider, msg.Host, repo))
+	}
+	return ids
+}
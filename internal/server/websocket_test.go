@@ -0,0 +1,113 @@
+package server
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/clems4ever/lgtm/internal/protocol"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateRegistration(t *testing.T) {
+	s, wsURL := newTestServer(t, nil, DefaultRetryPolicy())
+	alice := connectFakeApprover(t, s, wsURL, "alice", []string{"foo/bar", "foo/old"}, neverRespond)
+	connectFakeApprover(t, s, wsURL, "bob", []string{"foo/old"}, neverRespond)
+
+	_, err := protocol.Write(alice, protocol.UpdateRegistrationMessage{
+		Added:   []string{"foo/new", "foo/bar"},
+		Removed: []string{"foo/old", "foo/unknown"},
+	})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return len(s.RepoApprovers("foo/new")) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"alice"}, s.RepoApprovers("foo/new"))
+	require.Equal(t, []string{"alice"}, s.RepoApprovers("foo/bar"))
+	require.Equal(t, []string{"bob"}, s.RepoApprovers("foo/old"))
+
+	// A repository is registered once however many times it is added
+	s.mu.Lock()
+	require.Len(t, s.clientsByRepo["github:github.com/foo/bar"], 1)
+	s.mu.Unlock()
+}
+
+func TestUpdateRegistration_BeforeRegistration(t *testing.T) {
+	s, wsURL := newTestServer(t, nil, DefaultRetryPolicy())
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Messages of a connection are handled in order, the update is ignored once the registration is done
+	_, err = protocol.Write(conn, protocol.UpdateRegistrationMessage{Added: []string{"foo/bar"}})
+	require.NoError(t, err)
+	_, err = protocol.Write(conn, protocol.RegisterRequestMessage{Repos: []string{"foo/other"}, GithubUser: "alice"})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return len(s.RepoApprovers("foo/other")) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	require.Empty(t, s.RepoApprovers("foo/bar"))
+}
+
+func TestRegister_ReplacesPreviousRegistration(t *testing.T) {
+	s, wsURL := newTestServer(t, nil, DefaultRetryPolicy())
+	conn := connectFakeApprover(t, s, wsURL, "alice", []string{"foo/bar", "foo/old"}, neverRespond)
+
+	_, err := protocol.Write(conn, protocol.RegisterRequestMessage{Repos: []string{"foo/bar", "foo/new"}, GithubUser: "alice"})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return len(s.RepoApprovers("foo/new")) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	require.Empty(t, s.RepoApprovers("foo/old"))
+
+	s.mu.Lock()
+	require.Len(t, s.clientsByRepo["github:github.com/foo/bar"], 1)
+	s.mu.Unlock()
+
+	// The approver leaves once the connection closes.
+	conn.Close()
+	require.Eventually(t, func() bool {
+		return len(s.approvalEngine.GetApprovers()) == 0
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestRefreshRegistrations(t *testing.T) {
+	s, wsURL := newTestServer(t, nil, DefaultRetryPolicy())
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = protocol.Write(conn, protocol.RegisterRequestMessage{Repos: []string{"foo/bar"}, GithubUser: "alice"})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return len(s.RepoApprovers("foo/bar")) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	require.Equal(t, 0, s.RefreshRegistrations("bob"))
+	require.Equal(t, 1, s.RefreshRegistrations("Alice"))
+
+	var msg protocol.Message
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	require.NoError(t, protocol.Read(conn, &msg))
+	require.IsType(t, protocol.RefreshRegistrationMessage{}, msg.Message)
+}
+
+func TestRefreshRegistrations_ConcurrentWrites(t *testing.T) {
+	s, wsURL := newTestServer(t, nil, DefaultRetryPolicy())
+	connectFakeApprover(t, s, wsURL, "alice", []string{"foo/bar"}, neverRespond)
+
+	// The writes on a connection are serialized, gorilla/websocket panics on concurrent writers
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				s.RefreshRegistrations("alice")
+			}
+		}()
+	}
+	wg.Wait()
+}
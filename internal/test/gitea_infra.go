@@ -0,0 +1,284 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// giteaPageSize is the default maximum number of items per page of Gitea instances.
+const giteaPageSize = 50
+
+// GiteaPullRequest is a pull request served by GiteaMockServer.
+type GiteaPullRequest struct {
+	Title   string
+	Author  string
+	Base    string
+	HeadSHA string
+}
+
+// GiteaReview is a review submitted on a pull request of GiteaMockServer.
+type GiteaReview struct {
+	User     string
+	State    string
+	Body     string
+	CommitID string
+	// Stale is true once the head of the pull request moved after the review.
+	Stale bool
+}
+
+// GiteaMockServer is a test server that mocks the API of a Gitea or Forgejo instance.
+// Repositories are reused from the GitHub mock, whose JSON representation is the same.
+type GiteaMockServer struct {
+	t *testing.T
+
+	mu           sync.Mutex
+	users        map[string]string            // accessToken -> username
+	repos        map[string][]Repo            // username -> []Repo
+	pullRequests map[string]*GiteaPullRequest // "owner/repo/number" -> pull request
+	reviews      map[string][]GiteaReview     // "owner/repo/number" -> reviews, oldest first
+
+	server *httptest.Server
+}
+
+// NewGiteaMockServer creates a new GiteaMockServer. Its API is served under /api/v1.
+func NewGiteaMockServer(t *testing.T) *GiteaMockServer {
+	g := &GiteaMockServer{
+		t:            t,
+		users:        make(map[string]string),
+		repos:        make(map[string][]Repo),
+		pullRequests: make(map[string]*GiteaPullRequest),
+		reviews:      make(map[string][]GiteaReview),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/user", g.handleUser)
+	mux.HandleFunc("/api/v1/user/repos", g.handleUserRepos)
+	mux.HandleFunc("/api/v1/repos/", g.handleRepo)
+	g.server = httptest.NewServer(mux)
+	return g
+}
+
+// AddUser adds a user with a username and associates it with a repos list.
+func (g *GiteaMockServer) AddUser(username, accessToken string, repos []Repo) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.users[accessToken] = username
+	g.repos[username] = repos
+}
+
+// AddPullRequest adds a pull request to the repository ("owner/repo"). Setting it again moves its head,
+// which makes the previous reviews stale.
+func (g *GiteaMockServer) AddPullRequest(repo string, number int, pr GiteaPullRequest) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	key := fmt.Sprintf("%s/%d", repo, number)
+	for i := range g.reviews[key] {
+		if g.reviews[key][i].CommitID != pr.HeadSHA {
+			g.reviews[key][i].Stale = true
+		}
+	}
+	g.pullRequests[key] = &pr
+}
+
+// Reviews returns the reviews submitted on a pull request of the repository ("owner/repo").
+func (g *GiteaMockServer) Reviews(repo string, number int) []GiteaReview {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]GiteaReview{}, g.reviews[fmt.Sprintf("%s/%d", repo, number)]...)
+}
+
+// URL returns the base URL of the mock server.
+func (g *GiteaMockServer) URL() string {
+	return g.server.URL
+}
+
+// APIURL returns the base URL of the API of the mock server.
+func (g *GiteaMockServer) APIURL() string {
+	return g.server.URL + "/api/v1"
+}
+
+// Host returns the host of the mock server, as found in the URLs of its pull requests.
+func (g *GiteaMockServer) Host() string {
+	return strings.TrimPrefix(g.server.URL, "http://")
+}
+
+// Close shuts down the mock server.
+func (g *GiteaMockServer) Close() {
+	g.server.Close()
+}
+
+// --- Handlers ---
+
+// authenticate returns the user of the token of the request, or answers 401 and returns false.
+func (g *GiteaMockServer) authenticate(w http.ResponseWriter, r *http.Request) (string, bool) {
+	g.mu.Lock()
+	username := g.users[extractToken(r)]
+	g.mu.Unlock()
+	if username == "" {
+		http.Error(w, `{"message":"unauthorized"}`, http.StatusUnauthorized)
+		return "", false
+	}
+	return username, true
+}
+
+func (g *GiteaMockServer) handleUser(w http.ResponseWriter, r *http.Request) {
+	username, ok := g.authenticate(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"login": username})
+}
+
+func (g *GiteaMockServer) handleUserRepos(w http.ResponseWriter, r *http.Request) {
+	username, ok := g.authenticate(w, r)
+	if !ok {
+		return
+	}
+	g.mu.Lock()
+	repos := append([]Repo{}, g.repos[username]...)
+	g.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(paginate(r, repos))
+}
+
+func (g *GiteaMockServer) handleRepo(w http.ResponseWriter, r *http.Request) {
+	username, ok := g.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	// Example: /api/v1/repos/{owner}/{repo}/pulls/{number}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1"), "/")
+	if len(parts) < 6 || parts[2] == "" || parts[3] == "" {
+		http.NotFound(w, r)
+		return
+	}
+	repo := parts[2] + "/" + parts[3]
+
+	switch {
+	case parts[4] == "collaborators" && len(parts) == 7 && parts[6] == "permission":
+		// /repos/{owner}/{repo}/collaborators/{username}/permission
+		g.mu.Lock()
+		repos, ok := g.repos[parts[5]]
+		g.mu.Unlock()
+		if !ok {
+			http.Error(w, `{"message":"user does not exist"}`, http.StatusNotFound)
+			return
+		}
+		permission := "none"
+		for _, r := range repos {
+			if r.FullName == repo {
+				permission = "read"
+				if r.Permissions.Push {
+					permission = "write"
+				}
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"permission": permission})
+	case parts[4] == "pulls" && len(parts) == 6 && r.Method == http.MethodGet:
+		// /repos/{owner}/{repo}/pulls/{number}
+		g.mu.Lock()
+		pr, ok := g.pullRequests[repo+"/"+parts[5]]
+		g.mu.Unlock()
+		if !ok {
+			http.Error(w, `{"message":"pull request does not exist"}`, http.StatusNotFound)
+			return
+		}
+		number, _ := strconv.Atoi(parts[5])
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"number": number,
+			"title":  pr.Title,
+			"state":  "open",
+			"user":   map[string]string{"login": pr.Author},
+			"base":   map[string]string{"ref": pr.Base},
+			"head":   map[string]string{"sha": pr.HeadSHA},
+		})
+	case parts[4] == "pulls" && len(parts) == 7 && parts[6] == "reviews" && r.Method == http.MethodGet:
+		// /repos/{owner}/{repo}/pulls/{number}/reviews
+		g.mu.Lock()
+		reviews := append([]GiteaReview{}, g.reviews[repo+"/"+parts[5]]...)
+		g.mu.Unlock()
+		type review struct {
+			User struct {
+				Login string `json:"login"`
+			} `json:"user"`
+			State    string `json:"state"`
+			Body     string `json:"body"`
+			CommitID string `json:"commit_id"`
+			Stale    bool   `json:"stale"`
+		}
+		l := []review{}
+		for _, r := range reviews {
+			rv := review{State: r.State, Body: r.Body, CommitID: r.CommitID, Stale: r.Stale}
+			rv.User.Login = r.User
+			l = append(l, rv)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(paginate(r, l))
+	case parts[4] == "pulls" && len(parts) == 7 && parts[6] == "reviews" && r.Method == http.MethodPost:
+		// /repos/{owner}/{repo}/pulls/{number}/reviews
+		var body struct {
+			Event    string `json:"event"`
+			Body     string `json:"body"`
+			CommitID string `json:"commit_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		key := repo + "/" + parts[5]
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		pr, ok := g.pullRequests[key]
+		switch {
+		case !ok:
+			http.Error(w, `{"message":"pull request does not exist"}`, http.StatusNotFound)
+			return
+		case body.Event == "APPROVED" && strings.EqualFold(pr.Author, username):
+			http.Error(w, `{"message":"approve your own pull is not allowed"}`, http.StatusUnprocessableEntity)
+			return
+		}
+		commitID := body.CommitID
+		if commitID == "" {
+			commitID = pr.HeadSHA
+		}
+		g.reviews[key] = append(g.reviews[key], GiteaReview{
+			User:     username,
+			State:    body.Event,
+			Body:     body.Body,
+			CommitID: commitID,
+			Stale:    commitID != pr.HeadSHA,
+		})
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":%d,"state":%q}`, len(g.reviews[key]), body.Event)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// --- Helpers ---
+
+// paginate returns the page of the items requested with the page and limit query parameters of Gitea.
+func paginate[T any](r *http.Request, items []T) []T {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 || limit > giteaPageSize {
+		limit = giteaPageSize
+	}
+	start := (page - 1) * limit
+	if start >= len(items) {
+		return []T{}
+	}
+	return items[start:min(start+limit, len(items))]
+}
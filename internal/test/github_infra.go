@@ -1,6 +1,7 @@
 package test
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -23,14 +24,46 @@ type Repo struct {
 	Permissions RepoPermissions `json:"permissions"`
 }
 
+type status struct {
+	Context string `json:"context"`
+	State   string `json:"state"`
+}
+
+type review struct {
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	State    string `json:"state"`
+	CommitID string `json:"commit_id"`
+}
+
+type checkRun struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion,omitempty"`
+}
+
+// defaultBranch is the default branch of the mocked repositories.
+const defaultBranch = "main"
+
 // GithubMockServer is a test server that mocks both GitHub API and OAuth2 endpoints.
 type GithubMockServer struct {
 	t *testing.T
 
 	mu         sync.Mutex
-	users      map[string]string // accessToken -> username
-	repos      map[string][]Repo // username -> []Repo
-	oauthCodes map[string]string // code -> username
+	users      map[string]string     // accessToken -> username
+	repos      map[string][]Repo     // username -> []Repo
+	oauthCodes map[string]string     // code -> username
+	files      map[string]string     // "owner/repo@branch/path" -> content
+	prFiles    map[string][]string   // "owner/repo/number" -> changed files
+	teams      map[string][]string   // "org/team" -> members
+	orgs       map[string][]string   // org -> members
+	prHeads    map[string]string     // "owner/repo/number" -> head commit SHA
+	prBases    map[string]string     // "owner/repo/number" -> base branch
+	statuses   map[string][]status   // "owner/repo/sha" -> commit statuses
+	checkRuns  map[string][]checkRun // "owner/repo/sha" -> check runs
+	comments   map[string][]string   // "owner/repo/number" -> comments posted on the PR
+	reviews    map[string][]review   // "owner/repo/number" -> reviews, oldest first
 
 	server     *httptest.Server
 	oauth2Conf *oauth2.Config
@@ -43,11 +76,24 @@ func NewGithubMockServer(t *testing.T, callbackURL string) *GithubMockServer {
 		users:      make(map[string]string),
 		repos:      make(map[string][]Repo),
 		oauthCodes: make(map[string]string),
+		files:      make(map[string]string),
+		prFiles:    make(map[string][]string),
+		teams:      make(map[string][]string),
+		orgs:       make(map[string][]string),
+		prHeads:    make(map[string]string),
+		prBases:    make(map[string]string),
+		statuses:   make(map[string][]status),
+		checkRuns:  make(map[string][]checkRun),
+		comments:   make(map[string][]string),
+		reviews:    make(map[string][]review),
 	}
 	mux := http.NewServeMux()
 	mux.HandleFunc("/user", g.handleUser)
 	mux.HandleFunc("/user/repos", g.handleUserRepos)
+	mux.HandleFunc("/user/orgs", g.handleUserOrgs)
+	mux.HandleFunc("/user/teams", g.handleUserTeams)
 	mux.HandleFunc("/repos/", g.handleRepoPR)
+	mux.HandleFunc("/orgs/", g.handleOrgTeamMembers)
 	mux.HandleFunc("/authorize", g.handleAuth)
 	mux.HandleFunc("/access_token", g.handleToken)
 	g.server = httptest.NewServer(mux)
@@ -80,6 +126,87 @@ func (g *GithubMockServer) AddOAuthCode(code, username string) {
 	g.oauthCodes[code] = username
 }
 
+// AddFile adds a file with the given content to the default branch of the repository ("owner/repo").
+func (g *GithubMockServer) AddFile(repo, path, content string) {
+	g.AddFileOnBranch(repo, defaultBranch, path, content)
+}
+
+// AddFileOnBranch adds a file with the given content to a branch of the repository ("owner/repo").
+func (g *GithubMockServer) AddFileOnBranch(repo, branch, path, content string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.files[repo+"@"+branch+"/"+path] = content
+}
+
+// SetPRBase sets the base branch of a pull request of the repository ("owner/repo"), the default branch otherwise.
+func (g *GithubMockServer) SetPRBase(repo string, number int, branch string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.prBases[fmt.Sprintf("%s/%d", repo, number)] = branch
+}
+
+// SetPRFiles sets the files changed by a pull request of the repository ("owner/repo").
+func (g *GithubMockServer) SetPRFiles(repo string, number int, files []string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.prFiles[fmt.Sprintf("%s/%d", repo, number)] = files
+}
+
+// AddTeam adds a team of the organization with the given members.
+func (g *GithubMockServer) AddTeam(org, team string, members []string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.teams[org+"/"+team] = members
+}
+
+// AddOrgMember adds a member to the organization. Members of the teams of an organization are also members of it.
+func (g *GithubMockServer) AddOrgMember(org, username string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.orgs[org] = append(g.orgs[org], username)
+}
+
+// SetPRHead sets the head commit SHA of a pull request of the repository ("owner/repo").
+func (g *GithubMockServer) SetPRHead(repo string, number int, sha string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.prHeads[fmt.Sprintf("%s/%d", repo, number)] = sha
+}
+
+// AddCommitStatus adds a commit status (success, pending, failure or error) to a commit of the repository ("owner/repo").
+func (g *GithubMockServer) AddCommitStatus(repo, sha, context, state string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	key := repo + "/" + sha
+	g.statuses[key] = append(g.statuses[key], status{Context: context, State: state})
+}
+
+// AddCheckRun adds a check run to a commit of the repository ("owner/repo").
+// The conclusion is only meaningful once the status is "completed".
+func (g *GithubMockServer) AddCheckRun(repo, sha, name, runStatus, conclusion string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	key := repo + "/" + sha
+	g.checkRuns[key] = append(g.checkRuns[key], checkRun{Name: name, Status: runStatus, Conclusion: conclusion})
+}
+
+// AddReview adds a review (APPROVED, CHANGES_REQUESTED, ...) of a commit of a pull request of the repository ("owner/repo").
+func (g *GithubMockServer) AddReview(repo string, number int, username, state, commitID string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	rv := review{State: state, CommitID: commitID}
+	rv.User.Login = username
+	key := fmt.Sprintf("%s/%d", repo, number)
+	g.reviews[key] = append(g.reviews[key], rv)
+}
+
+// Comments returns the comments posted on a pull request of the repository ("owner/repo").
+func (g *GithubMockServer) Comments(repo string, number int) []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string{}, g.comments[fmt.Sprintf("%s/%d", repo, number)]...)
+}
+
 // OAuth2Config returns the oauth2.Config for this mock server.
 func (g *GithubMockServer) OAuth2Config() *oauth2.Config {
 	return g.oauth2Conf
@@ -124,16 +251,271 @@ func (g *GithubMockServer) handleUserRepos(w http.ResponseWriter, r *http.Reques
 	_ = json.NewEncoder(w).Encode(repos)
 }
 
+func (g *GithubMockServer) handleUserOrgs(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	username := g.users[extractToken(r)]
+	orgs := make(map[string]struct{})
+	for org, members := range g.orgs {
+		if containsLogin(members, username) {
+			orgs[org] = struct{}{}
+		}
+	}
+	for team, members := range g.teams {
+		if containsLogin(members, username) {
+			orgs[strings.SplitN(team, "/", 2)[0]] = struct{}{}
+		}
+	}
+	g.mu.Unlock()
+	if username == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	type org struct {
+		Login string `json:"login"`
+	}
+	l := []org{}
+	if r.URL.Query().Get("page") == "" || r.URL.Query().Get("page") == "1" {
+		for o := range orgs {
+			l = append(l, org{Login: o})
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(l)
+}
+
+func (g *GithubMockServer) handleUserTeams(w http.ResponseWriter, r *http.Request) {
+	type team struct {
+		Slug         string `json:"slug"`
+		Organization struct {
+			Login string `json:"login"`
+		} `json:"organization"`
+	}
+	l := []team{}
+	g.mu.Lock()
+	username := g.users[extractToken(r)]
+	for name, members := range g.teams {
+		if containsLogin(members, username) {
+			parts := strings.SplitN(name, "/", 2)
+			var t team
+			t.Organization.Login, t.Slug = parts[0], parts[1]
+			l = append(l, t)
+		}
+	}
+	g.mu.Unlock()
+	if username == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.URL.Query().Get("page") != "" && r.URL.Query().Get("page") != "1" {
+		l = []team{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(l)
+}
+
 func (g *GithubMockServer) handleRepoPR(w http.ResponseWriter, r *http.Request) {
 	// Example: /repos/{owner}/{repo}/pulls/{number}
 	parts := strings.Split(r.URL.Path, "/")
-	if len(parts) >= 6 && parts[3] != "" && parts[4] != "" && parts[5] == "pulls" {
+	if len(parts) < 4 || parts[2] == "" || parts[3] == "" {
+		http.NotFound(w, r)
+		return
+	}
+	repo := parts[2] + "/" + parts[3]
+
+	switch {
+	case len(parts) == 4:
+		// /repos/{owner}/{repo} with the permissions of the authenticated user
+		g.mu.Lock()
+		username, ok := g.users[extractToken(r)]
+		repos := g.repos[username]
+		g.mu.Unlock()
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		result := Repo{FullName: repo}
+		for _, r := range repos {
+			if r.FullName == repo {
+				result = r
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(result)
+	case parts[4] == "collaborators" && len(parts) == 7 && parts[6] == "permission":
+		// /repos/{owner}/{repo}/collaborators/{username}/permission
+		g.mu.Lock()
+		repos, ok := g.repos[parts[5]]
+		g.mu.Unlock()
+		permission := "none"
+		for _, r := range repos {
+			if r.FullName == repo {
+				permission = "read"
+				if r.Permissions.Push {
+					permission = "write"
+				}
+			}
+		}
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"permission": permission})
+	case parts[4] == "issues" && len(parts) == 7 && parts[6] == "comments" && r.Method == http.MethodPost:
+		// /repos/{owner}/{repo}/issues/{number}/comments
+		var comment struct {
+			Body string `json:"body"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		g.mu.Lock()
+		key := repo + "/" + parts[5]
+		g.comments[key] = append(g.comments[key], comment.Body)
+		g.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{}`)
+	case parts[4] == "contents" && len(parts) >= 6:
+		// /repos/{owner}/{repo}/contents/{path}
+		ref := r.URL.Query().Get("ref")
+		if ref == "" {
+			ref = defaultBranch
+		}
+		g.mu.Lock()
+		content, ok := g.files[repo+"@"+ref+"/"+strings.Join(parts[5:], "/")]
+		g.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"encoding": "base64",
+			"content":  base64.StdEncoding.EncodeToString([]byte(content)),
+		})
+	case parts[4] == "pulls" && len(parts) == 7 && parts[6] == "files":
+		// /repos/{owner}/{repo}/pulls/{number}/files
+		if r.URL.Query().Get("page") != "" && r.URL.Query().Get("page") != "1" {
+			fmt.Fprint(w, `[]`)
+			return
+		}
+		g.mu.Lock()
+		files := g.prFiles[repo+"/"+parts[5]]
+		g.mu.Unlock()
+		type file struct {
+			Filename string `json:"filename"`
+		}
+		l := []file{}
+		for _, f := range files {
+			l = append(l, file{Filename: f})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(l)
+	case parts[4] == "pulls" && len(parts) == 7 && parts[6] == "reviews" && r.Method == http.MethodGet:
+		// /repos/{owner}/{repo}/pulls/{number}/reviews
+		g.mu.Lock()
+		reviews := append([]review{}, g.reviews[repo+"/"+parts[5]]...)
+		g.mu.Unlock()
+		if r.URL.Query().Get("page") != "" && r.URL.Query().Get("page") != "1" {
+			reviews = nil
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(reviews)
+	case parts[4] == "pulls" && len(parts) == 6:
 		// Simulate PR author as "prauthor"
+		g.mu.Lock()
+		sha := g.prHeads[repo+"/"+parts[5]]
+		base, ok := g.prBases[repo+"/"+parts[5]]
+		g.mu.Unlock()
+		if !ok {
+			base = defaultBranch
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"user":{"login":"prauthor"},"base":{"ref":%q},"head":{"sha":%q}}`, base, sha)
+	case parts[4] == "commits" && len(parts) == 7 && parts[6] == "status":
+		// /repos/{owner}/{repo}/commits/{sha}/status
+		g.mu.Lock()
+		statuses := append([]status{}, g.statuses[repo+"/"+parts[5]]...)
+		g.mu.Unlock()
+		if r.URL.Query().Get("page") != "" && r.URL.Query().Get("page") != "1" {
+			statuses = nil
+		}
 		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprint(w, `{"user":{"login":"prauthor"}}`)
+		_ = json.NewEncoder(w).Encode(map[string]any{"statuses": statuses})
+	case parts[4] == "commits" && len(parts) == 7 && parts[6] == "check-runs":
+		// /repos/{owner}/{repo}/commits/{sha}/check-runs
+		g.mu.Lock()
+		runs := append([]checkRun{}, g.checkRuns[repo+"/"+parts[5]]...)
+		g.mu.Unlock()
+		if r.URL.Query().Get("page") != "" && r.URL.Query().Get("page") != "1" {
+			runs = nil
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"check_runs": runs})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (g *GithubMockServer) handleOrgTeamMembers(w http.ResponseWriter, r *http.Request) {
+	// Example: /orgs/{org}/teams/{team}/members
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) == 5 && parts[3] == "members" {
+		g.handleOrgMembership(w, r, parts[2], parts[4])
+		return
+	}
+	if len(parts) == 7 && parts[3] == "teams" && parts[5] == "memberships" {
+		g.mu.Lock()
+		member := containsLogin(g.teams[parts[2]+"/"+parts[4]], parts[6])
+		g.mu.Unlock()
+		if !member {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"state": "active"})
+		return
+	}
+	if len(parts) != 6 || parts[3] != "teams" || parts[5] != "members" {
+		http.NotFound(w, r)
+		return
+	}
+	g.mu.Lock()
+	members, ok := g.teams[parts[2]+"/"+parts[4]]
+	g.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
 		return
 	}
-	http.NotFound(w, r)
+	type user struct {
+		Login string `json:"login"`
+	}
+	l := []user{}
+	if r.URL.Query().Get("page") == "" || r.URL.Query().Get("page") == "1" {
+		for _, m := range members {
+			l = append(l, user{Login: m})
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(l)
+}
+
+// handleOrgMembership answers 204 No Content if the user is a member of the organization, 404 Not Found otherwise.
+func (g *GithubMockServer) handleOrgMembership(w http.ResponseWriter, r *http.Request, org, username string) {
+	g.mu.Lock()
+	member := containsLogin(g.orgs[org], username)
+	for team, members := range g.teams {
+		if strings.HasPrefix(team, org+"/") && containsLogin(members, username) {
+			member = true
+		}
+	}
+	g.mu.Unlock()
+	if !member {
+		http.NotFound(w, r)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func (g *GithubMockServer) handleAuth(w http.ResponseWriter, r *http.Request) {
@@ -173,3 +555,13 @@ func extractToken(r *http.Request) string {
 	}
 	return ""
 }
+
+// containsLogin tells whether the list contains the GitHub login. GitHub logins are case-insensitive.
+func containsLogin(logins []string, login string) bool {
+	for _, l := range logins {
+		if strings.EqualFold(l, login) {
+			return true
+		}
+	}
+	return false
+}
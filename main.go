@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/clems4ever/lgtm/internal/client"
+	"github.com/clems4ever/lgtm/internal/request"
 	"github.com/clems4ever/lgtm/internal/server"
 	"github.com/spf13/cobra"
 )
@@ -14,6 +15,7 @@ func main() {
 
 	rootCmd.AddCommand(client.BuildCommand())
 	rootCmd.AddCommand(server.BuildCommand())
+	rootCmd.AddCommand(request.BuildCommand())
 
 	if err := rootCmd.Execute(); err != nil {
 		panic(err)